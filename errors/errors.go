@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"log/slog"
+	"strings"
+)
+
 type JapikeyError struct {
 	Code    string
 	Message string
@@ -9,6 +14,20 @@ func (e *JapikeyError) Error() string {
 	return e.Message
 }
 
+// String renders the error as "[Code] Message", so logs keep the code. Error returns the
+// message alone, since it may be shown to clients.
+func (e *JapikeyError) String() string {
+	return "[" + e.Code + "] " + e.Message
+}
+
+// LogValue implements slog.LogValuer, logging the error as code and message attributes.
+func (e *JapikeyError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("code", e.Code),
+		slog.String("message", e.Message),
+	)
+}
+
 type ValidationError struct {
 	JapikeyError
 }
@@ -22,6 +41,95 @@ func NewValidationError(message string) *ValidationError {
 	}
 }
 
+// NewScopeError creates a ValidationError with the ScopeError code, so clients can tell a
+// missing scope or permission apart from other validation failures
+func NewScopeError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "ScopeError",
+			Message: message,
+		},
+	}
+}
+
+// NewSubjectMismatchError creates a ValidationError with the SubjectMismatchError code, for valid
+// tokens that belong to a different subject than the caller expected
+func NewSubjectMismatchError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "SubjectMismatchError",
+			Message: message,
+		},
+	}
+}
+
+// NewTokenTooOldError creates a ValidationError with the TokenTooOldError code, for unexpired
+// tokens that were issued longer ago than the verifier's maximum token age
+func NewTokenTooOldError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "TokenTooOldError",
+			Message: message,
+		},
+	}
+}
+
+// NewNotBeforeError creates a ValidationError with the NotBeforeError code, for tokens whose nbf
+// claim is still in the future, as opposed to tokens that are malformed or expired
+func NewNotBeforeError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "NotBeforeError",
+			Message: message,
+		},
+	}
+}
+
+// NewHeaderValidationError creates a ValidationError with the HeaderValidationError code, for
+// tokens whose JOSE header uses a feature JAPIKeys never use (e.g., an unencoded payload)
+func NewHeaderValidationError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "HeaderValidationError",
+			Message: message,
+		},
+	}
+}
+
+// NewIssuerFormatError creates a ValidationError with the IssuerFormatError code, for tokens whose
+// iss claim is missing or malformed, such as a key ID segment that is not a UUID, as opposed to
+// a well-formed issuer that does not match the expected one
+func NewIssuerFormatError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "IssuerFormatError",
+			Message: message,
+		},
+	}
+}
+
+// NewKeyIDFormatError creates a ValidationError with the KeyIDFormatError code, for tokens whose
+// kid header is missing or is not a UUID
+func NewKeyIDFormatError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "KeyIDFormatError",
+			Message: message,
+		},
+	}
+}
+
+// NewTokenSizeError creates a ValidationError with the TokenSizeError code, for tokens larger than
+// the maximum token size, rejected before any parsing
+func NewTokenSizeError(message string) *ValidationError {
+	return &ValidationError{
+		JapikeyError: JapikeyError{
+			Code:    "TokenSizeError",
+			Message: message,
+		},
+	}
+}
+
 type ConversionError struct {
 	JapikeyError
 }
@@ -50,8 +158,22 @@ func NewKeyNotFoundError(message string) *KeyNotFoundError {
 	}
 }
 
+// NewKeyRetrievalError creates a KeyNotFoundError with the KeyRetrievalError code, for key
+// callbacks that report success but return no key
+func NewKeyRetrievalError(message string) *KeyNotFoundError {
+	return &KeyNotFoundError{
+		JapikeyError: JapikeyError{
+			Code:    "KeyRetrievalError",
+			Message: message,
+		},
+	}
+}
+
 type InternalError struct {
 	JapikeyError
+	// Cause is the underlying error, if any, reachable through Unwrap. It is kept out of the
+	// message, which may be shown to clients.
+	Cause error
 }
 
 func NewInternalError(message string) *InternalError {
@@ -63,6 +185,18 @@ func NewInternalError(message string) *InternalError {
 	}
 }
 
+// NewInternalErrorWithCause creates an InternalError wrapping cause, for failures of the
+// environment, such as I/O, that callers may need to inspect with errors.Is or errors.As
+func NewInternalErrorWithCause(message string, cause error) *InternalError {
+	err := NewInternalError(message)
+	err.Cause = cause
+	return err
+}
+
+func (e *InternalError) Unwrap() error {
+	return e.Cause
+}
+
 type DatabaseTimeoutError struct {
 	JapikeyError
 }
@@ -89,6 +223,21 @@ func NewDatabaseUnavailableError(message string) *DatabaseUnavailableError {
 	}
 }
 
+// FetchError is kept separate because remote fetch failures are usually transient
+// (e.g., network error, upstream 5xx) and clients may want to retry
+type FetchError struct {
+	JapikeyError
+}
+
+func NewFetchError(message string) *FetchError {
+	return &FetchError{
+		JapikeyError: JapikeyError{
+			Code:    "FetchError",
+			Message: message,
+		},
+	}
+}
+
 // TokenExpiredError is kept separate because clients may need different behavior
 // (e.g., refresh token, redirect to login)
 type TokenExpiredError struct {
@@ -103,3 +252,73 @@ func NewTokenExpiredError(message string) *TokenExpiredError {
 		},
 	}
 }
+
+// TokenFormatError is kept separate because it signals a structurally broken token
+// (e.g., an empty segment) rather than a token whose contents failed validation
+type TokenFormatError struct {
+	JapikeyError
+}
+
+func NewTokenFormatError(message string) *TokenFormatError {
+	return &TokenFormatError{
+		JapikeyError: JapikeyError{
+			Code:    "TokenFormatError",
+			Message: message,
+		},
+	}
+}
+
+// ConfigError aggregates every problem found while validating a config, so callers can fix
+// them all at once. Each problem is a ValidationError reachable through Unwrap.
+type ConfigError struct {
+	JapikeyError
+	Errors []error
+}
+
+func NewConfigError(errs []error) *ConfigError {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return &ConfigError{
+		JapikeyError: JapikeyError{
+			Code:    "ConfigError",
+			Message: "invalid config: " + strings.Join(messages, "; "),
+		},
+		Errors: errs,
+	}
+}
+
+func (e *ConfigError) Unwrap() []error {
+	return e.Errors
+}
+
+// RandomnessError is kept separate because it points operators at the environment's entropy
+// source (e.g., a missing /dev/urandom in a minimal container) rather than at the request
+type RandomnessError struct {
+	JapikeyError
+}
+
+func NewRandomnessError(message string) *RandomnessError {
+	return &RandomnessError{
+		JapikeyError: JapikeyError{
+			Code:    "RandomnessError",
+			Message: message,
+		},
+	}
+}
+
+// RateLimitError is kept separate because the request was valid and may succeed later, so
+// clients should back off and retry rather than fix their input
+type RateLimitError struct {
+	JapikeyError
+}
+
+func NewRateLimitError(message string) *RateLimitError {
+	return &RateLimitError{
+		JapikeyError: JapikeyError{
+			Code:    "RateLimitError",
+			Message: message,
+		},
+	}
+}
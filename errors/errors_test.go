@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestJapikeyError_String(t *testing.T) {
+	err := NewScopeError("missing scope")
+
+	if err.Error() != "missing scope" {
+		t.Errorf("Expected Error to return the message alone, got %q", err.Error())
+	}
+	if err.String() != "[ScopeError] missing scope" {
+		t.Errorf("Expected String to include the code, got %q", err.String())
+	}
+
+	var stringer fmt.Stringer = NewDatabaseTimeoutError("timeout")
+	if stringer.String() != "[DatabaseTimeout] timeout" {
+		t.Errorf("Expected promoted String to include the code, got %q", stringer.String())
+	}
+}
+
+func TestJapikeyError_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logger.Error("verification failed", "err", NewTokenExpiredError("token has expired"))
+
+	var record struct {
+		Err struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"err"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode log record %q: %v", buf.String(), err)
+	}
+	if record.Err.Code != "TokenExpiredError" || record.Err.Message != "token has expired" {
+		t.Errorf("Expected code and message attributes, got %+v", record.Err)
+	}
+}
+
+func TestInternalError_Unwrap(t *testing.T) {
+	cause := stderrors.New("connection reset")
+	err := NewInternalErrorWithCause("failed to read token", cause)
+
+	if err.Error() != "failed to read token" {
+		t.Errorf("Expected the cause to be kept out of the message, got %q", err.Error())
+	}
+	if !stderrors.Is(err, cause) {
+		t.Error("Expected the cause to be reachable with errors.Is")
+	}
+	if NewInternalError("boom").Unwrap() != nil {
+		t.Error("Expected no cause from NewInternalError")
+	}
+}
@@ -36,7 +36,7 @@ func main() {
 	}
 
 	// Serialize JWKS to JSON
-	jwksJSON, err := json.MarshalIndent(jwks, "", "  ")
+	jwksJSON, err := jwks.MarshalJSONIndent("", "  ")
 	if err != nil {
 		log.Fatalf("Failed to marshal JWKS: %v", err)
 	}
@@ -90,12 +90,8 @@ func main() {
 		log.Fatalf("Failed to get public key from deserialized JWKS: %v", err)
 	}
 
-	if deserializedPublicKey.N.Cmp(publicKey.N) != 0 {
-		log.Fatal("Public key modulus mismatch after deserialization")
-	}
-
-	if deserializedPublicKey.E != publicKey.E {
-		log.Fatalf("Public key exponent mismatch: expected %d, got %d", publicKey.E, deserializedPublicKey.E)
+	if !japikey.PublicKeyEqual(deserializedPublicKey, publicKey) {
+		log.Fatal("Public key mismatch after deserialization")
 	}
 
 	fmt.Println("Successfully deserialized and verified JWKS!")
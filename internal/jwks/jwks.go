@@ -1,10 +1,15 @@
 package jwks
 
 import (
+	"bytes"
 	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"maps"
 	"math/big"
+	"slices"
 
 	"github.com/google/uuid"
 	"github.com/susu-dot-dev/japikey/errors"
@@ -14,6 +19,7 @@ type JWK struct {
 	kid       uuid.UUID
 	n         string
 	e         string
+	label     string
 	publicKey *rsa.PublicKey
 }
 
@@ -21,18 +27,46 @@ type JWKS struct {
 	jwk JWK
 }
 
+// JWKInfo is a read-only view of a key in a JWKS, for inspection by tooling.
+// It never contains private key material.
+type JWKInfo struct {
+	KeyID uuid.UUID
+	Kty   string
+	Bits  int
+	Alg   string
+	Label string // informational x-label, empty if none
+}
+
 // Separate type for JSON serialization to match RFC 7517 format with "keys" array
 type encodedJWK struct {
 	Kty string    `json:"kty"`
 	Kid uuid.UUID `json:"kid"`
 	N   string    `json:"n"`
 	E   string    `json:"e"`
+
+	// Label is operational metadata only; verification ignores it
+	Label string `json:"x-label,omitempty"`
 }
 type encodedJWKS struct {
 	Keys []encodedJWK `json:"keys"`
 }
 
+// labelField is the namespaced JWK member carrying a key's human-readable label
+const labelField = "x-label"
+
+// MaxPublicExponent is the largest RSA public exponent accepted, matching the limit crypto/rsa
+// enforces so that an accepted key can always verify signatures.
+const MaxPublicExponent = 1<<31 - 1
+
 func NewJWKS(publicKey *rsa.PublicKey, kid uuid.UUID) (*JWKS, error) {
+	return NewJWKSWithLabel(publicKey, kid, "")
+}
+
+// NewJWKSWithLabel creates a JWKS whose key carries a human-readable label (e.g. "prod-signer-2024Q1")
+// in the x-label member, to help operators identify keys when debugging. The label is purely
+// informational: it is not covered by any signature, so it must never be relied on for a
+// security decision. An empty label omits the member.
+func NewJWKSWithLabel(publicKey *rsa.PublicKey, kid uuid.UUID, label string) (*JWKS, error) {
 	if publicKey == nil {
 		return nil, errors.NewValidationError("RSA public key cannot be nil")
 	}
@@ -41,6 +75,12 @@ func NewJWKS(publicKey *rsa.PublicKey, kid uuid.UUID) (*JWKS, error) {
 		return nil, errors.NewValidationError("key ID cannot be empty")
 	}
 
+	if err := CheckPublicExponent(publicKey.E); err != nil {
+		return nil, err
+	}
+
+	// The exponent is encoded from the key itself, so keys with exponents other than 65537
+	// ("AQAB") round-trip unchanged
 	modulusBase64 := base64urlUIntEncode(publicKey.N)
 	exponentInt := big.NewInt(int64(publicKey.E))
 	exponentBase64 := base64urlUIntEncode(exponentInt)
@@ -49,6 +89,7 @@ func NewJWKS(publicKey *rsa.PublicKey, kid uuid.UUID) (*JWKS, error) {
 		kid:       kid,
 		n:         modulusBase64,
 		e:         exponentBase64,
+		label:     label,
 		publicKey: publicKey,
 	}
 
@@ -67,24 +108,90 @@ func (j *JWKS) GetPublicKey(kid uuid.UUID) (*rsa.PublicKey, error) {
 	return j.jwk.publicKey, nil
 }
 
+// KeyFunc returns a key callback backed by this JWKS, for passing straight to Verify:
+// Verify(token, config, keySet.KeyFunc()). Unknown key IDs return a KeyNotFoundError.
+func (j *JWKS) KeyFunc() func(kid uuid.UUID) (*rsa.PublicKey, error) {
+	return func(kid uuid.UUID) (*rsa.PublicKey, error) {
+		if j == nil {
+			return nil, errors.NewKeyNotFoundError("key ID not found in JWKS")
+		}
+		return j.GetPublicKey(kid)
+	}
+}
+
 func (j *JWKS) GetKeyID() uuid.UUID {
 	return j.jwk.kid
 }
 
+// Keys returns a read-only view of each key in the set.
+func (j *JWKS) Keys() []JWKInfo {
+	bits := 0
+	if j.jwk.publicKey != nil && j.jwk.publicKey.N != nil {
+		bits = j.jwk.publicKey.N.BitLen()
+	}
+
+	return []JWKInfo{
+		{
+			KeyID: j.jwk.kid,
+			Kty:   "RSA",
+			Bits:  bits,
+			Alg:   "RS256",
+			Label: j.jwk.label,
+		},
+	}
+}
+
 func (j *JWKS) MarshalJSON() ([]byte, error) {
 	ejwks := encodedJWKS{
 		Keys: []encodedJWK{
 			{
-				Kty: "RSA",
-				Kid: j.jwk.kid,
-				N:   j.jwk.n,
-				E:   j.jwk.e,
+				Kty:   "RSA",
+				Kid:   j.jwk.kid,
+				N:     j.jwk.n,
+				E:     j.jwk.e,
+				Label: j.jwk.label,
 			},
 		},
 	}
 	return json.Marshal(ejwks)
 }
 
+// MarshalKeySet encodes the keys of several single-key sets into one JWKS document, in the order
+// given, e.g. for a statically hosted combined jwks.json. The result lists more than one key, so
+// it is for generic JWKS consumers; JWKS.UnmarshalJSON only accepts single-key documents.
+func MarshalKeySet(sets []*JWKS) ([]byte, error) {
+	ejwks := encodedJWKS{Keys: make([]encodedJWK, 0, len(sets))}
+	for _, set := range sets {
+		if set == nil {
+			return nil, errors.NewValidationError("JWKS cannot be nil")
+		}
+		ejwks.Keys = append(ejwks.Keys, encodedJWK{
+			Kty:   "RSA",
+			Kid:   set.jwk.kid,
+			N:     set.jwk.n,
+			E:     set.jwk.e,
+			Label: set.jwk.label,
+		})
+	}
+	return json.Marshal(ejwks)
+}
+
+// MarshalJSONIndent is like MarshalJSON but indents the output, for CLI output and checked-in
+// files. The members keep the canonical order; only whitespace is added, so compacting the
+// result gives exactly the MarshalJSON output.
+func (j *JWKS) MarshalJSONIndent(prefix, indent string) ([]byte, error) {
+	data, err := j.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func (j *JWKS) UnmarshalJSON(data []byte) error {
 	if err := j.validateJSONShape(data); err != nil {
 		return err
@@ -95,16 +202,26 @@ func (j *JWKS) UnmarshalJSON(data []byte) error {
 	}
 	ejwk := ejwks.Keys[0]
 	if ejwk.Kty != "RSA" {
-		return errors.NewValidationError("kty parameter must be 'RSA'")
+		return fieldError(0, "kty", "must be 'RSA'")
+	}
+	if ejwk.Kid == uuid.Nil {
+		return fieldError(0, "kid", "cannot be the nil UUID")
 	}
 	modulus, err := base64urlUIntDecode(ejwk.N)
 	if err != nil {
-		return errors.NewValidationError("failed to decode modulus: " + err.Error())
+		return fieldError(0, "n", err.Error())
 	}
 
 	exponent, err := base64urlUIntDecode(ejwk.E)
 	if err != nil {
-		return errors.NewValidationError("failed to decode exponent: " + err.Error())
+		return fieldError(0, "e", err.Error())
+	}
+	// Checked before the conversion to int, which would otherwise silently truncate
+	if !exponent.IsInt64() || exponent.Int64() > MaxPublicExponent {
+		return fieldError(0, "e", "exponent is too large")
+	}
+	if err := CheckPublicExponent(int(exponent.Int64())); err != nil {
+		return fieldError(0, "e", err.Error())
 	}
 
 	publicKey := &rsa.PublicKey{
@@ -112,20 +229,29 @@ func (j *JWKS) UnmarshalJSON(data []byte) error {
 		E: int(exponent.Int64()),
 	}
 
-	jwks, err := NewJWKS(publicKey, ejwk.Kid)
+	jwks, err := NewJWKSWithLabel(publicKey, ejwk.Kid, ejwk.Label)
 	if err != nil {
 		return err
 	}
 
 	// Round-trip validation ensures encoded values match exactly
-	if jwks.jwk.n != ejwk.N || jwks.jwk.e != ejwk.E {
-		return errors.NewConversionError("round-trip validation failed: n values do not match")
+	if jwks.jwk.n != ejwk.N {
+		return errors.NewConversionError("round-trip validation failed: keys[0].n is not canonically encoded")
+	}
+	if jwks.jwk.e != ejwk.E {
+		return errors.NewConversionError("round-trip validation failed: keys[0].e is not canonically encoded")
 	}
 	j.jwk = jwks.jwk
 
 	return nil
 }
 
+// fieldError returns a ValidationError naming the offending member of the key at index, e.g.
+// "invalid JWK keys[0].kid: must be a UUID", so key-server integrations can be debugged.
+func fieldError(index int, field, problem string) *errors.ValidationError {
+	return errors.NewValidationError(fmt.Sprintf("invalid JWK keys[%d].%s: %s", index, field, problem))
+}
+
 func (j *JWKS) validateJSONShape(data []byte) error {
 	// Two-phase validation: first untyped to detect extra fields that Go would silently ignore
 	var jwksUntyped struct {
@@ -141,18 +267,130 @@ func (j *JWKS) validateJSONShape(data []byte) error {
 
 	jwkUntyped := jwksUntyped.Keys[0]
 	expectedFields := []string{"kty", "kid", "n", "e"}
-	if len(jwkUntyped) != len(expectedFields) {
-		return errors.NewValidationError("JWK must contain exactly 4 fields: kty, kid, n, e")
+	// The informational label is the only optional member. Fields are checked in sorted order so
+	// the reported field is deterministic
+	for _, field := range slices.Sorted(maps.Keys(jwkUntyped)) {
+		if field != labelField && !slices.Contains(expectedFields, field) {
+			return fieldError(0, field, "unexpected field; a JWK must contain exactly kty, kid, n, e (plus an optional x-label)")
+		}
+	}
+	if label, exists := jwkUntyped[labelField]; exists {
+		if _, ok := label.(string); !ok {
+			return fieldError(0, labelField, "must be a string")
+		}
 	}
 
 	for _, field := range expectedFields {
-		if _, exists := jwkUntyped[field]; !exists {
-			return errors.NewValidationError("JWK must contain '" + field + "' field")
+		value, exists := jwkUntyped[field]
+		if !exists {
+			return fieldError(0, field, "is missing")
+		}
+		str, ok := value.(string)
+		if !ok {
+			return fieldError(0, field, "must be a string")
+		}
+		if field == "kid" {
+			if _, err := uuid.Parse(str); err != nil {
+				return fieldError(0, field, "must be a UUID")
+			}
 		}
 	}
 	return nil
 }
 
+// Thumbprint returns the RFC 7638 JWK SHA-256 thumbprint of an RSA public key, base64url-encoded
+// without padding.
+func Thumbprint(publicKey *rsa.PublicKey) (string, error) {
+	digest, err := thumbprintDigest(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(digest[:]), nil
+}
+
+// ThumbprintKeyID derives a key ID from the RFC 7638 thumbprint of an RSA public key, for
+// deployments that tie the kid cryptographically to the key. Key IDs must be UUIDs, so the first
+// 16 bytes of the SHA-256 thumbprint are used, with the version 8 (custom) and variant bits set.
+func ThumbprintKeyID(publicKey *rsa.PublicKey) (uuid.UUID, error) {
+	digest, err := thumbprintDigest(publicKey)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var kid uuid.UUID
+	copy(kid[:], digest[:16])
+	kid[6] = (kid[6] & 0x0f) | 0x80 // version 8
+	kid[8] = (kid[8] & 0x3f) | 0x80 // RFC 9562 variant
+	return kid, nil
+}
+
+// thumbprintDigest returns the SHA-256 digest of the RFC 7638 canonical JWK of an RSA public key.
+func thumbprintDigest(publicKey *rsa.PublicKey) ([sha256.Size]byte, error) {
+	if publicKey == nil || publicKey.N == nil {
+		return [sha256.Size]byte{}, errors.NewValidationError("RSA public key cannot be nil")
+	}
+
+	// RFC 7638 requires the required members only, in lexicographic order, with no whitespace
+	canonical := `{"e":"` + base64urlUIntEncode(big.NewInt(int64(publicKey.E))) +
+		`","kty":"RSA","n":"` + base64urlUIntEncode(publicKey.N) + `"}`
+	return sha256.Sum256([]byte(canonical)), nil
+}
+
+// VerifyMatchesKey parses a JWKS document and confirms it contains exactly the given key under
+// the given kid, e.g. so a deployment pipeline can assert a checked-in JWKS file matches the key
+// it intends to publish. Parsing applies the same strict shape and encoding checks as
+// UnmarshalJSON, so non-canonical encodings are rejected too. An informational x-label is ignored.
+// Mismatches are reported as a ValidationError describing the first difference found.
+func VerifyMatchesKey(data []byte, publicKey *rsa.PublicKey, kid uuid.UUID) error {
+	if publicKey == nil || publicKey.N == nil {
+		return errors.NewValidationError("expected RSA public key cannot be nil")
+	}
+
+	keySet := &JWKS{}
+	if err := keySet.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	if keySet.jwk.kid != kid {
+		return errors.NewValidationError(fmt.Sprintf("JWKS key ID %s does not match expected key ID %s", keySet.jwk.kid, kid))
+	}
+	if keySet.jwk.publicKey.E != publicKey.E {
+		return errors.NewValidationError(fmt.Sprintf("JWKS exponent %d does not match expected exponent %d", keySet.jwk.publicKey.E, publicKey.E))
+	}
+	if keySet.jwk.publicKey.N.Cmp(publicKey.N) != 0 {
+		return errors.NewValidationError(fmt.Sprintf("JWKS modulus (%d bits) does not match expected modulus (%d bits)", keySet.jwk.publicKey.N.BitLen(), publicKey.N.BitLen()))
+	}
+
+	return nil
+}
+
+// CheckPublicExponent validates an RSA public exponent. Any odd exponent from 3 up to
+// MaxPublicExponent is accepted, not just the default of 65537.
+func CheckPublicExponent(e int) error {
+	if e < 3 {
+		return errors.NewValidationError("RSA public exponent must be at least 3")
+	}
+	if e > MaxPublicExponent {
+		return errors.NewValidationError("RSA public exponent is too large")
+	}
+	if e%2 == 0 {
+		return errors.NewValidationError("RSA public exponent must be odd")
+	}
+	return nil
+}
+
+// PublicKeyEqual reports whether two RSA public keys have the same modulus and exponent.
+// Two nil keys are equal; a nil key never equals a non-nil one.
+func PublicKeyEqual(a, b *rsa.PublicKey) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.N == nil || b.N == nil {
+		return a.N == b.N && a.E == b.E
+	}
+	return a.N.Cmp(b.N) == 0 && a.E == b.E
+}
+
 // RFC 7518 requires zero to be encoded as "AA" (single zero-valued octet)
 func base64urlUIntEncode(n *big.Int) string {
 	if n == nil {
@@ -1,11 +1,13 @@
 package jwks
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os/exec"
 	"strings"
 	"testing"
@@ -369,12 +371,8 @@ func TestJWKS_SerializationDeserializationRoundTrip(t *testing.T) {
 		t.Fatalf("Failed to extract public key from round-trip JWKS: %v", err)
 	}
 
-	if origPubKey.N.Cmp(rtPubKey.N) != 0 {
-		t.Error("Public key modulus does not match after round-trip")
-	}
-
-	if origPubKey.E != rtPubKey.E {
-		t.Errorf("Public key exponent does not match after round-trip: %d != %d", origPubKey.E, rtPubKey.E)
+	if !PublicKeyEqual(origPubKey, rtPubKey) {
+		t.Errorf("Public key does not match after round-trip: e %d != %d", origPubKey.E, rtPubKey.E)
 	}
 }
 
@@ -931,3 +929,589 @@ func TestJWKS_JWKNotAnObject(t *testing.T) {
 		t.Errorf("Expected ValidationError, got %T", err)
 	}
 }
+
+func TestPublicKeyEqual(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	key := &privateKey.PublicKey
+	sameValues := &rsa.PublicKey{N: new(big.Int).Set(key.N), E: key.E}
+	differentExponent := &rsa.PublicKey{N: key.N, E: 3}
+
+	tests := []struct {
+		name     string
+		a, b     *rsa.PublicKey
+		expected bool
+	}{
+		{"same pointer", key, key, true},
+		{"same values", key, sameValues, true},
+		{"different modulus", key, &otherKey.PublicKey, false},
+		{"different exponent", key, differentExponent, false},
+		{"both nil", nil, nil, true},
+		{"first nil", nil, key, false},
+		{"second nil", key, nil, false},
+		{"nil modulus", &rsa.PublicKey{E: 65537}, key, false},
+		{"both nil modulus", &rsa.PublicKey{E: 65537}, &rsa.PublicKey{E: 65537}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PublicKeyEqual(tt.a, tt.b); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJWKS_Keys(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	kid := uuid.New()
+
+	jwks, err := NewJWKS(&privateKey.PublicKey, kid)
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+
+	keys := jwks.Keys()
+	if len(keys) != 1 {
+		t.Fatalf("Expected 1 key, got %d", len(keys))
+	}
+
+	expected := JWKInfo{KeyID: kid, Kty: "RSA", Bits: 2048, Alg: "RS256"}
+	if keys[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, keys[0])
+	}
+
+	// The view is a copy, so modifying it does not affect the JWKS
+	keys[0].KeyID = uuid.New()
+	if jwks.Keys()[0].KeyID != kid {
+		t.Error("Expected Keys to return a copy of the key info")
+	}
+}
+
+func TestJWKS_Keys_AfterUnmarshal(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	kid := uuid.New()
+
+	original, err := NewJWKS(&privateKey.PublicKey, kid)
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	data, err := original.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+
+	var parsed JWKS
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Failed to unmarshal JWKS: %v", err)
+	}
+
+	keys := parsed.Keys()
+	if len(keys) != 1 || keys[0].KeyID != kid || keys[0].Bits != 2048 {
+		t.Errorf("Unexpected keys after unmarshal: %+v", keys)
+	}
+}
+
+func TestJWKS_Label(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	kid := uuid.New()
+
+	t.Run("label is emitted and round-trips", func(t *testing.T) {
+		labeled, err := NewJWKSWithLabel(&privateKey.PublicKey, kid, "prod-signer-2024Q1")
+		if err != nil {
+			t.Fatalf("Failed to create JWKS: %v", err)
+		}
+		data, err := labeled.MarshalJSON()
+		if err != nil {
+			t.Fatalf("Failed to marshal JWKS: %v", err)
+		}
+		if !strings.Contains(string(data), `"x-label":"prod-signer-2024Q1"`) {
+			t.Errorf("Expected x-label member in %s", data)
+		}
+
+		var parsed JWKS
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			t.Fatalf("Expected labeled JWKS to parse, got: %v", err)
+		}
+		if parsed.Keys()[0].Label != "prod-signer-2024Q1" {
+			t.Errorf("Expected label to round-trip, got %q", parsed.Keys()[0].Label)
+		}
+		publicKey, err := parsed.GetPublicKey(kid)
+		if err != nil || !PublicKeyEqual(publicKey, &privateKey.PublicKey) {
+			t.Errorf("Expected label not to affect the key, got %v", err)
+		}
+	})
+
+	t.Run("no label omits member", func(t *testing.T) {
+		unlabeled, err := NewJWKS(&privateKey.PublicKey, kid)
+		if err != nil {
+			t.Fatalf("Failed to create JWKS: %v", err)
+		}
+		data, err := unlabeled.MarshalJSON()
+		if err != nil {
+			t.Fatalf("Failed to marshal JWKS: %v", err)
+		}
+		if strings.Contains(string(data), "x-label") {
+			t.Errorf("Expected no x-label member in %s", data)
+		}
+	})
+
+	t.Run("non-string label rejected", func(t *testing.T) {
+		jsonStr := `{"keys":[{"kty":"RSA","kid":"` + kid.String() + `","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbPFRP_gdM_X7zVFQ84l8g7hQg-jC6SGODpEcF7yR3xNgQBKzAV-OdSQ","e":"AQAB","x-label":42}]}`
+		var parsed JWKS
+		err := json.Unmarshal([]byte(jsonStr), &parsed)
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError, got %T: %v", err, err)
+		}
+	})
+
+	t.Run("other extra members still rejected", func(t *testing.T) {
+		jsonStr := `{"keys":[{"kty":"RSA","kid":"` + kid.String() + `","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbPFRP_gdM_X7zVFQ84l8g7hQg-jC6SGODpEcF7yR3xNgQBKzAV-OdSQ","e":"AQAB","x-label":"a","extra":"field"}]}`
+		var parsed JWKS
+		err := json.Unmarshal([]byte(jsonStr), &parsed)
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError, got %T: %v", err, err)
+		}
+	})
+}
+
+func TestThumbprint(t *testing.T) {
+	// Example key and thumbprint from RFC 7638 section 3.1
+	modulus, err := base64urlUIntDecode("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatalf("Failed to decode modulus: %v", err)
+	}
+
+	thumbprint, err := Thumbprint(&rsa.PublicKey{N: modulus, E: 65537})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Unexpected thumbprint %s", thumbprint)
+	}
+
+	if _, err := Thumbprint(nil); err == nil {
+		t.Error("Expected error for nil key")
+	}
+}
+
+func TestThumbprintKeyID(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	kid, err := ThumbprintKeyID(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if kid.Version() != 8 || kid.Variant() != uuid.RFC4122 {
+		t.Errorf("Expected a version 8 RFC 9562 UUID, got version %d variant %v", kid.Version(), kid.Variant())
+	}
+
+	again, err := ThumbprintKeyID(&rsa.PublicKey{N: privateKey.N, E: privateKey.E})
+	if err != nil || again != kid {
+		t.Errorf("Expected the same key ID for the same key, got %s and %s", kid, again)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	if other, _ := ThumbprintKeyID(&otherKey.PublicKey); other == kid {
+		t.Error("Expected different keys to derive different key IDs")
+	}
+
+	if _, err := ThumbprintKeyID(nil); err == nil {
+		t.Error("Expected error for nil key")
+	}
+}
+
+func TestJWKS_KeyFunc(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	kid := uuid.New()
+
+	jwks, err := NewJWKS(&privateKey.PublicKey, kid)
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	keyFunc := jwks.KeyFunc()
+
+	publicKey, err := keyFunc(kid)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !PublicKeyEqual(publicKey, &privateKey.PublicKey) {
+		t.Error("Expected the JWKS public key")
+	}
+
+	if _, err := keyFunc(uuid.New()); err == nil {
+		t.Error("Expected error for unknown key ID")
+	} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T", err)
+	}
+
+	var nilJWKS *JWKS
+	if _, err := nilJWKS.KeyFunc()(kid); err == nil {
+		t.Error("Expected error for nil JWKS")
+	} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T", err)
+	}
+}
+
+func TestJWKS_NonDefaultExponentRoundTrip(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		exponent int
+		encoded  string
+	}{
+		{"three", 3, "Aw"},
+		{"seventeen", 17, "EQ"},
+		{"default", 65537, "AQAB"},
+		{"above default", 65539, "AQAD"},
+		{"maximum", MaxPublicExponent, "f____w"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey := &rsa.PublicKey{N: privateKey.N, E: tt.exponent}
+			keyID := uuid.New()
+
+			original, err := NewJWKS(publicKey, keyID)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			data, err := json.Marshal(original)
+			if err != nil {
+				t.Fatalf("Failed to marshal JWKS: %v", err)
+			}
+
+			var encoded encodedJWKS
+			if err := json.Unmarshal(data, &encoded); err != nil {
+				t.Fatalf("Failed to decode JWKS JSON: %v", err)
+			}
+			if encoded.Keys[0].E != tt.encoded {
+				t.Errorf("Expected exponent encoded as %q, got %q", tt.encoded, encoded.Keys[0].E)
+			}
+
+			var decoded JWKS
+			if err := decoded.UnmarshalJSON(data); err != nil {
+				t.Fatalf("Expected round trip to succeed, got: %v", err)
+			}
+			roundTripped, err := decoded.GetPublicKey(keyID)
+			if err != nil {
+				t.Fatalf("Expected key after round trip, got: %v", err)
+			}
+			if roundTripped.E != tt.exponent {
+				t.Errorf("Expected exponent %d after round trip, got %d", tt.exponent, roundTripped.E)
+			}
+			if !PublicKeyEqual(roundTripped, publicKey) {
+				t.Error("Round-tripped public key does not match the original")
+			}
+		})
+	}
+}
+
+func TestNewJWKS_InvalidExponent(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	for _, exponent := range []int{-3, 0, 1, 2, 65536} {
+		_, err := NewJWKS(&rsa.PublicKey{N: privateKey.N, E: exponent}, uuid.New())
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError for exponent %d, got %T", exponent, err)
+		}
+	}
+}
+
+func TestJWKS_ExponentTooLargeInJSON(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	modulus := base64urlUIntEncode(privateKey.N)
+
+	for _, exponent := range []*big.Int{
+		big.NewInt(MaxPublicExponent + 2),
+		new(big.Int).Lsh(big.NewInt(1), 64), // would truncate to 0 if converted unchecked
+	} {
+		data := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"%s","n":"%s","e":"%s"}]}`,
+			uuid.New(), modulus, base64urlUIntEncode(exponent))
+
+		var keySet JWKS
+		err := keySet.UnmarshalJSON([]byte(data))
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError for exponent %s, got %T", exponent, err)
+		}
+	}
+}
+
+func TestJWKS_ExponentFromJWXToolGenerate(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keyID := uuid.New()
+	modulus := base64.StdEncoding.EncodeToString(privateKey.N.Bytes())
+
+	cmd := exec.Command("bash", "-c", fmt.Sprintf("cd ../../jwx/tool && echo '%s' | go run . generate --exponent 17 %s", modulus, keyID))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(err.Error(), "executable file not found") ||
+			strings.Contains(string(output), "cannot find") {
+			t.Skip("jwx tool not available, skipping validation test")
+		}
+		t.Fatalf("jwx tool failed: %s, error: %v", string(output), err)
+	}
+
+	var keySet JWKS
+	if err := keySet.UnmarshalJSON(output); err != nil {
+		t.Fatalf("Expected generated JWKS to parse, got: %v", err)
+	}
+	publicKey, err := keySet.GetPublicKey(keyID)
+	if err != nil {
+		t.Fatalf("Expected generated key, got: %v", err)
+	}
+	if !PublicKeyEqual(publicKey, &rsa.PublicKey{N: privateKey.N, E: 17}) {
+		t.Errorf("Expected generated key to carry exponent 17, got %d", publicKey.E)
+	}
+}
+
+func TestVerifyMatchesKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	publicKey := &privateKey.PublicKey
+	kid := uuid.New()
+
+	encode := func(publicKey *rsa.PublicKey, kid uuid.UUID, label string) []byte {
+		keySet, err := NewJWKSWithLabel(publicKey, kid, label)
+		if err != nil {
+			t.Fatalf("Failed to create JWKS: %v", err)
+		}
+		data, err := json.Marshal(keySet)
+		if err != nil {
+			t.Fatalf("Failed to marshal JWKS: %v", err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		expectError string
+	}{
+		{name: "matching key", data: encode(publicKey, kid, "")},
+		{name: "matching key with label", data: encode(publicKey, kid, "prod-signer")},
+		{name: "different kid", data: encode(publicKey, uuid.New(), ""), expectError: "key ID"},
+		{name: "different modulus", data: encode(&otherKey.PublicKey, kid, ""), expectError: "modulus"},
+		{name: "different exponent", data: encode(&rsa.PublicKey{N: publicKey.N, E: 3}, kid, ""), expectError: "exponent"},
+		{
+			name: "non-canonical modulus encoding",
+			data: fmt.Appendf(nil, `{"keys":[{"kty":"RSA","kid":"%s","n":"%s","e":"AQAB"}]}`,
+				kid, base64.RawURLEncoding.EncodeToString(append([]byte{0}, publicKey.N.Bytes()...))),
+			expectError: "round-trip",
+		},
+		{name: "invalid JSON", data: []byte(`{"keys":`), expectError: "invalid JWKS JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyMatchesKey(tt.data, publicKey, kid)
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error mentioning %q, got: %v", tt.expectError, err)
+			}
+		})
+	}
+
+	if err := VerifyMatchesKey(encode(publicKey, kid, ""), nil, kid); err == nil {
+		t.Error("Expected error for nil expected key")
+	}
+}
+
+func TestJWKS_MarshalJSONIndent(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keyID := uuid.New()
+	keySet, err := NewJWKSWithLabel(&privateKey.PublicKey, keyID, "prod-signer")
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+
+	compact, err := keySet.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	pretty, err := keySet.MarshalJSONIndent("", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal indented JWKS: %v", err)
+	}
+
+	if !strings.Contains(string(pretty), "\n  \"keys\": [") {
+		t.Errorf("Expected indented output, got:\n%s", pretty)
+	}
+
+	// Only whitespace differs, so the canonical member order is preserved
+	var recompacted bytes.Buffer
+	if err := json.Compact(&recompacted, pretty); err != nil {
+		t.Fatalf("Failed to compact indented JWKS: %v", err)
+	}
+	if recompacted.String() != string(compact) {
+		t.Errorf("Expected compacted output %s, got %s", compact, recompacted.String())
+	}
+
+	var decoded JWKS
+	if err := decoded.UnmarshalJSON(pretty); err != nil {
+		t.Fatalf("Expected indented JWKS to parse, got: %v", err)
+	}
+	if decoded.GetKeyID() != keyID {
+		t.Errorf("Expected key ID %s, got %s", keyID, decoded.GetKeyID())
+	}
+}
+
+func TestJWKS_UnmarshalErrorNamesField(t *testing.T) {
+	keyID := uuid.New().String()
+	modulus := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbPFRP_gdM_X7zVFQ84l8g7hQg-jC6SGODpEcF7yR3xNgQBKzAV-OdSQ"
+
+	tests := []struct {
+		name        string
+		jsonStr     string
+		expectError string
+	}{
+		{
+			name:        "kid not a UUID",
+			jsonStr:     `{"keys":[{"kty":"RSA","kid":"not-a-uuid","n":"` + modulus + `","e":"AQAB"}]}`,
+			expectError: "keys[0].kid: must be a UUID",
+		},
+		{
+			name:        "kid wrong type",
+			jsonStr:     `{"keys":[{"kty":"RSA","kid":123,"n":"` + modulus + `","e":"AQAB"}]}`,
+			expectError: "keys[0].kid: must be a string",
+		},
+		{
+			name:        "n not base64url",
+			jsonStr:     `{"keys":[{"kty":"RSA","kid":"` + keyID + `","n":"not base64!","e":"AQAB"}]}`,
+			expectError: "keys[0].n:",
+		},
+		{
+			name:        "missing e",
+			jsonStr:     `{"keys":[{"kty":"RSA","kid":"` + keyID + `","n":"` + modulus + `"}]}`,
+			expectError: "keys[0].e: is missing",
+		},
+		{
+			name:        "even e",
+			jsonStr:     `{"keys":[{"kty":"RSA","kid":"` + keyID + `","n":"` + modulus + `","e":"AQAA"}]}`,
+			expectError: "keys[0].e:",
+		},
+		{
+			name:        "wrong kty",
+			jsonStr:     `{"keys":[{"kty":"EC","kid":"` + keyID + `","n":"` + modulus + `","e":"AQAB"}]}`,
+			expectError: "keys[0].kty: must be 'RSA'",
+		},
+		{
+			name:        "unexpected field",
+			jsonStr:     `{"keys":[{"kty":"RSA","kid":"` + keyID + `","n":"` + modulus + `","e":"AQAB","use":"sig"}]}`,
+			expectError: "keys[0].use: unexpected field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var jwks JWKS
+			err := json.Unmarshal([]byte(tt.jsonStr), &jwks)
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error mentioning %q, got: %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestMarshalKeySet(t *testing.T) {
+	var sets []*JWKS
+	for range 2 {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		keySet, err := NewJWKS(&privateKey.PublicKey, uuid.New())
+		if err != nil {
+			t.Fatalf("Failed to create JWKS: %v", err)
+		}
+		sets = append(sets, keySet)
+	}
+
+	data, err := MarshalKeySet(sets)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var decoded encodedJWKS
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode combined JWKS: %v", err)
+	}
+	if len(decoded.Keys) != len(sets) {
+		t.Fatalf("Expected %d keys, got %d", len(sets), len(decoded.Keys))
+	}
+	for i, set := range sets {
+		single, err := set.MarshalJSON()
+		if err != nil {
+			t.Fatalf("Failed to marshal JWKS: %v", err)
+		}
+		var expected encodedJWKS
+		if err := json.Unmarshal(single, &expected); err != nil {
+			t.Fatalf("Failed to decode JWKS: %v", err)
+		}
+		if decoded.Keys[i] != expected.Keys[0] {
+			t.Errorf("Expected key %d to match its single-key JWKS, got %+v", i, decoded.Keys[i])
+		}
+	}
+
+	if _, err := MarshalKeySet([]*JWKS{nil}); err == nil {
+		t.Error("Expected error for a nil JWKS")
+	}
+}
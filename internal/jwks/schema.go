@@ -0,0 +1,72 @@
+package jwks
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of a serialized JWKS, derived from the
+// types MarshalJSON encodes, so clients can generate code against the exact response contract.
+// Only the constraints Go types cannot express are added by hand: the single key, and kty.
+func JSONSchema() map[string]interface{} {
+	schema := StructSchema(reflect.TypeOf(encodedJWKS{}))
+
+	keys := schema["properties"].(map[string]interface{})["keys"].(map[string]interface{})
+	keys["minItems"] = 1
+	keys["maxItems"] = 1
+
+	key := keys["items"].(map[string]interface{})
+	key["properties"].(map[string]interface{})["kty"] = map[string]interface{}{"type": "string", "const": "RSA"}
+	return schema
+}
+
+// StructSchema derives a JSON Schema object from a struct type's json tags. Fields without
+// omitempty are required, and no other members are allowed. Strings, UUIDs and other text
+// marshalers, slices and nested structs are supported; it panics on any other field type, since
+// the schemas it describes are fixed at compile time.
+func StructSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		properties[name] = typeSchema(field.Type)
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t.Implements(textMarshalerType) {
+		if t == reflect.TypeFor[uuid.UUID]() {
+			return map[string]interface{}{"type": "string", "format": "uuid"}
+		}
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return StructSchema(t)
+	}
+	panic("jwks: unsupported schema field type " + t.String())
+}
@@ -0,0 +1,60 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestJSONSchema_MatchesMarshaledJWKS(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keySet, err := NewJWKSWithLabel(&privateKey.PublicKey, uuid.New(), "prod-signer")
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	data, err := keySet.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	var document struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Failed to decode JWKS: %v", err)
+	}
+
+	schema := JSONSchema()
+	if !slices.Equal(schema["required"].([]string), []string{"keys"}) {
+		t.Errorf("Expected keys to be required, got %v", schema["required"])
+	}
+	keys := schema["properties"].(map[string]interface{})["keys"].(map[string]interface{})
+	if keys["type"] != "array" || keys["minItems"] != 1 || keys["maxItems"] != 1 {
+		t.Errorf("Expected a single-item keys array, got %v", keys)
+	}
+
+	key := keys["items"].(map[string]interface{})
+	properties := key["properties"].(map[string]interface{})
+	for member := range document.Keys[0] {
+		if _, ok := properties[member]; !ok {
+			t.Errorf("Serialized member %q missing from schema", member)
+		}
+	}
+	required := key["required"].([]string)
+	slices.Sort(required)
+	if !slices.Equal(required, []string{"e", "kid", "kty", "n"}) {
+		t.Errorf("Expected kty, kid, n and e to be required, got %v", required)
+	}
+	if kid := properties["kid"].(map[string]interface{}); kid["format"] != "uuid" {
+		t.Errorf("Expected kid to have uuid format, got %v", kid)
+	}
+	if kty := properties["kty"].(map[string]interface{}); kty["const"] != "RSA" {
+		t.Errorf("Expected kty to be constant RSA, got %v", kty)
+	}
+}
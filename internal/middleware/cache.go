@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+const (
+	// DefaultCachedDriverTTL is the lifetime of a cached lookup applied when CachedDriver.TTL is 0
+	DefaultCachedDriverTTL = time.Minute
+
+	// DefaultCachedDriverSize is the entry cap applied when CachedDriver.MaxEntries is 0
+	DefaultCachedDriverSize = 1000
+)
+
+// CachedDriver is a DatabaseDriver that caches the lookups of an underlying Driver, so caching
+// composes with any backend instead of living in the handler. Found keys (including revoked
+// ones) are cached for TTL. KeyNotFoundErrors are cached for NegativeTTL only when it is set;
+// every other error is returned uncached, so a database outage is never remembered.
+//
+// It implements KeyInvalidator, so revocations routed through JWKSRouterConfig.Revocations
+// drop both positive and negative entries. A lookup still in flight when Invalidate is called
+// is returned to its caller but not cached, so it cannot restore a pre-revocation answer. It
+// is safe for concurrent use.
+type CachedDriver struct {
+	Driver DatabaseDriver
+
+	// TTL is how long a found key is served from the cache. 0 = DefaultCachedDriverTTL applied.
+	TTL time.Duration
+
+	// NegativeTTL is how long a KeyNotFoundError is served from the cache, shielding the
+	// backend from repeated lookups of unknown kids. 0 = negative caching disabled.
+	NegativeTTL time.Duration
+
+	// MaxEntries caps the number of cached lookups. When full, expired entries are swept and,
+	// if none were, new lookups go uncached until space frees up. 0 = DefaultCachedDriverSize applied.
+	MaxEntries int
+
+	now func() time.Time // for tests; nil = time.Now
+
+	mu      sync.Mutex
+	entries map[string]cachedLookup
+	// generation counts Invalidate calls; lookups that started before the latest are not cached
+	generation uint64
+}
+
+type cachedLookup struct {
+	result    *KeyLookupResult // nil = cached KeyNotFoundError
+	err       error
+	expiresAt time.Time
+}
+
+func (c *CachedDriver) GetKey(ctx context.Context, kid string) (*KeyLookupResult, error) {
+	if entry, ok := c.get(kid); ok {
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		result := *entry.result
+		return &result, nil
+	}
+
+	generation := c.currentGeneration()
+	result, err := c.Driver.GetKey(ctx, kid)
+	if err != nil {
+		if _, ok := err.(*errors.KeyNotFoundError); ok && c.NegativeTTL > 0 {
+			c.put(kid, cachedLookup{err: err}, c.NegativeTTL, generation)
+		}
+		return nil, err
+	}
+
+	// A nil result is a driver contract violation; leave it for the handler to report
+	if result != nil {
+		cached := *result
+		c.put(kid, cachedLookup{result: &cached}, c.ttl(), generation)
+	}
+	return result, nil
+}
+
+// Invalidate drops any cached lookup, positive or negative, for kid.
+func (c *CachedDriver) Invalidate(kid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, kid)
+	c.generation++
+}
+
+func (c *CachedDriver) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+func (c *CachedDriver) get(kid string) (cachedLookup, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[kid]
+	if !ok {
+		return cachedLookup{}, false
+	}
+	if !c.clock().Before(entry.expiresAt) {
+		delete(c.entries, kid)
+		return cachedLookup{}, false
+	}
+	return entry, true
+}
+
+// put caches entry for kid unless Invalidate has been called since the lookup began at
+// generation, in which case the result may predate a revocation.
+func (c *CachedDriver) put(kid string, entry cachedLookup, ttl time.Duration, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+
+	now := c.clock()
+	entry.expiresAt = now.Add(ttl)
+
+	if c.entries == nil {
+		c.entries = make(map[string]cachedLookup)
+	}
+	if _, exists := c.entries[kid]; !exists && len(c.entries) >= c.maxEntries() {
+		for cachedKid, cached := range c.entries {
+			if !now.Before(cached.expiresAt) {
+				delete(c.entries, cachedKid)
+			}
+		}
+		if len(c.entries) >= c.maxEntries() {
+			return
+		}
+	}
+	c.entries[kid] = entry
+}
+
+func (c *CachedDriver) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultCachedDriverTTL
+	}
+	return c.TTL
+}
+
+func (c *CachedDriver) maxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultCachedDriverSize
+	}
+	return c.MaxEntries
+}
+
+func (c *CachedDriver) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"math/big"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// countingDriver returns a fixed result/error and counts the lookups reaching it
+func countingDriver(calls *atomic.Int64, result *KeyLookupResult, err error) *MockDatabaseDriver {
+	return &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			calls.Add(1)
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		},
+	}
+}
+
+func TestCachedDriver_CachesFoundKeyUntilTTL(t *testing.T) {
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetInt64(12345), E: 65537}
+	var calls atomic.Int64
+	now := time.Now()
+	driver := &CachedDriver{
+		Driver: countingDriver(&calls, &KeyLookupResult{PublicKey: publicKey}, nil),
+		TTL:    time.Minute,
+		now:    func() time.Time { return now },
+	}
+
+	for range 3 {
+		result, err := driver.GetKey(context.Background(), "kid")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result.PublicKey != publicKey {
+			t.Error("Expected the cached public key")
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 backend lookup within TTL, got %d", calls.Load())
+	}
+
+	// Callers mutating a result must not corrupt the cache
+	result, _ := driver.GetKey(context.Background(), "kid")
+	result.Revoked = true
+	if result, _ := driver.GetKey(context.Background(), "kid"); result.Revoked {
+		t.Error("Expected cached result to be unaffected by caller mutation")
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := driver.GetKey(context.Background(), "kid"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected expired entry to be looked up again, got %d lookups", calls.Load())
+	}
+}
+
+func TestCachedDriver_NegativeCaching(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		var calls atomic.Int64
+		driver := &CachedDriver{Driver: countingDriver(&calls, nil, errors.NewKeyNotFoundError("not found"))}
+
+		for range 3 {
+			if _, err := driver.GetKey(context.Background(), "kid"); err == nil {
+				t.Fatal("Expected KeyNotFoundError, got nil")
+			}
+		}
+		if calls.Load() != 3 {
+			t.Errorf("Expected every miss to reach the backend, got %d lookups", calls.Load())
+		}
+	})
+
+	t.Run("enabled with NegativeTTL", func(t *testing.T) {
+		var calls atomic.Int64
+		now := time.Now()
+		driver := &CachedDriver{
+			Driver:      countingDriver(&calls, nil, errors.NewKeyNotFoundError("not found")),
+			NegativeTTL: 10 * time.Second,
+			now:         func() time.Time { return now },
+		}
+
+		for range 3 {
+			_, err := driver.GetKey(context.Background(), "kid")
+			if _, ok := err.(*errors.KeyNotFoundError); !ok {
+				t.Fatalf("Expected KeyNotFoundError, got %T", err)
+			}
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected 1 backend lookup within NegativeTTL, got %d", calls.Load())
+		}
+
+		now = now.Add(10 * time.Second)
+		driver.GetKey(context.Background(), "kid")
+		if calls.Load() != 2 {
+			t.Errorf("Expected expired negative entry to be looked up again, got %d lookups", calls.Load())
+		}
+	})
+}
+
+func TestCachedDriver_OtherErrorsNotCached(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{"database unavailable", errors.NewDatabaseUnavailableError("down")},
+		{"database timeout", errors.NewDatabaseTimeoutError("slow")},
+		{"deadline exceeded", context.DeadlineExceeded},
+		{"internal", errors.NewInternalError("boom")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int64
+			driver := &CachedDriver{
+				Driver:      countingDriver(&calls, nil, tc.err),
+				NegativeTTL: time.Minute,
+			}
+
+			for range 3 {
+				if _, err := driver.GetKey(context.Background(), "kid"); err != tc.err {
+					t.Fatalf("Expected %v, got %v", tc.err, err)
+				}
+			}
+			if calls.Load() != 3 {
+				t.Errorf("Expected errors to go uncached, got %d lookups", calls.Load())
+			}
+		})
+	}
+}
+
+func TestCachedDriver_Invalidate(t *testing.T) {
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetInt64(12345), E: 65537}
+	var calls atomic.Int64
+	driver := &CachedDriver{Driver: countingDriver(&calls, &KeyLookupResult{PublicKey: publicKey}, nil)}
+
+	var _ KeyInvalidator = driver
+
+	driver.GetKey(context.Background(), "kid")
+	driver.Invalidate("kid")
+	driver.GetKey(context.Background(), "kid")
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected invalidated entry to be looked up again, got %d lookups", calls.Load())
+	}
+}
+
+func TestCachedDriver_InvalidateDuringLookup(t *testing.T) {
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetInt64(12345), E: 65537}
+
+	testCases := []struct {
+		name   string
+		result *KeyLookupResult
+		err    error
+	}{
+		{"found key", &KeyLookupResult{PublicKey: publicKey}, nil},
+		{"key not found", nil, errors.NewKeyNotFoundError("not found")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int64
+			var driver *CachedDriver
+			driver = &CachedDriver{
+				Driver: &MockDatabaseDriver{
+					GetKeyFunc: func(ctx context.Context, kid string) (*KeyLookupResult, error) {
+						// The revocation lands while the first lookup is in flight
+						if calls.Add(1) == 1 {
+							driver.Invalidate(kid)
+						}
+						return tc.result, tc.err
+					},
+				},
+				NegativeTTL: time.Minute,
+			}
+
+			driver.GetKey(context.Background(), "kid")
+			driver.GetKey(context.Background(), "kid")
+			if calls.Load() != 2 {
+				t.Errorf("Expected the in-flight lookup to go uncached, got %d lookups", calls.Load())
+			}
+
+			// Lookups starting after the invalidation are cached again
+			driver.GetKey(context.Background(), "kid")
+			if calls.Load() != 2 {
+				t.Errorf("Expected later lookups to be cached, got %d lookups", calls.Load())
+			}
+		})
+	}
+}
+
+func TestCachedDriver_MaxEntries(t *testing.T) {
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetInt64(12345), E: 65537}
+	var calls atomic.Int64
+	now := time.Now()
+	driver := &CachedDriver{
+		Driver:     countingDriver(&calls, &KeyLookupResult{PublicKey: publicKey}, nil),
+		TTL:        time.Minute,
+		MaxEntries: 2,
+		now:        func() time.Time { return now },
+	}
+
+	driver.GetKey(context.Background(), "a")
+	driver.GetKey(context.Background(), "b")
+	driver.GetKey(context.Background(), "c") // cache full, not cached
+	driver.GetKey(context.Background(), "c")
+	if calls.Load() != 4 {
+		t.Errorf("Expected lookups beyond MaxEntries to go uncached, got %d lookups", calls.Load())
+	}
+
+	// Once the entries expire they are swept to make room
+	now = now.Add(time.Minute)
+	driver.GetKey(context.Background(), "c")
+	driver.GetKey(context.Background(), "c")
+	if calls.Load() != 5 {
+		t.Errorf("Expected expired entries to be swept, got %d lookups", calls.Load())
+	}
+}
+
+func TestCachedDriver_ConcurrentUse(t *testing.T) {
+	publicKey := &rsa.PublicKey{N: new(big.Int).SetInt64(12345), E: 65537}
+	var calls atomic.Int64
+	driver := &CachedDriver{
+		Driver:      countingDriver(&calls, &KeyLookupResult{PublicKey: publicKey}, nil),
+		NegativeTTL: time.Minute,
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			kid := strconv.Itoa(i % 5)
+			if _, err := driver.GetKey(context.Background(), kid); err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			if i%10 == 0 {
+				driver.Invalidate(kid)
+			}
+		}()
+	}
+	wg.Wait()
+}
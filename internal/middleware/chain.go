@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// ChainDriver is a DatabaseDriver that consults several drivers in order, e.g. a fast cache
+// in front of a slower authoritative store. The first driver that finds the key wins; a
+// KeyNotFoundError (or a nil result) falls through to the next driver. A revoked result is
+// also definitive, even without a PublicKey, so a stale store later in the chain cannot serve
+// a key that an earlier driver has revoked.
+type ChainDriver struct {
+	Drivers []DatabaseDriver
+
+	// FallThroughOnError controls what happens when a driver returns an error other than
+	// KeyNotFoundError. By default the chain stops and returns that error. When true, the
+	// remaining drivers are still tried, and the first such error is returned only if none
+	// of them finds the key.
+	FallThroughOnError bool
+}
+
+func (c *ChainDriver) GetKey(ctx context.Context, kid string) (*KeyLookupResult, error) {
+	var firstErr error
+	for _, driver := range c.Drivers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result, err := driver.GetKey(ctx, kid)
+		if err != nil {
+			if _, ok := err.(*errors.KeyNotFoundError); ok {
+				continue
+			}
+			if !c.FallThroughOnError || err == context.DeadlineExceeded {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if result == nil || (result.PublicKey == nil && !result.Revoked) {
+			continue
+		}
+		return result, nil
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, errors.NewKeyNotFoundError("key not found in any driver")
+}
+
+// Invalidate forwards the invalidation to every chained driver that caches lookups.
+func (c *ChainDriver) Invalidate(kid string) {
+	for _, driver := range c.Drivers {
+		if invalidator, ok := driver.(KeyInvalidator); ok {
+			invalidator.Invalidate(kid)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+func TestChainDriver_CacheMiss_FallsThroughToDB(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	dbCalled := false
+	cache := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return nil, errors.NewKeyNotFoundError("cache miss")
+		},
+	}
+	db := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			dbCalled = true
+			return &KeyLookupResult{PublicKey: publicKey, Revoked: false}, nil
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{
+		DB:      &ChainDriver{Drivers: []DatabaseDriver{cache, db}},
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/"+uuid.New().String()+"/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !dbCalled {
+		t.Error("Expected DB to be consulted after cache miss")
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestChainDriver_FirstHit_SkipsRemainingDrivers(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	cache := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return &KeyLookupResult{PublicKey: publicKey, Revoked: true}, nil
+		},
+	}
+	db := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			t.Error("Expected DB not to be consulted after cache hit")
+			return nil, nil
+		},
+	}
+
+	chain := &ChainDriver{Drivers: []DatabaseDriver{cache, db}}
+	result, err := chain.GetKey(context.Background(), uuid.New().String())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Revoked {
+		t.Error("Expected revoked result from the first driver to be returned unchanged")
+	}
+}
+
+func TestChainDriver_RevocationOverridesLaterDrivers(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	// The front layer records the revocation without keeping the key
+	revocations := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return &KeyLookupResult{Revoked: true}, nil
+		},
+	}
+	staleDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			t.Error("Expected stale DB not to be consulted after a revocation")
+			return &KeyLookupResult{PublicKey: publicKey}, nil
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{
+		DB:      &ChainDriver{Drivers: []DatabaseDriver{revocations, staleDB}},
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/"+uuid.New().String()+"/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a revoked key, got %d", rr.Code)
+	}
+}
+
+func TestChainDriver_AllMiss_ReturnsKeyNotFound(t *testing.T) {
+	miss := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return nil, errors.NewKeyNotFoundError("miss")
+		},
+	}
+	empty := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return nil, nil
+		},
+	}
+
+	chain := &ChainDriver{Drivers: []DatabaseDriver{miss, empty}}
+	_, err := chain.GetKey(context.Background(), uuid.New().String())
+	if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T", err)
+	}
+}
+
+func TestChainDriver_HardErrorPolicy(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	failing := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return nil, errors.NewDatabaseUnavailableError("cache down")
+		},
+	}
+	db := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return &KeyLookupResult{PublicKey: publicKey}, nil
+		},
+	}
+	miss := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return nil, errors.NewKeyNotFoundError("miss")
+		},
+	}
+
+	t.Run("stops on hard error by default", func(t *testing.T) {
+		chain := &ChainDriver{Drivers: []DatabaseDriver{failing, db}}
+		_, err := chain.GetKey(context.Background(), uuid.New().String())
+		if _, ok := err.(*errors.DatabaseUnavailableError); !ok {
+			t.Errorf("Expected DatabaseUnavailableError, got %T", err)
+		}
+	})
+
+	t.Run("falls through on hard error when configured", func(t *testing.T) {
+		chain := &ChainDriver{Drivers: []DatabaseDriver{failing, db}, FallThroughOnError: true}
+		result, err := chain.GetKey(context.Background(), uuid.New().String())
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result.PublicKey != publicKey {
+			t.Error("Expected public key from the second driver")
+		}
+	})
+
+	t.Run("returns hard error when nothing else finds the key", func(t *testing.T) {
+		chain := &ChainDriver{Drivers: []DatabaseDriver{failing, miss}, FallThroughOnError: true}
+		_, err := chain.GetKey(context.Background(), uuid.New().String())
+		if _, ok := err.(*errors.DatabaseUnavailableError); !ok {
+			t.Errorf("Expected DatabaseUnavailableError, got %T", err)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rsa"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -14,9 +15,25 @@ import (
 	internaljwks "github.com/susu-dot-dev/japikey/internal/jwks"
 )
 
+// maxKIDLength bounds the kid accepted before any work is done. A UUID is 36 characters (45 in
+// its longest urn:uuid: form), so anything longer cannot be a valid key ID.
+const maxKIDLength = 64
+
 type KeyLookupResult struct {
 	PublicKey *rsa.PublicKey
 	Revoked   bool
+	RevokedAt time.Time // when the key was revoked; required for RevocationGrace to apply
+	MaxAge    *int      // optional; overrides the router-wide MaxAgeSeconds for this key, negative values clamped to 0
+	Label     string    // optional; published as the informational x-label member, never used for security decisions
+
+	// NotBefore is when the key becomes active for signing, for make-before-break rotation. It
+	// does not gate serving: a pre-published key is served before then so clients can cache it.
+	// Verifiers enforce it through VerifyConfig.KeyActivation. Zero = active immediately.
+	NotBefore time.Time
+
+	// LastModified is when the key's record last changed, sent as Last-Modified so caches can
+	// revalidate with If-Modified-Since and get a 304. Zero = no Last-Modified header.
+	LastModified time.Time
 }
 
 type ErrorResponse struct {
@@ -26,10 +43,22 @@ type ErrorResponse struct {
 
 type JWKSRouterConfig struct {
 	DB            DatabaseDriver
-	MaxAgeSeconds int           // 0 = no caching, negative values clamped to 0
-	Timeout       time.Duration // 0 = 5-second default applied
+	MaxAgeSeconds int                // 0 = no caching, negative values clamped to 0
+	Timeout       time.Duration      // 0 = 5-second default applied
+	Revocations   RevocationNotifier // optional; revoked kids are invalidated in DB if it implements KeyInvalidator
+
+	// RevocationGrace keeps serving a revoked key until RevokedAt + RevocationGrace, so that
+	// in-flight requests using its token (necessarily issued before RevokedAt) keep working.
+	// 0 = revocation takes effect immediately.
+	RevocationGrace time.Duration
+
+	// RetryAfter is sent as a Retry-After header (rounded up to whole seconds) on 503 responses,
+	// so clients back off during database incidents. 0 = no header.
+	RetryAfter time.Duration
 }
 
+// DatabaseDriver looks up the public key for a kid. Unknown kids must be reported with a
+// KeyNotFoundError; returning no key without an error is logged as a contract violation.
 type DatabaseDriver interface {
 	GetKey(ctx context.Context, kid string) (*KeyLookupResult, error)
 }
@@ -49,8 +78,15 @@ func CreateJWKSRouter(config JWKSRouterConfig) (http.Handler, error) {
 
 	handler := &JWKSHandler{JWKSRouterConfig: config}
 
+	if config.Revocations != nil {
+		if invalidator, ok := config.DB.(KeyInvalidator); ok {
+			config.Revocations.Subscribe(invalidator.Invalidate)
+		}
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/{kid}/.well-known/jwks.json", handler.ServeHTTP)
+	mux.HandleFunc("/.well-known/jwks.json", handler.ServeKIDQuery)
 
 	return mux, nil
 }
@@ -62,6 +98,22 @@ func clampMaxAge(maxAge int) int {
 	return maxAge
 }
 
+// revocationGraceRemaining returns how much longer a key revoked at revokedAt may be served.
+func (h *JWKSHandler) revocationGraceRemaining(revokedAt time.Time, now time.Time) time.Duration {
+	if h.RevocationGrace <= 0 || revokedAt.IsZero() {
+		return 0
+	}
+	return revokedAt.Add(h.RevocationGrace).Sub(now)
+}
+
+// describeError renders err for the log, including its code when it is a JapikeyError.
+func describeError(err error) string {
+	if stringer, ok := err.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return err.Error()
+}
+
 func sendErrorResponse(w http.ResponseWriter, statusCode int, code, message string) {
 	w.WriteHeader(statusCode)
 	if err := json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: message}); err != nil {
@@ -69,7 +121,62 @@ func sendErrorResponse(w http.ResponseWriter, statusCode int, code, message stri
 	}
 }
 
+// sendUnavailableResponse sends a 503 error response, with a Retry-After header if configured.
+func (h *JWKSHandler) sendUnavailableResponse(w http.ResponseWriter, code, message string) {
+	if h.RetryAfter > 0 {
+		seconds := int((h.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	sendErrorResponse(w, http.StatusServiceUnavailable, code, message)
+}
+
 func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serveKey(w, r, r.PathValue("kid"))
+}
+
+// ServeKIDQuery serves the combined endpoint filtered by the kid query parameter, returning the
+// same single-key JWKS as the per-kid path. Unknown query parameters are ignored.
+// The full key set is never published here (DatabaseDriver cannot list keys), so the response is
+// always a single key no matter how many a driver holds. The static combined JWKS written by
+// japikey.PublishJWKS, which does list every key, is capped by MaxPublishedKeys instead.
+func (h *JWKSHandler) ServeKIDQuery(w http.ResponseWriter, r *http.Request) {
+	kid := r.URL.Query().Get("kid")
+	if kid == "" {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, http.StatusBadRequest, "ValidationError", "kid query parameter is required")
+		return
+	}
+	if _, err := uuid.Parse(kid); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, http.StatusBadRequest, "ValidationError", "kid query parameter must be a UUID")
+		return
+	}
+
+	h.serveKey(w, r, kid)
+}
+
+// notModifiedSince reports whether the request's If-Modified-Since covers lastModified. HTTP dates
+// have one-second resolution, so lastModified is truncated before comparing.
+func notModifiedSince(r *http.Request, lastModified time.Time) bool {
+	header := r.Header.Get("If-Modified-Since")
+	if header == "" {
+		return false
+	}
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+func (h *JWKSHandler) serveKey(w http.ResponseWriter, r *http.Request, kid string) {
+	// Reject oversized kids before touching the database
+	if len(kid) > maxKIDLength {
+		w.Header().Set("Content-Type", "application/json")
+		sendErrorResponse(w, http.StatusNotFound, "KeyNotFoundError", "API key not found")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
 	defer cancel()
 
@@ -79,57 +186,86 @@ func (h *JWKSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	select {
 	case <-ctx.Done():
 		if ctx.Err() == context.DeadlineExceeded {
-			sendErrorResponse(w, http.StatusServiceUnavailable, "Timeout", "Request timeout")
+			h.sendUnavailableResponse(w, "Timeout", "Request timeout")
 			return
 		}
 	default:
 	}
 
-	kid := r.PathValue("kid")
-
 	result, err := h.DB.GetKey(ctx, kid)
 	if err != nil {
 		if err == context.DeadlineExceeded {
-			sendErrorResponse(w, http.StatusServiceUnavailable, "Timeout", "Request timeout")
+			h.sendUnavailableResponse(w, "Timeout", "Request timeout")
 			return
 		}
 		switch err.(type) {
 		case *errors.KeyNotFoundError:
 			sendErrorResponse(w, http.StatusNotFound, "KeyNotFoundError", "API key not found")
 		case *errors.DatabaseTimeoutError:
-			log.Printf("[JWKS] Database timeout: %v", err)
-			sendErrorResponse(w, http.StatusServiceUnavailable, "InternalError", "Database temporarily unavailable")
+			log.Printf("[JWKS] Database timeout: %s", describeError(err))
+			h.sendUnavailableResponse(w, "InternalError", "Database temporarily unavailable")
 		case *errors.DatabaseUnavailableError:
-			log.Printf("[JWKS] Database unavailable: %v", err)
-			sendErrorResponse(w, http.StatusServiceUnavailable, "InternalError", "Database temporarily unavailable")
+			log.Printf("[JWKS] Database unavailable: %s", describeError(err))
+			h.sendUnavailableResponse(w, "InternalError", "Database temporarily unavailable")
 		default:
-			log.Printf("[JWKS] Database error: %v", err)
+			log.Printf("[JWKS] Database error: %s", describeError(err))
 			sendErrorResponse(w, http.StatusInternalServerError, "InternalError", "Internal server error")
 		}
 		return
 	}
 
-	if result == nil || result.PublicKey == nil || result.Revoked {
+	if result == nil || result.PublicKey == nil {
+		// Drivers must report a missing key with KeyNotFoundError; a result without a key (that
+		// is not a revocation) is a driver bug, logged so it does not pass as a routine 404
+		if result == nil || !result.Revoked {
+			log.Printf("[JWKS] DatabaseDriver contract violation: GetKey returned no public key and no error for kid %q", kid)
+		}
 		sendErrorResponse(w, http.StatusNotFound, "KeyNotFoundError", "API key not found")
 		return
 	}
 
+	maxAge := h.MaxAgeSeconds
+	if result.MaxAge != nil {
+		maxAge = clampMaxAge(*result.MaxAge)
+	}
+
+	if result.Revoked {
+		remaining := h.revocationGraceRemaining(result.RevokedAt, time.Now())
+		if remaining <= 0 {
+			sendErrorResponse(w, http.StatusNotFound, "KeyNotFoundError", "API key not found")
+			return
+		}
+		// Don't let caches hold the key past the end of the grace window
+		maxAge = min(maxAge, int(remaining/time.Second))
+	}
+	w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(maxAge))
+
 	kidUUID, err := uuid.Parse(kid)
 	if err != nil {
 		sendErrorResponse(w, http.StatusNotFound, "KeyNotFoundError", "API key not found")
 		return
 	}
 
-	jwks, err := internaljwks.NewJWKS(result.PublicKey, kidUUID)
+	if !result.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", result.LastModified.UTC().Format(http.TimeFormat))
+		if notModifiedSince(r, result.LastModified) {
+			// A 304 carries the cache headers but no body
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	jwks, err := internaljwks.NewJWKSWithLabel(result.PublicKey, kidUUID, result.Label)
 	if err != nil {
-		log.Printf("[JWKS] Error generating JWKS: %v", err)
+		log.Printf("[JWKS] Error generating JWKS: %s", describeError(err))
 		sendErrorResponse(w, http.StatusInternalServerError, "InternalError", "Internal server error")
 		return
 	}
 
 	jsonData, err := jwks.MarshalJSON()
 	if err != nil {
-		log.Printf("[JWKS] Error marshaling JWKS: %v", err)
+		log.Printf("[JWKS] Error marshaling JWKS: %s", describeError(err))
 		sendErrorResponse(w, http.StatusInternalServerError, "InternalError", "Internal server error")
 		return
 	}
@@ -1,19 +1,24 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"crypto/rsa"
 	"encoding/json"
 	"fmt"
+	"log"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/susu-dot-dev/japikey/errors"
+	internaljwks "github.com/susu-dot-dev/japikey/internal/jwks"
 )
 
 type MockDatabaseDriver struct {
@@ -1069,3 +1074,583 @@ func TestJWKSEndpoint_TimeoutWithCustomValue_UsesCustomTimeout(t *testing.T) {
 		t.Errorf("Expected timeout to trigger with 100ms timeout but 200ms delay")
 	}
 }
+
+func TestJWKSEndpoint_RevocationGrace(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	kid := uuid.New()
+
+	tests := []struct {
+		name           string
+		grace          time.Duration
+		revokedAt      time.Time
+		expectedStatus int
+		capsMaxAge     bool
+	}{
+		{"no grace revokes immediately", 0, time.Now(), http.StatusNotFound, false},
+		{"within grace still served", time.Hour, time.Now().Add(-30 * time.Minute), http.StatusOK, false},
+		{"within grace caps max-age", 10 * time.Minute, time.Now().Add(-9 * time.Minute), http.StatusOK, true},
+		{"after grace not served", time.Hour, time.Now().Add(-2 * time.Hour), http.StatusNotFound, false},
+		{"missing RevokedAt not served", time.Hour, time.Time{}, http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDatabaseDriver{
+				GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+					return &KeyLookupResult{PublicKey: publicKey, Revoked: true, RevokedAt: tt.revokedAt}, nil
+				},
+			}
+
+			handler, err := CreateJWKSRouter(JWKSRouterConfig{
+				DB:              mockDB,
+				MaxAgeSeconds:   300,
+				Timeout:         5 * time.Second,
+				RevocationGrace: tt.grace,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+
+			req, _ := http.NewRequest("GET", "/"+kid.String()+"/.well-known/jwks.json", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.capsMaxAge {
+				cacheControl := rr.Header().Get("Cache-Control")
+				maxAge, err := strconv.Atoi(strings.TrimPrefix(cacheControl, "max-age="))
+				if err != nil {
+					t.Fatalf("Unexpected Cache-Control %s", cacheControl)
+				}
+				if maxAge > 60 {
+					t.Errorf("Expected max-age capped to remaining grace, got %s", cacheControl)
+				}
+			}
+		})
+	}
+}
+
+func TestJWKSEndpoint_KIDQueryFilter(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	activeKid := uuid.New()
+	revokedKid := uuid.New()
+
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, kid string) (*KeyLookupResult, error) {
+			switch kid {
+			case activeKid.String():
+				return &KeyLookupResult{PublicKey: publicKey}, nil
+			case revokedKid.String():
+				return &KeyLookupResult{PublicKey: publicKey, Revoked: true}, nil
+			}
+			return nil, errors.NewKeyNotFoundError("not found")
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{
+		DB:      mockDB,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"active kid", "?kid=" + activeKid.String(), http.StatusOK, ""},
+		{"unknown query params ignored", "?foo=bar&kid=" + activeKid.String(), http.StatusOK, ""},
+		{"revoked kid", "?kid=" + revokedKid.String(), http.StatusNotFound, "KeyNotFoundError"},
+		{"unknown kid", "?kid=" + uuid.New().String(), http.StatusNotFound, "KeyNotFoundError"},
+		{"invalid kid", "?kid=not-a-uuid", http.StatusBadRequest, "ValidationError"},
+		{"missing kid", "", http.StatusBadRequest, "ValidationError"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/.well-known/jwks.json"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Expected Content-Type application/json, got %s", rr.Header().Get("Content-Type"))
+			}
+
+			if tt.expectedCode == "" {
+				var jwks map[string][]map[string]interface{}
+				if err := json.Unmarshal(rr.Body.Bytes(), &jwks); err != nil {
+					t.Fatalf("Failed to parse JWKS: %v", err)
+				}
+				if len(jwks["keys"]) != 1 || jwks["keys"][0]["kid"] != activeKid.String() {
+					t.Errorf("Expected single key for %s, got %s", activeKid, rr.Body.String())
+				}
+				return
+			}
+
+			var errResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("Failed to parse error response: %v", err)
+			}
+			if errResp.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, got %s", tt.expectedCode, errResp.Code)
+			}
+		})
+	}
+}
+
+func TestJWKSEndpoint_RetryAfterHeader(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	kid := uuid.New()
+
+	tests := []struct {
+		name           string
+		retryAfter     time.Duration
+		dbErr          error
+		expectedStatus int
+		expectedHeader string
+	}{
+		{"default off", 0, errors.NewDatabaseTimeoutError("timeout"), http.StatusServiceUnavailable, ""},
+		{"database timeout", 30 * time.Second, errors.NewDatabaseTimeoutError("timeout"), http.StatusServiceUnavailable, "30"},
+		{"database unavailable", 30 * time.Second, errors.NewDatabaseUnavailableError("down"), http.StatusServiceUnavailable, "30"},
+		{"rounded up to whole seconds", 1500 * time.Millisecond, errors.NewDatabaseUnavailableError("down"), http.StatusServiceUnavailable, "2"},
+		{"not sent on 404", 30 * time.Second, errors.NewKeyNotFoundError("missing"), http.StatusNotFound, ""},
+		{"not sent on 500", 30 * time.Second, fmt.Errorf("boom"), http.StatusInternalServerError, ""},
+		{"not sent on success", 30 * time.Second, nil, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDatabaseDriver{
+				GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+					if tt.dbErr != nil {
+						return nil, tt.dbErr
+					}
+					return &KeyLookupResult{PublicKey: publicKey}, nil
+				},
+			}
+
+			handler, err := CreateJWKSRouter(JWKSRouterConfig{
+				DB:         mockDB,
+				Timeout:    5 * time.Second,
+				RetryAfter: tt.retryAfter,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+
+			req, _ := http.NewRequest("GET", "/"+kid.String()+"/.well-known/jwks.json", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Header().Get("Retry-After") != tt.expectedHeader {
+				t.Errorf("Expected Retry-After %q, got %q", tt.expectedHeader, rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestJWKSEndpoint_PerKeyMaxAgeOverride(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	kid := uuid.New()
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name           string
+		keyMaxAge      *int
+		expectedHeader string
+	}{
+		{"no override uses router default", nil, "max-age=300"},
+		{"shorter override", intPtr(60), "max-age=60"},
+		{"longer override", intPtr(3600), "max-age=3600"},
+		{"zero override disables caching", intPtr(0), "max-age=0"},
+		{"negative override clamped", intPtr(-5), "max-age=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &MockDatabaseDriver{
+				GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+					return &KeyLookupResult{PublicKey: publicKey, MaxAge: tt.keyMaxAge}, nil
+				},
+			}
+
+			handler, err := CreateJWKSRouter(JWKSRouterConfig{
+				DB:            mockDB,
+				MaxAgeSeconds: 300,
+				Timeout:       5 * time.Second,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+
+			req, _ := http.NewRequest("GET", "/"+kid.String()+"/.well-known/jwks.json", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+			if rr.Header().Get("Cache-Control") != tt.expectedHeader {
+				t.Errorf("Expected Cache-Control %s, got %s", tt.expectedHeader, rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+
+	t.Run("override still capped by revocation grace", func(t *testing.T) {
+		mockDB := &MockDatabaseDriver{
+			GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+				return &KeyLookupResult{
+					PublicKey: publicKey,
+					Revoked:   true,
+					RevokedAt: time.Now().Add(-9 * time.Minute),
+					MaxAge:    intPtr(3600),
+				}, nil
+			},
+		}
+
+		handler, err := CreateJWKSRouter(JWKSRouterConfig{
+			DB:              mockDB,
+			MaxAgeSeconds:   300,
+			Timeout:         5 * time.Second,
+			RevocationGrace: 10 * time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create handler: %v", err)
+		}
+
+		req, _ := http.NewRequest("GET", "/"+kid.String()+"/.well-known/jwks.json", nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		maxAge, err := strconv.Atoi(strings.TrimPrefix(rr.Header().Get("Cache-Control"), "max-age="))
+		if err != nil || maxAge > 60 {
+			t.Errorf("Expected max-age capped to remaining grace, got %s", rr.Header().Get("Cache-Control"))
+		}
+	})
+}
+
+func TestJWKSEndpoint_KeyLabel(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	kid := uuid.New()
+
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return &KeyLookupResult{PublicKey: publicKey, Label: "prod-signer-2024Q1"}, nil
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{DB: mockDB, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/"+kid.String()+"/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var body struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Keys) != 1 || body.Keys[0]["x-label"] != "prod-signer-2024Q1" {
+		t.Errorf("Expected x-label in response, got %s", rr.Body.String())
+	}
+}
+
+func TestJWKSEndpoint_OversizedKid_Returns404WithoutDBAccess(t *testing.T) {
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			t.Error("Expected database not to be accessed for an oversized kid")
+			return nil, errors.NewKeyNotFoundError("not found")
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{
+		DB:            mockDB,
+		MaxAgeSeconds: 300,
+		Timeout:       5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	hugeKid := strings.Repeat("a", 1024*1024)
+
+	req, _ := http.NewRequest("GET", "/"+hugeKid+"/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for oversized path kid, got %d", rr.Code)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Code != "KeyNotFoundError" {
+		t.Errorf("Expected KeyNotFoundError code, got %s", response.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/.well-known/jwks.json?kid="+hugeKid, nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for oversized kid query parameter, got %d", rr.Code)
+	}
+}
+
+func TestJWKSEndpoint_DriverContractViolation_LoggedAs404(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name      string
+		result    *KeyLookupResult
+		expectLog bool
+	}{
+		{name: "nil result", result: nil, expectLog: true},
+		{name: "nil public key", result: &KeyLookupResult{}, expectLog: true},
+		{name: "revoked without key", result: &KeyLookupResult{Revoked: true}, expectLog: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			mockDB := &MockDatabaseDriver{
+				GetKeyFunc: func(ctx context.Context, kid string) (*KeyLookupResult, error) {
+					return tt.result, nil
+				},
+			}
+			handler, err := CreateJWKSRouter(JWKSRouterConfig{DB: mockDB, Timeout: 5 * time.Second})
+			if err != nil {
+				t.Fatalf("Failed to create handler: %v", err)
+			}
+
+			req, _ := http.NewRequest("GET", "/"+uuid.New().String()+"/.well-known/jwks.json", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", rr.Code)
+			}
+			var response ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Code != "KeyNotFoundError" {
+				t.Errorf("Expected code KeyNotFoundError, got %s", response.Code)
+			}
+
+			logged := strings.Contains(logs.String(), "contract violation")
+			if logged != tt.expectLog {
+				t.Errorf("Expected contract violation logged: %v, got logs: %q", tt.expectLog, logs.String())
+			}
+		})
+	}
+}
+
+func TestJWKSEndpoint_DatabaseErrorLoggedWithCode(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, kid string) (*KeyLookupResult, error) {
+			return nil, errors.NewDatabaseUnavailableError("connection refused")
+		},
+	}
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{DB: mockDB, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/"+uuid.New().String()+"/.well-known/jwks.json", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(logs.String(), "[DatabaseUnavailable] connection refused") {
+		t.Errorf("Expected the error code in the log, got %q", logs.String())
+	}
+}
+
+func TestJWKSEndpoint_PrePublishedKeyServed(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return &KeyLookupResult{PublicKey: publicKey, NotBefore: time.Now().Add(24 * time.Hour)}, nil
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{DB: mockDB, MaxAgeSeconds: 300, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/"+uuid.New().String()+"/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected a key that is not yet active to be served, got status %d", rr.Code)
+	}
+	if rr.Header().Get("Cache-Control") != "max-age=300" {
+		t.Errorf("Expected the router-wide max-age, got %s", rr.Header().Get("Cache-Control"))
+	}
+}
+
+func TestJWKSEndpoint_EachKidServesItsOwnKey(t *testing.T) {
+	previousKid, currentKid := uuid.New(), uuid.New()
+	keys := map[string]*rsa.PublicKey{
+		previousKid.String(): {N: new(big.Int).SetInt64(12345), E: 65537},
+		currentKid.String():  {N: new(big.Int).SetInt64(67891), E: 65537},
+	}
+
+	// The driver knows which key is current, but the handler must never serve it in place of
+	// the requested kid: tokens signed by the previous key must keep verifying during rotation
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, kid string) (*KeyLookupResult, error) {
+			publicKey, ok := keys[kid]
+			if !ok {
+				return nil, errors.NewKeyNotFoundError("key not found")
+			}
+			return &KeyLookupResult{PublicKey: publicKey}, nil
+		},
+	}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{DB: mockDB, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	for _, kid := range []uuid.UUID{previousKid, currentKid, previousKid} {
+		for _, path := range []string{"/" + kid.String() + "/.well-known/jwks.json", "/.well-known/jwks.json?kid=" + kid.String()} {
+			req, _ := http.NewRequest("GET", path, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200 for %s, got %d", path, rr.Code)
+			}
+			var keySet internaljwks.JWKS
+			if err := json.Unmarshal(rr.Body.Bytes(), &keySet); err != nil {
+				t.Fatalf("Failed to parse JWKS response for %s: %v", path, err)
+			}
+			publicKey, err := keySet.GetPublicKey(kid)
+			if err != nil {
+				t.Fatalf("Expected the JWKS for %s to hold kid %s, got: %v", path, kid, err)
+			}
+			if !internaljwks.PublicKeyEqual(publicKey, keys[kid.String()]) {
+				t.Errorf("Expected %s to serve the key for kid %s", path, kid)
+			}
+		}
+	}
+}
+
+func TestJWKSEndpoint_LastModified(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+	lastModified := time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+	var result KeyLookupResult
+	mockDB := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return &result, nil
+		},
+	}
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{DB: mockDB, MaxAgeSeconds: 300, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	tests := []struct {
+		name               string
+		lastModified       time.Time
+		ifModifiedSince    string
+		expectedStatus     int
+		expectLastModified string
+	}{
+		{"no modification time", time.Time{}, "", http.StatusOK, ""},
+		{"no modification time ignores conditional request", time.Time{}, lastModified.Format(http.TimeFormat), http.StatusOK, ""},
+		{"unconditional request", lastModified, "", http.StatusOK, "Sun, 01 Mar 2026 12:00:00 GMT"},
+		{"not modified", lastModified, "Sun, 01 Mar 2026 12:00:00 GMT", http.StatusNotModified, "Sun, 01 Mar 2026 12:00:00 GMT"},
+		{"not modified since later date", lastModified, "Mon, 02 Mar 2026 12:00:00 GMT", http.StatusNotModified, "Sun, 01 Mar 2026 12:00:00 GMT"},
+		{"modified", lastModified, "Sun, 01 Mar 2026 11:59:59 GMT", http.StatusOK, "Sun, 01 Mar 2026 12:00:00 GMT"},
+		{"invalid date", lastModified, "yesterday", http.StatusOK, "Sun, 01 Mar 2026 12:00:00 GMT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result = KeyLookupResult{PublicKey: publicKey, LastModified: tt.lastModified}
+			req, _ := http.NewRequest("GET", "/"+uuid.New().String()+"/.well-known/jwks.json", nil)
+			if tt.ifModifiedSince != "" {
+				req.Header.Set("If-Modified-Since", tt.ifModifiedSince)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Last-Modified"); got != tt.expectLastModified {
+				t.Errorf("Expected Last-Modified %q, got %q", tt.expectLastModified, got)
+			}
+			if rr.Header().Get("Cache-Control") != "max-age=300" {
+				t.Errorf("Expected Cache-Control max-age=300, got %q", rr.Header().Get("Cache-Control"))
+			}
+			if tt.expectedStatus == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("Expected no body on 304, got %q", rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import "sync"
+
+// RevocationNotifier delivers revocation events so the JWKS handler can react to a revoked
+// key immediately rather than waiting for a cache TTL to expire.
+type RevocationNotifier interface {
+	// Subscribe registers fn to be called with the kid of every key revoked from now on.
+	Subscribe(fn func(kid string))
+}
+
+// KeyInvalidator is implemented by DatabaseDrivers that cache lookups. When the handler
+// receives a revocation event it calls Invalidate, which must drop both positive (key found)
+// and negative (key not found) cache entries for the kid, so the next request reaches the
+// authoritative store. Drivers without a cache need not implement it.
+//
+// Revocation events cannot reach HTTP caches downstream of the handler; responses already
+// served remain cached for up to MaxAgeSeconds.
+type KeyInvalidator interface {
+	Invalidate(kid string)
+}
+
+// RevocationBroadcaster is a RevocationNotifier that fans out revocations to all subscribers.
+// It is safe for concurrent use.
+type RevocationBroadcaster struct {
+	mu          sync.RWMutex
+	subscribers []func(kid string)
+}
+
+func (b *RevocationBroadcaster) Subscribe(fn func(kid string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Revoke notifies every subscriber that kid has been revoked.
+func (b *RevocationBroadcaster) Revoke(kid string) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(kid)
+	}
+}
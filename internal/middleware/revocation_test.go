@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// cachingMockDriver caches every lookup from its backing driver until invalidated
+type cachingMockDriver struct {
+	backing DatabaseDriver
+	mu      sync.Mutex
+	cache   map[string]*KeyLookupResult
+}
+
+func (c *cachingMockDriver) GetKey(ctx context.Context, kid string) (*KeyLookupResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if result, ok := c.cache[kid]; ok {
+		return result, nil
+	}
+	result, err := c.backing.GetKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	c.cache[kid] = result
+	return result, nil
+}
+
+func (c *cachingMockDriver) Invalidate(kid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cache, kid)
+}
+
+func TestJWKSEndpoint_RevocationNotifier_InvalidatesCachedKey(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+	kid := uuid.New()
+
+	var revoked bool
+	cache := &cachingMockDriver{
+		backing: &MockDatabaseDriver{
+			GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+				return &KeyLookupResult{PublicKey: publicKey, Revoked: revoked}, nil
+			},
+		},
+		cache: make(map[string]*KeyLookupResult),
+	}
+	revocations := &RevocationBroadcaster{}
+
+	handler, err := CreateJWKSRouter(JWKSRouterConfig{
+		DB:          cache,
+		Timeout:     5 * time.Second,
+		Revocations: revocations,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+
+	serve := func() int {
+		req, _ := http.NewRequest("GET", "/"+kid.String()+"/.well-known/jwks.json", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("Expected status 200 before revocation, got %d", code)
+	}
+
+	revoked = true
+	if code := serve(); code != http.StatusOK {
+		t.Fatalf("Expected cached status 200 before notification, got %d", code)
+	}
+
+	revocations.Revoke(kid.String())
+	if code := serve(); code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after revocation notification, got %d", code)
+	}
+}
+
+func TestChainDriver_Invalidate_ForwardsToCachingDrivers(t *testing.T) {
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetInt64(12345),
+		E: 65537,
+	}
+	kid := uuid.New().String()
+
+	cache := &cachingMockDriver{
+		backing: &MockDatabaseDriver{
+			GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+				return &KeyLookupResult{PublicKey: publicKey}, nil
+			},
+		},
+		cache: make(map[string]*KeyLookupResult),
+	}
+	plain := &MockDatabaseDriver{
+		GetKeyFunc: func(ctx context.Context, _ string) (*KeyLookupResult, error) {
+			return nil, nil
+		},
+	}
+
+	chain := &ChainDriver{Drivers: []DatabaseDriver{plain, cache}}
+	if _, err := chain.GetKey(context.Background(), kid); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, ok := cache.cache[kid]; !ok {
+		t.Fatal("Expected lookup to be cached")
+	}
+
+	chain.Invalidate(kid)
+	if _, ok := cache.cache[kid]; ok {
+		t.Error("Expected cached entry to be invalidated through the chain")
+	}
+}
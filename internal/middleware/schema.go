@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"encoding/json"
+	"reflect"
+
+	internaljwks "github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+// OpenAPI returns an OpenAPI 3.1 fragment (paths and components) describing the JWKS endpoints
+// served by CreateJWKSRouter: the JWKS success response, the {code, message} error response and
+// the status codes the handler uses. The schemas are derived from the response types, so the
+// fragment can be served alongside the endpoint or merged into API docs for client codegen.
+func OpenAPI() ([]byte, error) {
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/ErrorResponse"},
+				},
+			},
+		}
+	}
+	unavailable := errorResponse("Database timeout or unavailable; code is Timeout or InternalError")
+	unavailable["headers"] = map[string]interface{}{
+		"Retry-After": map[string]interface{}{
+			"description": "Seconds to wait before retrying, if JWKSRouterConfig.RetryAfter is set",
+			"schema":      map[string]interface{}{"type": "integer"},
+		},
+	}
+	responses := func(extra map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "The JWKS holding the requested key",
+				"headers": map[string]interface{}{
+					"Cache-Control": map[string]interface{}{
+						"description": "max-age directive for caching the key",
+						"schema":      map[string]interface{}{"type": "string"},
+					},
+					"Last-Modified": map[string]interface{}{
+						"description": "When the key last changed, if the driver supplies KeyLookupResult.LastModified",
+						"schema":      map[string]interface{}{"type": "string"},
+					},
+				},
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{"$ref": "#/components/schemas/JWKS"},
+					},
+				},
+			},
+			"304": map[string]interface{}{
+				"description": "Not modified since If-Modified-Since, with no body",
+			},
+			"404": errorResponse("Unknown or revoked key; code is KeyNotFoundError"),
+			"500": errorResponse("Internal server error; code is InternalError"),
+			"503": unavailable,
+		}
+		for status, response := range extra {
+			result[status] = response
+		}
+		return result
+	}
+	kidSchema := map[string]interface{}{"type": "string", "format": "uuid"}
+
+	fragment := map[string]interface{}{
+		"paths": map[string]interface{}{
+			"/{kid}/.well-known/jwks.json": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary": "Get the JWKS for a key ID",
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "kid", "in": "path", "required": true, "schema": kidSchema},
+					},
+					"responses": responses(nil),
+				},
+			},
+			"/.well-known/jwks.json": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary": "Get the JWKS for the key ID given as a query parameter",
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "kid", "in": "query", "required": true, "schema": kidSchema},
+					},
+					"responses": responses(map[string]interface{}{
+						"400": errorResponse("Missing or malformed kid query parameter; code is ValidationError"),
+					}),
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"JWKS":          internaljwks.JSONSchema(),
+				"ErrorResponse": internaljwks.StructSchema(reflect.TypeOf(ErrorResponse{})),
+			},
+		},
+	}
+
+	return json.Marshal(fragment)
+}
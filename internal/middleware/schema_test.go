@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	data, err := OpenAPI()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var fragment struct {
+		Paths map[string]struct {
+			Get struct {
+				Responses map[string]interface{} `json:"responses"`
+			} `json:"get"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+				Required   []string               `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &fragment); err != nil {
+		t.Fatalf("Failed to decode fragment: %v", err)
+	}
+
+	expectedStatuses := map[string][]string{
+		"/{kid}/.well-known/jwks.json": {"200", "304", "404", "500", "503"},
+		"/.well-known/jwks.json":       {"200", "304", "400", "404", "500", "503"},
+	}
+	for path, expected := range expectedStatuses {
+		var statuses []string
+		for status := range fragment.Paths[path].Get.Responses {
+			statuses = append(statuses, status)
+		}
+		slices.Sort(statuses)
+		if !slices.Equal(statuses, expected) {
+			t.Errorf("Expected statuses %v for %s, got %v", expected, path, statuses)
+		}
+	}
+
+	errorSchema := fragment.Components.Schemas["ErrorResponse"]
+	slices.Sort(errorSchema.Required)
+	if !slices.Equal(errorSchema.Required, []string{"code", "message"}) {
+		t.Errorf("Expected code and message to be required, got %v", errorSchema.Required)
+	}
+	if _, ok := fragment.Components.Schemas["JWKS"].Properties["keys"]; !ok {
+		t.Error("Expected the JWKS schema to describe the keys array")
+	}
+}
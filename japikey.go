@@ -4,9 +4,14 @@
 package japikey
 
 import (
+	"context"
 	"crypto/rsa"
+	"io"
+	"io/fs"
 	"net/http"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/susu-dot-dev/japikey/errors"
 	"github.com/susu-dot-dev/japikey/internal/jwks"
@@ -22,8 +27,38 @@ func NewJAPIKey(config Config) (*JAPIKey, error) {
 	return japikey.NewJAPIKey(config)
 }
 
+// NewServiceJAPIKey mints a minimal, short-lived token for service-to-service identity.
+func NewServiceJAPIKey(subject, issuerBase, audience string, ttl time.Duration) (*JAPIKey, error) {
+	return japikey.NewServiceJAPIKey(subject, issuerBase, audience, ttl)
+}
+
+// EstimateTokenSize estimates the length of the token NewJAPIKey would produce, without generating a key.
+func EstimateTokenSize(config Config) (int, error) {
+	return japikey.EstimateTokenSize(config)
+}
+
+// Keystore issues JAPIKeys and retains their public keys, guaranteeing unique key IDs
+type Keystore = japikey.Keystore
+
+func NewKeystore() *Keystore {
+	return japikey.NewKeystore()
+}
+
+// ValidateConfig checks a config without generating a key, listing every problem at once.
+func ValidateConfig(config Config) error {
+	return japikey.ValidateConfig(config)
+}
+
+// CheckRandomness self-tests the system's randomness source, for failing fast at startup.
+func CheckRandomness() error {
+	return japikey.CheckRandomness()
+}
+
 type ValidationError = errors.ValidationError
 
+// ConfigError aggregates every problem found by ValidateConfig
+type ConfigError = errors.ConfigError
+
 type ConversionError = errors.ConversionError
 
 // KeyNotFoundError is kept separate from ValidationError because clients may need different behavior
@@ -32,12 +67,63 @@ type KeyNotFoundError = errors.KeyNotFoundError
 
 type InternalError = errors.InternalError
 
+// FetchError is returned when fetching a remote JWKS or manifest fails
+type FetchError = errors.FetchError
+
+// TokenFormatError is returned when a token is structurally broken, such as having an empty signature segment
+type TokenFormatError = errors.TokenFormatError
+
+// RandomnessError is returned by CheckRandomness when the system's randomness source is broken
+type RandomnessError = errors.RandomnessError
+
+// RateLimitError is returned by Keystore.Issue when a subject exceeds its issuance rate limit
+type RateLimitError = errors.RateLimitError
+
 type JWKS = jwks.JWKS
 
+// JWKInfo is a read-only view of a key in a JWKS, returned by JWKS.Keys
+type JWKInfo = jwks.JWKInfo
+
 func NewJWKS(publicKey *rsa.PublicKey, kid uuid.UUID) (*JWKS, error) {
 	return jwks.NewJWKS(publicKey, kid)
 }
 
+// NewJWKSWithLabel creates a JWKS whose key carries an informational x-label. Never rely on the label for security decisions.
+func NewJWKSWithLabel(publicKey *rsa.PublicKey, kid uuid.UUID, label string) (*JWKS, error) {
+	return jwks.NewJWKSWithLabel(publicKey, kid, label)
+}
+
+// Thumbprint returns the RFC 7638 JWK SHA-256 thumbprint of an RSA public key.
+func Thumbprint(publicKey *rsa.PublicKey) (string, error) {
+	return jwks.Thumbprint(publicKey)
+}
+
+// ThumbprintKeyID derives a UUID key ID from the RFC 7638 thumbprint of an RSA public key,
+// for use with VerifyConfig.KeyIDIsThumbprint.
+func ThumbprintKeyID(publicKey *rsa.PublicKey) (uuid.UUID, error) {
+	return jwks.ThumbprintKeyID(publicKey)
+}
+
+// PublishJWKSConfig configures PublishJWKS.
+type PublishJWKSConfig = japikey.PublishJWKSConfig
+
+// PublishJWKS writes one JWKS per kid into dir at <kid>/.well-known/jwks.json, plus an optional
+// combined file, for serving keys from static hosting without the JWKS router.
+func PublishJWKS(dir string, keys map[uuid.UUID]*rsa.PublicKey, config PublishJWKSConfig) error {
+	return japikey.PublishJWKS(dir, keys, config)
+}
+
+// VerifyJWKSMatchesKey parses a JWKS document and confirms it contains exactly publicKey under kid,
+// returning a descriptive error otherwise. Useful for checking a published JWKS file in deployment pipelines.
+func VerifyJWKSMatchesKey(jwksJSON []byte, publicKey *rsa.PublicKey, kid uuid.UUID) error {
+	return jwks.VerifyMatchesKey(jwksJSON, publicKey, kid)
+}
+
+// PublicKeyEqual reports whether two RSA public keys have the same modulus and exponent.
+func PublicKeyEqual(a, b *rsa.PublicKey) bool {
+	return jwks.PublicKeyEqual(a, b)
+}
+
 // VerifyConfig holds the configuration for verifying a JAPIKey.
 // It contains the required and optional parameters for API key verification.
 type VerifyConfig = japikey.VerifyConfig
@@ -47,15 +133,186 @@ type VerifyConfig = japikey.VerifyConfig
 // for signature verification.
 type JWKCallback = japikey.JWKCallback
 
+// JWKSetCallback is a function that retrieves the whole JWKS holding the key for the given key ID.
+type JWKSetCallback = japikey.JWKSetCallback
+
+// KeyCandidate is a public key that may have signed a token, with its key ID and creation time.
+type KeyCandidate = japikey.KeyCandidate
+
+// JWKCandidatesCallback is a function that retrieves every key that may have signed a token with the given key ID.
+type JWKCandidatesCallback = japikey.JWKCandidatesCallback
+
+// JWKHistoryCallback is a function that retrieves every key material the given key ID has had, current first.
+type JWKHistoryCallback = japikey.JWKHistoryCallback
+
+// Signer signs tokens with a key held outside the process, such as in an HSM or KMS.
+type Signer = japikey.Signer
+
+// Confirmation is the RFC 7800 cnf claim binding a token to a client-held key.
+type Confirmation = japikey.Confirmation
+
+// ConfirmationCheck is called during verification to enforce the token's cnf claim.
+type ConfirmationCheck = japikey.ConfirmationCheck
+
+// RemoteKeyFuncConfig configures a JWKCallback that fetches keys from an issuer's JWKS endpoint.
+type RemoteKeyFuncConfig = japikey.RemoteKeyFuncConfig
+
+// NewRemoteKeyFunc creates a JWKCallback that fetches the JWKS for each key ID from the issuer.
+func NewRemoteKeyFunc(config RemoteKeyFuncConfig) (JWKCallback, error) {
+	return japikey.NewRemoteKeyFunc(config)
+}
+
+// JWKSURLForToken returns the JWKS URL to fetch for an UNVERIFIED token from baseIssuer.
+func JWKSURLForToken(tokenString, baseIssuer string) (string, error) {
+	return japikey.JWKSURLForToken(tokenString, baseIssuer)
+}
+
+// IssuerManifestConfig configures loading an issuer allowlist from a manifest URL.
+type IssuerManifestConfig = japikey.IssuerManifestConfig
+
+// IssuerManifest holds an issuer allowlist loaded from a manifest and verifies tokens against the issuer each names.
+type IssuerManifest = japikey.IssuerManifest
+
+// LoadIssuerManifest fetches the issuer manifest and optionally keeps it refreshed in the background.
+func LoadIssuerManifest(ctx context.Context, config IssuerManifestConfig) (*IssuerManifest, error) {
+	return japikey.LoadIssuerManifest(ctx, config)
+}
+
+// TrustStore verifies JAPIKeys offline against pre-loaded keys of known issuers
+type TrustStore = japikey.TrustStore
+
+func NewTrustStore() *TrustStore {
+	return japikey.NewTrustStore()
+}
+
+// LoadTrustStore creates a TrustStore from the issuer and JWKS files in dir, e.g. of an embed.FS.
+func LoadTrustStore(fsys fs.FS, dir string) (*TrustStore, error) {
+	return japikey.LoadTrustStore(fsys, dir)
+}
+
+// Verifier bundles a VerifyConfig and key callback, with an optional cache of verified tokens.
+type Verifier = japikey.Verifier
+
+// VerifiedTokenCache caches successful verification results for a short TTL.
+type VerifiedTokenCache = japikey.VerifiedTokenCache
+
+// CachingKeyFunc wraps a JWKCallback with a bounded LRU cache of public keys.
+type CachingKeyFunc = japikey.CachingKeyFunc
+
+// CachingKeyFuncConfig configures a CachingKeyFunc.
+type CachingKeyFuncConfig = japikey.CachingKeyFuncConfig
+
+// KeyCacheStats reports the hits, misses and evictions of a CachingKeyFunc.
+type KeyCacheStats = japikey.KeyCacheStats
+
+// StandardClaims is a typed view of the registered claims of a verified token.
+type StandardClaims = japikey.StandardClaims
+
+// TokenKind is the kind of bearer token reported by Classify.
+type TokenKind = japikey.TokenKind
+
+// Token kinds reported by Classify.
+const (
+	KindUnknown = japikey.KindUnknown
+	KindJWT     = japikey.KindJWT
+	KindJAPIKey = japikey.KindJAPIKey
+)
+
+// BatchResult is the outcome of verifying one token from a stream.
+type BatchResult = japikey.BatchResult
+
+// NewVerifiedTokenCache creates a cache holding verification results for ttl, bounded to maxEntries.
+func NewVerifiedTokenCache(ttl time.Duration, maxEntries int) *VerifiedTokenCache {
+	return japikey.NewVerifiedTokenCache(ttl, maxEntries)
+}
+
 // VerificationResult holds the result of a successful token verification.
 type VerificationResult = japikey.VerificationResult
 
+// Timings breaks down the time spent in each verification phase, collected when VerifyConfig.CollectTimings is set.
+type Timings = japikey.Timings
+
 // Verify takes in the JWT string, the config, as well as a callback function which retrieves the JWK if given the key id.
 // It either returns the validated claims, or an appropriate error.
 func Verify(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, error) {
 	return japikey.Verify(tokenString, config, keyFunc)
 }
 
+// VerifyWithSet is Verify with a callback returning a JWKS, from which the key matching the token's kid is selected.
+func VerifyWithSet(tokenString string, config VerifyConfig, setFunc JWKSetCallback) (*VerificationResult, error) {
+	return japikey.VerifyWithSet(tokenString, config, setFunc)
+}
+
+// VerifyWithCandidates is Verify with a callback returning several candidate keys, tried in a deterministic order.
+func VerifyWithCandidates(tokenString string, config VerifyConfig, candidatesFunc JWKCandidatesCallback) (*VerificationResult, error) {
+	return japikey.VerifyWithCandidates(tokenString, config, candidatesFunc)
+}
+
+// VerifyWithKeyHistory is Verify with a callback returning every key material a reused kid has had.
+// Distinct kids per key are strongly preferred; this is a compatibility accommodation.
+func VerifyWithKeyHistory(tokenString string, config VerifyConfig, historyFunc JWKHistoryCallback) (*VerificationResult, error) {
+	return japikey.VerifyWithKeyHistory(tokenString, config, historyFunc)
+}
+
+// VerifyDiagnostic reports every reason a token is invalid rather than the first, for auditing tools.
+// It is NOT an authorization check: use Verify to decide whether to accept a token.
+func VerifyDiagnostic(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, []error) {
+	return japikey.VerifyDiagnostic(tokenString, config, keyFunc)
+}
+
+// NewVerificationResult builds a VerificationResult without verifying a token, for tests of code
+// consuming results. It must never be used to authorize untrusted claims.
+func NewVerificationResult(claims map[string]interface{}, kid uuid.UUID, algorithm string) *VerificationResult {
+	return japikey.NewVerificationResult(claims, kid, algorithm)
+}
+
+// SelfTest mints a token from config with a throwaway key and verifies it with verifyConfig,
+// returning the first mismatch, e.g. an issuer inconsistent with the verifier's base issuer URL.
+func SelfTest(config Config, verifyConfig VerifyConfig) error {
+	return japikey.SelfTest(config, verifyConfig)
+}
+
+// VerifyForSubject verifies the token and requires its sub claim to equal expectedSubject.
+func VerifyForSubject(ctx context.Context, tokenString string, expectedSubject string, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, error) {
+	return japikey.VerifyForSubject(ctx, tokenString, expectedSubject, config, keyFunc)
+}
+
+// NewCachingKeyFunc creates a bounded LRU cache in front of keyFunc.
+func NewCachingKeyFunc(keyFunc JWKCallback, config CachingKeyFuncConfig) *CachingKeyFunc {
+	return japikey.NewCachingKeyFunc(keyFunc, config)
+}
+
+// VerifyReader reads a single token from r, rejecting input over MaxTokenSize without buffering it,
+// and verifies it like Verify.
+func VerifyReader(ctx context.Context, r io.Reader, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, error) {
+	return japikey.VerifyReader(ctx, r, config, keyFunc)
+}
+
+// VerifyStream verifies tokens read from a channel with a pool of workers, emitting results on the returned channel.
+func VerifyStream(ctx context.Context, in <-chan string, config VerifyConfig, keyFunc JWKCallback, workers int) <-chan BatchResult {
+	return japikey.VerifyStream(ctx, in, config, keyFunc, workers)
+}
+
+// RequireJAPIKey returns HTTP middleware that verifies the request's bearer token and stores the result in its context.
+func RequireJAPIKey(config VerifyConfig, keyFunc JWKCallback) func(http.Handler) http.Handler {
+	return japikey.RequireJAPIKey(config, keyFunc)
+}
+
+// VerificationResultFromContext returns the verification result stored by RequireJAPIKey.
+func VerificationResultFromContext(ctx context.Context) (*VerificationResult, bool) {
+	return japikey.VerificationResultFromContext(ctx)
+}
+
+// ClaimsFromContext returns the verified claims stored by RequireJAPIKey.
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	return japikey.ClaimsFromContext(ctx)
+}
+
+// StandardClaimsFromContext returns the typed registered claims stored by RequireJAPIKey.
+func StandardClaimsFromContext(ctx context.Context) (StandardClaims, bool) {
+	return japikey.StandardClaimsFromContext(ctx)
+}
+
 // ShouldVerify is a pre-validation function that checks if a token has the correct format before full verification.
 func ShouldVerify(tokenString string, baseIssuer string) bool {
 	return japikey.ShouldVerify(tokenString, baseIssuer)
@@ -65,8 +322,61 @@ type DatabaseDriver = middleware.DatabaseDriver
 
 type KeyLookupResult = middleware.KeyLookupResult
 
+// ChainDriver is a DatabaseDriver that tries several drivers in order, falling through on KeyNotFoundError
+type ChainDriver = middleware.ChainDriver
+
+// CachedDriver is a DatabaseDriver that caches found keys, and optionally KeyNotFoundErrors, of an underlying driver
+type CachedDriver = middleware.CachedDriver
+
 type JWKSRouterConfig = middleware.JWKSRouterConfig
 
+// RevocationNotifier delivers revocation events to the JWKS handler
+type RevocationNotifier = middleware.RevocationNotifier
+
+// KeyInvalidator is implemented by caching DatabaseDrivers to drop entries for revoked keys
+type KeyInvalidator = middleware.KeyInvalidator
+
+// RevocationBroadcaster is a RevocationNotifier that fans out revocations to all subscribers
+type RevocationBroadcaster = middleware.RevocationBroadcaster
+
 func CreateJWKSRouter(config JWKSRouterConfig) (http.Handler, error) {
 	return middleware.CreateJWKSRouter(config)
 }
+
+// JWKSOpenAPI returns an OpenAPI 3.1 fragment describing the responses of the JWKS router.
+func JWKSOpenAPI() ([]byte, error) {
+	return middleware.OpenAPI()
+}
+
+// Classify reports whether an UNVERIFIED token looks like a JAPIKey, another JWT, or neither. For routing only.
+func Classify(tokenString string) TokenKind {
+	return japikey.Classify(tokenString)
+}
+
+// ParseClaimsUnverified decodes a token's header and claims WITHOUT verifying its signature.
+// The result is UNVERIFIED and must never be used for authorization decisions.
+func ParseClaimsUnverified(tokenString string) (map[string]interface{}, map[string]interface{}, error) {
+	return japikey.ParseClaimsUnverified(tokenString)
+}
+
+// SetMaxVersion overrides the highest token version accepted during verification and returns a
+// function restoring the previous value. It is intended for tests and staged rollouts only.
+func SetMaxVersion(n int) (restore func()) {
+	return japikey.SetMaxVersion(n)
+}
+
+// ReservedClaimsAfter returns the claim names reserved by token versions later than version.
+func ReservedClaimsAfter(version int) []string {
+	return japikey.ReservedClaimsAfter(version)
+}
+
+// PeekKeyID returns the kid from a token header WITHOUT verifying the token, for logging only.
+func PeekKeyID(tokenString string) (uuid.UUID, error) {
+	return japikey.PeekKeyID(tokenString)
+}
+
+// VerifySignature checks only the token's signature and algorithm against publicKey. It validates
+// NO claims and must not be used in place of Verify for authorization.
+func VerifySignature(tokenString string, publicKey *rsa.PublicKey, allowedAlgs []string) error {
+	return japikey.VerifySignature(tokenString, publicKey, allowedAlgs)
+}
@@ -0,0 +1,134 @@
+package japikey
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey int
+
+const (
+	verificationResultKey contextKey = iota
+	standardClaimsKey
+)
+
+// StandardClaims is a typed view of the registered claims of a verified token.
+// Time fields are zero when the claim is absent. Audience holds both the single-string and array
+// forms of aud, with the semantics of VerificationResult.Audiences.
+type StandardClaims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	Version   string
+}
+
+// newStandardClaims extracts the registered claims from already validated claims.
+func newStandardClaims(claims jwt.MapClaims) StandardClaims {
+	standard := StandardClaims{}
+	standard.Subject, _ = claims.GetSubject()
+	standard.Issuer, _ = claims.GetIssuer()
+	standard.Audience = audienceClaim(claims)
+	if exp, err := timeClaim(claims, "exp", true); err == nil && exp != nil {
+		standard.ExpiresAt = *exp
+	}
+	if nbf, err := timeClaim(claims, "nbf", true); err == nil && nbf != nil {
+		standard.NotBefore = *nbf
+	}
+	if iat, err := timeClaim(claims, "iat", true); err == nil && iat != nil {
+		standard.IssuedAt = *iat
+	}
+	standard.ID, _ = claims["jti"].(string)
+	standard.Version, _ = claims[VersionClaim].(string)
+	return standard
+}
+
+// RequireJAPIKey returns HTTP middleware that verifies the bearer token in the Authorization
+// header. On success the VerificationResult and StandardClaims are stored in the request context
+// for the next handler; on failure a 401 response is sent and the next handler is not called.
+// If the key source could not be reached (FetchError, DatabaseTimeoutError or
+// DatabaseUnavailableError), a 503 response is sent instead, since the token may well be valid.
+func RequireJAPIKey(config VerifyConfig, keyFunc JWKCallback) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := bearerToken(r)
+			if !ok {
+				sendUnauthorized(w)
+				return
+			}
+
+			result, err := Verify(tokenString, config, keyFunc)
+			if err != nil {
+				if keySourceOutage(err) != nil {
+					sendUnavailable(w)
+					return
+				}
+				sendUnauthorized(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), verificationResultKey, result)
+			ctx = context.WithValue(ctx, standardClaimsKey, newStandardClaims(result.Claims))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func sendUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	body := map[string]string{"code": "Unauthorized", "message": "invalid or missing API key"}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[JAPIKey] Error encoding response: %v", err)
+	}
+}
+
+func sendUnavailable(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	body := map[string]string{"code": "Unavailable", "message": "API key verification temporarily unavailable"}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[JAPIKey] Error encoding response: %v", err)
+	}
+}
+
+// VerificationResultFromContext returns the result stored by RequireJAPIKey.
+func VerificationResultFromContext(ctx context.Context) (*VerificationResult, bool) {
+	result, ok := ctx.Value(verificationResultKey).(*VerificationResult)
+	return result, ok && result != nil
+}
+
+// ClaimsFromContext returns the verified claims stored by RequireJAPIKey.
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	result, ok := VerificationResultFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return result.Claims, true
+}
+
+// StandardClaimsFromContext returns the typed registered claims stored by RequireJAPIKey.
+func StandardClaimsFromContext(ctx context.Context) (StandardClaims, bool) {
+	claims, ok := ctx.Value(standardClaimsKey).(StandardClaims)
+	return claims, ok
+}
@@ -0,0 +1,155 @@
+package japikey
+
+import (
+	"context"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+func TestRequireJAPIKey_InjectsResultAndClaims(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"jti": "token-1"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return pubKey, nil
+	}
+
+	var called bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+
+		result, ok := VerificationResultFromContext(r.Context())
+		if !ok || result == nil {
+			t.Error("Expected verification result in context")
+		}
+
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok || claims["sub"] != "test-user" {
+			t.Errorf("Expected claims in context, got %v", claims)
+		}
+
+		standard, ok := StandardClaimsFromContext(r.Context())
+		if !ok {
+			t.Fatal("Expected standard claims in context")
+		}
+		if standard.Subject != "test-user" || standard.ID != "token-1" || standard.Version != "japikey-v1" {
+			t.Errorf("Unexpected standard claims: %+v", standard)
+		}
+		if len(standard.Audience) != 1 || standard.Audience[0] != "test-audience" {
+			t.Errorf("Expected audience [test-audience], got %v", standard.Audience)
+		}
+		if standard.ExpiresAt.IsZero() {
+			t.Error("Expected ExpiresAt to be set")
+		}
+		if !standard.NotBefore.IsZero() {
+			t.Error("Expected NotBefore to be zero when the claim is absent")
+		}
+	})
+
+	handler := RequireJAPIKey(VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc)(next)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !called {
+		t.Fatal("Expected next handler to be called")
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestRequireJAPIKey_RejectsWithoutInjecting(t *testing.T) {
+	tokenString, _, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	_, otherKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return otherKey, nil
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"missing header", ""},
+		{"wrong scheme", "Basic " + tokenString},
+		{"empty token", "Bearer "},
+		{"invalid signature", "Bearer " + tokenString},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("Expected next handler not to be called")
+			})
+
+			handler := RequireJAPIKey(VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc)(next)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", rr.Code)
+			}
+			if rr.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("Expected WWW-Authenticate Bearer, got %q", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestRequireJAPIKey_KeySourceOutage(t *testing.T) {
+	tokenString, _, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return nil, errors.NewFetchError("issuer unreachable")
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected next handler not to be called")
+	})
+
+	handler := RequireJAPIKey(VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc)(next)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 when the key source is unreachable, got %d", rr.Code)
+	}
+}
+
+func TestClaimsFromContext_Empty(t *testing.T) {
+	ctx := context.Background()
+
+	if _, ok := VerificationResultFromContext(ctx); ok {
+		t.Error("Expected no verification result in empty context")
+	}
+	if _, ok := ClaimsFromContext(ctx); ok {
+		t.Error("Expected no claims in empty context")
+	}
+	if _, ok := StandardClaimsFromContext(ctx); ok {
+		t.Error("Expected no standard claims in empty context")
+	}
+}
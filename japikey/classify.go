@@ -0,0 +1,63 @@
+package japikey
+
+import (
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenKind is the kind of bearer token reported by Classify.
+type TokenKind int
+
+const (
+	// KindUnknown is anything that does not decode as a JWT, e.g. an opaque token
+	KindUnknown TokenKind = iota
+
+	// KindJWT is a well-formed JWT that is not a JAPIKey
+	KindJWT
+
+	// KindJAPIKey is a JWT that looks like a JAPIKey: RS256 with a japikey-v version claim
+	KindJAPIKey
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case KindJWT:
+		return "JWT"
+	case KindJAPIKey:
+		return "JAPIKey"
+	default:
+		return "Unknown"
+	}
+}
+
+// Classify reports what kind of token tokenString is, so that gateways handling mixed token
+// types can route it to the right auth handler. It is a superset of ShouldVerify that does not
+// need to know the issuer.
+//
+// WARNING: the classification is based on an UNVERIFIED decode and can be forged by anyone.
+// Use it for routing only, and always verify the token with the handler it is routed to.
+func Classify(tokenString string) TokenKind {
+	claims := jwt.MapClaims{}
+	token, parts, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	if err != nil || len(parts) != 3 {
+		return KindUnknown
+	}
+
+	// Anything Verify would reject outright on shape alone is not a JAPIKey
+	if checkTokenSize(tokenString) != nil || checkTokenSegments(tokenString) != nil {
+		return KindJWT
+	}
+
+	if checkPayloadEncoding(token.Header) != nil {
+		return KindJWT
+	}
+	if alg, _ := token.Header["alg"].(string); alg != AlgorithmRS256 {
+		return KindJWT
+	}
+	if version, _ := claims[VersionClaim].(string); !strings.HasPrefix(version, VersionPrefix) {
+		return KindJWT
+	}
+
+	return KindJAPIKey
+}
@@ -0,0 +1,59 @@
+package japikey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestClassify(t *testing.T) {
+	japikeyToken, _, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	thirdPartyToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "user"}).SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"ver": "japikey-v1"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	otherVersionToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"ver": "other-v1"}).SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	parts := strings.Split(japikeyToken, ".")
+
+	tests := []struct {
+		name     string
+		token    string
+		expected TokenKind
+	}{
+		{"JAPIKey", japikeyToken, KindJAPIKey},
+		{"third-party RS256 JWT", thirdPartyToken, KindJWT},
+		{"HS256 token with japikey version", hmacToken, KindJWT},
+		{"other version prefix", otherVersionToken, KindJWT},
+		{"JAPIKey claims without signature", parts[0] + "." + parts[1] + ".", KindJWT},
+		{"opaque token", "sk_live_abc123", KindUnknown},
+		{"empty", "", KindUnknown},
+		{"two segments", parts[0] + "." + parts[1], KindUnknown},
+		{"undecodable segments", "a.b.c", KindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.token); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
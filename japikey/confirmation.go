@@ -0,0 +1,97 @@
+package japikey
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	japikeyerrors "github.com/susu-dot-dev/japikey/errors"
+)
+
+// Confirmation is the RFC 7800 cnf claim, binding a token to a key held by the client so that
+// a stolen token cannot be replayed by a different client.
+type Confirmation struct {
+	// JKT is the base64url-encoded SHA-256 JWK thumbprint of the client's key (RFC 9449, DPoP)
+	JKT string `json:"jkt,omitempty"`
+
+	// X5TS256 is the base64url-encoded SHA-256 thumbprint of the client's certificate (RFC 8705, mTLS)
+	X5TS256 string `json:"x5t#S256,omitempty"`
+}
+
+// ConfirmationCheck is called during verification with the token's confirmation claim,
+// or nil if the token has none. Returning an error rejects the token.
+type ConfirmationCheck func(cnf *Confirmation) error
+
+func (c *Confirmation) isEmpty() bool {
+	return c.JKT == "" && c.X5TS256 == ""
+}
+
+// toClaim converts the confirmation to its claim representation.
+func (c *Confirmation) toClaim() map[string]interface{} {
+	claim := map[string]interface{}{}
+	if c.JKT != "" {
+		claim["jkt"] = c.JKT
+	}
+	if c.X5TS256 != "" {
+		claim["x5t#S256"] = c.X5TS256
+	}
+	return claim
+}
+
+// extractConfirmation parses the cnf claim, returning nil if the token has none.
+func extractConfirmation(claims jwt.MapClaims) (*Confirmation, error) {
+	cnfRaw, ok := claims[ConfirmationClaim]
+	if !ok {
+		return nil, nil
+	}
+
+	cnfMap, ok := cnfRaw.(map[string]interface{})
+	if !ok {
+		return nil, japikeyerrors.NewValidationError("token confirmation claim must be an object")
+	}
+
+	cnf := &Confirmation{}
+	for field, target := range map[string]*string{"jkt": &cnf.JKT, "x5t#S256": &cnf.X5TS256} {
+		valueRaw, ok := cnfMap[field]
+		if !ok {
+			continue
+		}
+		value, ok := valueRaw.(string)
+		if !ok || value == "" {
+			return nil, japikeyerrors.NewValidationError("token confirmation claim contains an invalid " + field + " value")
+		}
+		*target = value
+	}
+
+	return cnf, nil
+}
+
+// parseConfirmation parses the cnf claim for verification. Its shape is only enforced when a
+// check depends on it; otherwise a cnf that is not an RFC 7800 confirmation, such as a custom
+// claim of the same name, is tolerated and reported as no confirmation.
+func parseConfirmation(claims jwt.MapClaims, check ConfirmationCheck) (*Confirmation, error) {
+	cnf, err := extractConfirmation(claims)
+	if err != nil {
+		if check == nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cnf, nil
+}
+
+// validateConfirmation parses the cnf claim and runs the optional check against it.
+func validateConfirmation(claims jwt.MapClaims, check ConfirmationCheck) (*Confirmation, error) {
+	cnf, err := parseConfirmation(claims, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if check != nil {
+		if err := check(cnf); err != nil {
+			if validationErr, ok := err.(*japikeyerrors.ValidationError); ok {
+				return nil, validationErr
+			}
+			return nil, japikeyerrors.NewValidationError("token confirmation check failed")
+		}
+	}
+
+	return cnf, nil
+}
@@ -8,12 +8,40 @@ const (
 	// MaxTokenSize is the maximum allowed token size to prevent resource exhaustion (4KB)
 	MaxTokenSize = 4096
 
+	// DefaultMaxClaimDepth is the maximum claim nesting depth applied when VerifyConfig.MaxClaimDepth is 0
+	DefaultMaxClaimDepth = 16
+
+	// DefaultMaxIssuerLength is the maximum issuer length in bytes applied when VerifyConfig.MaxIssuerLength is 0
+	DefaultMaxIssuerLength = 2048
+
+	// DefaultMaxTimestamp is the latest Unix time, 9999-12-31T23:59:59Z, accepted in time claims
+	// when VerifyConfig.MaxTimestamp is 0
+	DefaultMaxTimestamp = 253402300799
+
 	// VersionClaim is the JWT claim key for the version identifier
 	VersionClaim = "ver"
 
 	// IssuerClaim is the JWT claim key for the issuer
 	IssuerClaim = "iss"
 
+	// ScopeClaim is the JWT claim key for the OAuth-style space-delimited scope string
+	ScopeClaim = "scope"
+
+	// PermissionsClaim is the JWT claim key for the permissions array
+	PermissionsClaim = "permissions"
+
+	// ConfirmationClaim is the JWT claim key for the RFC 7800 confirmation (proof-of-possession) claim
+	ConfirmationClaim = "cnf"
+
 	// KeyIDHeader is the JWT header key for the key identifier
 	KeyIDHeader = "kid"
+
+	// TypeHeader is the JWT header key for the token type
+	TypeHeader = "typ"
+
+	// TokenType is the typ header value every JAPIKey declares
+	TokenType = "JWT"
+
+	// KeyThumbprintHeader is the JWT header key for the RFC 7638 thumbprint of the signing key
+	KeyThumbprintHeader = "jkt"
 )
@@ -0,0 +1,106 @@
+package japikey
+
+import (
+	"crypto/rsa"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	japikeyerrors "github.com/susu-dot-dev/japikey/errors"
+)
+
+// VerifyDiagnostic reports every reason a token is invalid instead of stopping at the first, for
+// security auditing and debugging tools.
+//
+// WARNING: this is a diagnostic aid, NOT an authorization check. Use Verify to decide whether to
+// accept a token.
+//
+// The header and claims are decoded without verification and every structural check is run,
+// collecting one error per failing check (e.g. a wrong algorithm, an expired token and a bad
+// issuer are all reported). The signature is only verified, through Verify, once every structural
+// check passes, so the key callback is never called for a structurally invalid token. A token too
+// malformed to decode yields just that error. On success the result of Verify is returned.
+func VerifyDiagnostic(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, []error) {
+	header, claimsMap, err := ParseClaimsUnverified(tokenString)
+	if err != nil {
+		return nil, []error{err}
+	}
+	claims := jwt.MapClaims(claimsMap)
+
+	var problems []error
+	collect := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	collect(checkPayloadEncoding(header))
+	if config.RequiredType != "" {
+		collect(checkRequiredType(header, config.RequiredType))
+	} else if config.RequireTypeHeader {
+		collect(checkTypeHeader(header))
+	}
+	if alg, _ := header["alg"].(string); alg != AlgorithmRS256 {
+		collect(japikeyerrors.NewValidationError("token algorithm must be " + AlgorithmRS256))
+	}
+
+	keyID, err := extractKeyID(header, claims, config.KeyIDClaim)
+	collect(err)
+
+	collect(validateClaimDepth(claims, config.MaxClaimDepth))
+	collect(validateTimeClaims(claims, config, time.Now()))
+	collect(validateVersion(claims))
+
+	// The issuer is bound to the kid, so it can only be checked against a valid one
+	if keyID != uuid.Nil {
+		issuer, err := claims.GetIssuer()
+		if err != nil {
+			collect(japikeyerrors.NewIssuerFormatError("Invalid issuer"))
+		} else {
+			collect(validateIssuer(issuer, config, keyID))
+		}
+	}
+
+	if config.RequireSubject {
+		collect(validateSubject(claims))
+	}
+	if config.SingleAudienceOnly {
+		collect(validateSingleAudience(claims))
+	}
+	collect(validateForwardReservedClaims(claims, config.ForwardReservedClaims))
+	collect(validateScopes(claims, config.RequiredScopes))
+	// Only the cnf shape is structural, and only enforced when ConfirmationCheck is set, as in
+	// Verify; ConfirmationCheck itself runs in Verify, after the signature
+	_, err = parseConfirmation(claims, config.ConfirmationCheck)
+	collect(err)
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	result, err := Verify(tokenString, config, keyFunc)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return result, nil
+}
+
+// SelfTest checks that tokens minted from config verify under verifyConfig, before real tokens are
+// issued. It mints a token with a throwaway key (or config.Signer, if set), serves that key to
+// Verify for the minted kid only, and returns the first mismatch, e.g. a Config.Issuer outside
+// VerifyConfig.BaseIssuerURL, or an issuer not ending in the kid because Config.KeyIDGenerator does
+// not produce the kid the issuer was built from. A nil error means the sign→verify loop succeeded.
+func SelfTest(config Config, verifyConfig VerifyConfig) error {
+	apiKey, err := NewJAPIKey(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = Verify(apiKey.JWT, verifyConfig, func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		if keyID != apiKey.KeyID {
+			return nil, japikeyerrors.NewKeyNotFoundError("token key ID does not match the minted key")
+		}
+		return apiKey.PublicKey, nil
+	})
+	return err
+}
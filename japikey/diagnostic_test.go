@@ -0,0 +1,252 @@
+package japikey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+func newDiagnosticConfig() VerifyConfig {
+	return VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+}
+
+func TestVerifyDiagnostic_ValidToken(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	result, problems := VerifyDiagnostic(tokenString, newDiagnosticConfig(), mockKeyFunc(pubKey))
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems, got: %v", problems)
+	}
+	if result == nil || result.Claims["sub"] != "test-user" {
+		t.Errorf("Expected verified result, got %+v", result)
+	}
+}
+
+func TestVerifyDiagnostic_CollectsEveryFailure(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{
+		"exp": time.Now().Add(-1 * time.Hour).Unix(),
+		"iss": "https://other.example.com/123e4567-e89b-12d3-a456-426614174000",
+		"ver": "not-a-version",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	keyFuncCalled := false
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		keyFuncCalled = true
+		return pubKey, nil
+	}
+
+	result, problems := VerifyDiagnostic(tokenString, newDiagnosticConfig(), keyFunc)
+	if result != nil {
+		t.Error("Expected no result for an invalid token")
+	}
+	if len(problems) != 3 {
+		t.Fatalf("Expected 3 problems (expiry, version, issuer), got %d: %v", len(problems), problems)
+	}
+	if _, ok := problems[0].(*errors.TokenExpiredError); !ok {
+		t.Errorf("Expected TokenExpiredError first, got %T", problems[0])
+	}
+	if keyFuncCalled {
+		t.Error("Expected the key callback not to be called for a structurally invalid token")
+	}
+}
+
+func TestVerifyDiagnostic_WrongAlgorithm(t *testing.T) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "test-user",
+		"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+		"exp": time.Now().Add(-1 * time.Hour).Unix(),
+		"ver": "japikey-v1",
+	})
+	token.Header["kid"] = "123e4567-e89b-12d3-a456-426614174000"
+	tokenString, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	_, problems := VerifyDiagnostic(tokenString, newDiagnosticConfig(), mockKeyFunc(&privateKey.PublicKey))
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems (algorithm, expiry), got %d: %v", len(problems), problems)
+	}
+}
+
+func TestVerifyDiagnostic_MalformedToken(t *testing.T) {
+	_, problems := VerifyDiagnostic("not.a.token", newDiagnosticConfig(), mockKeyFunc(nil))
+	if len(problems) != 1 {
+		t.Fatalf("Expected a single problem for a malformed token, got %d: %v", len(problems), problems)
+	}
+}
+
+func TestVerifyDiagnostic_SignatureCheckedLast(t *testing.T) {
+	tokenString, _, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	result, problems := VerifyDiagnostic(tokenString, newDiagnosticConfig(), mockKeyFunc(&otherKey.PublicKey))
+	if result != nil {
+		t.Error("Expected no result when the signature does not verify")
+	}
+	if len(problems) != 1 {
+		t.Fatalf("Expected only the signature failure, got %d: %v", len(problems), problems)
+	}
+	if _, ok := problems[0].(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", problems[0])
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	keyID := uuid.New()
+	config := func(issuer string, generator func() uuid.UUID) Config {
+		return Config{
+			Subject:        "test-user",
+			Issuer:         issuer,
+			Audience:       "test-audience",
+			ExpiresAt:      time.Now().Add(time.Hour),
+			KeyIDGenerator: generator,
+		}
+	}
+	fixedKeyID := func() uuid.UUID { return keyID }
+
+	tests := []struct {
+		name        string
+		config      Config
+		verify      VerifyConfig
+		expectedErr string
+	}{
+		{
+			name:   "consistent issuer and base",
+			config: config("https://example.com/"+keyID.String(), fixedKeyID),
+			verify: newDiagnosticConfig(),
+		},
+		{
+			name:        "issuer outside the base issuer URL",
+			config:      config("https://other.example.com/"+keyID.String(), fixedKeyID),
+			verify:      newDiagnosticConfig(),
+			expectedErr: "*errors.ValidationError",
+		},
+		{
+			name:        "issuer not coupled to the minted kid",
+			config:      config("https://example.com/"+keyID.String(), nil),
+			verify:      newDiagnosticConfig(),
+			expectedErr: "*errors.ValidationError",
+		},
+		{
+			name:        "invalid config",
+			config:      config("", fixedKeyID),
+			verify:      newDiagnosticConfig(),
+			expectedErr: "*errors.ValidationError",
+		},
+		{
+			name:        "verifier requires a claim the config does not mint",
+			config:      config("https://example.com/"+keyID.String(), fixedKeyID),
+			verify:      VerifyConfig{BaseIssuerURL: "https://example.com/", RequiredScopes: []string{"admin"}},
+			expectedErr: "*errors.ValidationError",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SelfTest(tt.config, tt.verify)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if got := fmt.Sprintf("%T", err); got != tt.expectedErr {
+				t.Errorf("Expected %s, got %s: %v", tt.expectedErr, got, err)
+			}
+		})
+	}
+}
+
+func TestVerifyDiagnostic_KeyIDClaim(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": "test-user",
+		"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"ver": "japikey-v1",
+		"kid": "123e4567-e89b-12d3-a456-426614174000",
+	})
+	tokenString, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	config := newDiagnosticConfig()
+	config.KeyIDClaim = "kid"
+	if _, problems := VerifyDiagnostic(tokenString, config, mockKeyFunc(&privateKey.PublicKey)); len(problems) != 0 {
+		t.Errorf("Expected no problems, got: %v", problems)
+	}
+}
+
+func TestVerifyDiagnostic_CollectsConfirmationWithOtherFailures(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{
+		"exp": time.Now().Add(-1 * time.Hour).Unix(),
+		"cnf": "jkt",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	// The cnf shape is only enforced when a ConfirmationCheck depends on it
+	config := newDiagnosticConfig()
+	config.ConfirmationCheck = func(*Confirmation) error { return nil }
+	_, problems := VerifyDiagnostic(tokenString, config, mockKeyFunc(pubKey))
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems (expiry, confirmation), got %d: %v", len(problems), problems)
+	}
+	if _, ok := problems[0].(*errors.TokenExpiredError); !ok {
+		t.Errorf("Expected TokenExpiredError first, got %T", problems[0])
+	}
+	if _, ok := problems[1].(*errors.ValidationError); !ok || !strings.Contains(problems[1].Error(), "confirmation") {
+		t.Errorf("Expected confirmation ValidationError, got %T: %v", problems[1], problems[1])
+	}
+}
+
+func TestVerifyDiagnostic_ConfirmationIgnoredWithoutCheck(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"cnf": "custom"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	result, problems := VerifyDiagnostic(tokenString, newDiagnosticConfig(), mockKeyFunc(pubKey))
+	if len(problems) != 0 {
+		t.Fatalf("Expected no problems for a custom cnf without a check, got: %v", problems)
+	}
+	if result.Confirmation != nil {
+		t.Errorf("Expected nil confirmation, got %+v", result.Confirmation)
+	}
+}
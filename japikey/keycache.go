@@ -0,0 +1,196 @@
+package japikey
+
+import (
+	"container/list"
+	"crypto/rsa"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultKeyCacheSize is the maximum number of keys applied when CachingKeyFuncConfig.MaxEntries is 0
+	DefaultKeyCacheSize = 1000
+
+	// DefaultKeyCacheTTL is the key lifetime applied when CachingKeyFuncConfig.TTL is 0
+	DefaultKeyCacheTTL = 5 * time.Minute
+)
+
+// CachingKeyFuncConfig configures a CachingKeyFunc.
+type CachingKeyFuncConfig struct {
+	// TTL is how long a fetched key is reused before it is looked up again.
+	// 0 = DefaultKeyCacheTTL applied.
+	TTL time.Duration
+
+	// MaxEntries is a hard cap on the number of cached keys; the least recently used key is
+	// evicted to make room. Size it comfortably above the number of keys in active use (around
+	// twice is a good start): a working set larger than the cap makes the cache thrash, sending
+	// most lookups through to the underlying key callback. 0 = DefaultKeyCacheSize applied.
+	MaxEntries int
+
+	// OnEvict is optionally called with the key ID of every entry evicted to make room.
+	// Expired and invalidated entries are not reported.
+	OnEvict func(kid uuid.UUID)
+}
+
+// KeyCacheStats reports the activity of a CachingKeyFunc.
+type KeyCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// CachingKeyFunc wraps a JWKCallback with a bounded LRU cache of public keys. Only successful
+// lookups are cached, so tokens carrying many distinct unknown kids cannot grow it, and concurrent
+// misses for the same key ID share one lookup. A lookup still in flight when Invalidate is called
+// is returned but not cached. It is safe for concurrent use.
+type CachingKeyFunc struct {
+	keyFunc    JWKCallback
+	ttl        time.Duration
+	maxEntries int
+	onEvict    func(kid uuid.UUID)
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	order   *list.List // front = most recently used
+	stats   KeyCacheStats
+	// generation counts Invalidate calls; lookups that started before the latest are not cached
+	generation uint64
+}
+
+type cachedKey struct {
+	keyID     uuid.UUID
+	publicKey *rsa.PublicKey
+	expiresAt time.Time
+}
+
+// NewCachingKeyFunc creates a cache in front of keyFunc. Pass its GetPublicKey method wherever a
+// JWKCallback is expected.
+func NewCachingKeyFunc(keyFunc JWKCallback, config CachingKeyFuncConfig) *CachingKeyFunc {
+	if config.TTL <= 0 {
+		config.TTL = DefaultKeyCacheTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultKeyCacheSize
+	}
+	cache := &CachingKeyFunc{
+		ttl:        config.TTL,
+		maxEntries: config.MaxEntries,
+		onEvict:    config.OnEvict,
+		now:        time.Now,
+		entries:    make(map[uuid.UUID]*list.Element),
+		order:      list.New(),
+	}
+	// The shared lookup caches its own result, so callers joining it cannot cache a key
+	// fetched before an Invalidate
+	cache.keyFunc = dedupKeyFunc(func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		generation := cache.currentGeneration()
+		publicKey, err := keyFunc(keyID)
+		if err == nil && publicKey != nil {
+			cache.put(keyID, publicKey, generation)
+		}
+		return publicKey, err
+	})
+	return cache
+}
+
+// GetPublicKey returns the cached key for keyID, looking it up with the underlying callback on a miss.
+func (c *CachingKeyFunc) GetPublicKey(keyID uuid.UUID) (*rsa.PublicKey, error) {
+	if publicKey := c.get(keyID); publicKey != nil {
+		return publicKey, nil
+	}
+
+	publicKey, err := c.keyFunc(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
+
+func (c *CachingKeyFunc) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+func (c *CachingKeyFunc) get(keyID uuid.UUID) *rsa.PublicKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[keyID]
+	if ok {
+		entry := element.Value.(*cachedKey)
+		if c.now().Before(entry.expiresAt) {
+			c.order.MoveToFront(element)
+			c.stats.Hits++
+			return entry.publicKey
+		}
+		c.order.Remove(element)
+		delete(c.entries, keyID)
+	}
+
+	c.stats.Misses++
+	return nil
+}
+
+// put caches publicKey unless Invalidate has been called since its lookup began at generation,
+// in which case the key may predate a revocation.
+func (c *CachingKeyFunc) put(keyID uuid.UUID, publicKey *rsa.PublicKey, generation uint64) {
+	var evicted []uuid.UUID
+
+	c.mu.Lock()
+	if c.generation != generation {
+		c.mu.Unlock()
+		return
+	}
+	entry := &cachedKey{keyID: keyID, publicKey: publicKey, expiresAt: c.now().Add(c.ttl)}
+	if element, ok := c.entries[keyID]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+	} else {
+		for c.order.Len() >= c.maxEntries {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			oldestID := oldest.Value.(*cachedKey).keyID
+			delete(c.entries, oldestID)
+			c.stats.Evictions++
+			evicted = append(evicted, oldestID)
+		}
+		c.entries[keyID] = c.order.PushFront(entry)
+	}
+	c.mu.Unlock()
+
+	// Call OnEvict without the lock held, so the callback may use the cache
+	if c.onEvict != nil {
+		for _, kid := range evicted {
+			c.onEvict(kid)
+		}
+	}
+}
+
+// Stats returns a snapshot of the cache's hit, miss and eviction counts.
+func (c *CachingKeyFunc) Stats() KeyCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Invalidate drops the cached key for the given key ID. Its signature matches KeyInvalidator,
+// so it can be subscribed directly to a RevocationNotifier.
+func (c *CachingKeyFunc) Invalidate(kid string) {
+	keyID, err := uuid.Parse(kid)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[keyID]; ok {
+		c.order.Remove(element)
+		delete(c.entries, keyID)
+	}
+	c.generation++
+}
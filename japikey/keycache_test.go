@@ -0,0 +1,185 @@
+package japikey
+
+import (
+	"crypto/rsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// perKeyCountingKeyFunc returns a distinct fake public key per key ID and counts lookups.
+func perKeyCountingKeyFunc(calls map[uuid.UUID]int) JWKCallback {
+	return func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		calls[keyID]++
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(keyID[:]), E: 65537}, nil
+	}
+}
+
+func TestCachingKeyFunc_HitsAndMisses(t *testing.T) {
+	calls := map[uuid.UUID]int{}
+	cache := NewCachingKeyFunc(perKeyCountingKeyFunc(calls), CachingKeyFuncConfig{})
+	keyID := uuid.New()
+
+	first, err := cache.GetPublicKey(keyID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	second, err := cache.GetPublicKey(keyID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if first != second {
+		t.Error("Expected the cached key to be returned")
+	}
+	if calls[keyID] != 1 {
+		t.Errorf("Expected 1 lookup, got %d", calls[keyID])
+	}
+	if stats := cache.Stats(); stats != (KeyCacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCachingKeyFunc_EvictsLeastRecentlyUsed(t *testing.T) {
+	calls := map[uuid.UUID]int{}
+	var evicted []uuid.UUID
+	cache := NewCachingKeyFunc(perKeyCountingKeyFunc(calls), CachingKeyFuncConfig{
+		MaxEntries: 2,
+		OnEvict:    func(kid uuid.UUID) { evicted = append(evicted, kid) },
+	})
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	for _, keyID := range []uuid.UUID{a, b, a, c} {
+		if _, err := cache.GetPublicKey(keyID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	// b was least recently used when c was added
+	if len(evicted) != 1 || evicted[0] != b {
+		t.Fatalf("Expected only %s to be evicted, got %v", b, evicted)
+	}
+	if stats := cache.Stats(); stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", stats.Evictions)
+	}
+
+	if _, err := cache.GetPublicKey(a); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls[a] != 1 {
+		t.Errorf("Expected a to still be cached, got %d lookups", calls[a])
+	}
+	if _, err := cache.GetPublicKey(b); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls[b] != 2 {
+		t.Errorf("Expected b to be looked up again, got %d lookups", calls[b])
+	}
+}
+
+func TestCachingKeyFunc_ManyDistinctKidsStayBounded(t *testing.T) {
+	calls := map[uuid.UUID]int{}
+	cache := NewCachingKeyFunc(perKeyCountingKeyFunc(calls), CachingKeyFuncConfig{MaxEntries: 10})
+
+	for range 100 {
+		if _, err := cache.GetPublicKey(uuid.New()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	if len(cache.entries) != 10 || cache.order.Len() != 10 {
+		t.Errorf("Expected cache to hold 10 entries, got %d", len(cache.entries))
+	}
+	if stats := cache.Stats(); stats.Evictions != 90 {
+		t.Errorf("Expected 90 evictions, got %d", stats.Evictions)
+	}
+}
+
+func TestCachingKeyFunc_ErrorsNotCached(t *testing.T) {
+	lookups := 0
+	cache := NewCachingKeyFunc(func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		lookups++
+		return nil, errors.NewKeyNotFoundError("unknown key")
+	}, CachingKeyFuncConfig{})
+	keyID := uuid.New()
+
+	for range 2 {
+		if _, err := cache.GetPublicKey(keyID); err == nil {
+			t.Fatal("Expected error for unknown key")
+		} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+			t.Errorf("Expected KeyNotFoundError, got %T", err)
+		}
+	}
+
+	if lookups != 2 {
+		t.Errorf("Expected errors not to be cached, got %d lookups", lookups)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected empty cache, got %d entries", len(cache.entries))
+	}
+}
+
+func TestCachingKeyFunc_ExpiryAndInvalidate(t *testing.T) {
+	calls := map[uuid.UUID]int{}
+	cache := NewCachingKeyFunc(perKeyCountingKeyFunc(calls), CachingKeyFuncConfig{TTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	keyID := uuid.New()
+
+	lookup := func() {
+		t.Helper()
+		if _, err := cache.GetPublicKey(keyID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	lookup()
+	now = now.Add(2 * time.Minute)
+	lookup()
+	if calls[keyID] != 2 {
+		t.Errorf("Expected expired key to be looked up again, got %d lookups", calls[keyID])
+	}
+
+	cache.Invalidate(keyID.String())
+	lookup()
+	if calls[keyID] != 3 {
+		t.Errorf("Expected invalidated key to be looked up again, got %d lookups", calls[keyID])
+	}
+	if stats := cache.Stats(); stats.Evictions != 0 {
+		t.Errorf("Expected expiry and invalidation not to count as evictions, got %d", stats.Evictions)
+	}
+}
+
+func TestCachingKeyFunc_InvalidateDuringLookup(t *testing.T) {
+	keyID := uuid.New()
+	calls := 0
+	var cache *CachingKeyFunc
+	cache = NewCachingKeyFunc(func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		calls++
+		// The revocation lands while the first lookup is in flight
+		if calls == 1 {
+			cache.Invalidate(keyID.String())
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(keyID[:]), E: 65537}, nil
+	}, CachingKeyFuncConfig{})
+
+	for range 2 {
+		if _, err := cache.GetPublicKey(keyID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected the in-flight lookup to go uncached, got %d lookups", calls)
+	}
+
+	// Lookups starting after the invalidation are cached again
+	if _, err := cache.GetPublicKey(keyID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected later lookups to be cached, got %d lookups", calls)
+	}
+}
@@ -0,0 +1,322 @@
+package japikey
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// MaxKeyIDAttempts is the number of key IDs a Keystore will try before giving up on a collision
+const MaxKeyIDAttempts = 3
+
+// Keystore issues JAPIKeys and retains their public keys, indexed by key ID.
+// By default every issued JAPIKey gets its own key pair. After Rotate, Issue signs with the
+// keystore's active key instead, and previously active keys are retained according to the
+// policy set with SetRetention. It is safe for concurrent use.
+type Keystore struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]*storedKey
+
+	// active signs all Issue calls once Rotate has been called; nil = one key pair per JAPIKey
+	active *keystoreSigner
+	// retired holds the key IDs of previously active keys, oldest first
+	retired []uuid.UUID
+
+	retainKeys int
+	retainFor  time.Duration
+
+	// issueLimit JAPIKeys may be issued per subject within any issueWindow; 0 = unlimited
+	issueLimit  int
+	issueWindow time.Duration
+	// issuedAt holds each subject's issue times within the current window, oldest first
+	issuedAt map[string][]time.Time
+	// issueSweptAt is when issuedAt was last swept of subjects with no issues in the window
+	issueSweptAt time.Time
+
+	// newKeyID generates candidate key IDs; overridable in tests to force collisions
+	newKeyID func() uuid.UUID
+	now      func() time.Time
+}
+
+// storedKey is a public key together with the last time it signed a token. For a per-JAPIKey
+// key that is its issue time; for a previously active key it is the time it was rotated out.
+type storedKey struct {
+	publicKey    *rsa.PublicKey
+	lastSignedAt time.Time
+}
+
+// NewKeystore creates an empty in-memory Keystore.
+func NewKeystore() *Keystore {
+	return &Keystore{
+		keys:     make(map[uuid.UUID]*storedKey),
+		issuedAt: make(map[string][]time.Time),
+		newKeyID: uuid.New,
+		now:      time.Now,
+	}
+}
+
+// SetRetention sets how many previously active keys Rotate retains (maxKeys) and for how long
+// after they were rotated out (maxAge). A key is dropped when it exceeds either limit; 0 disables
+// that limit. Retention is applied on each Rotate; the default retains every key until Prune.
+func (k *Keystore) SetRetention(maxKeys int, maxAge time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.retainKeys = maxKeys
+	k.retainFor = maxAge
+}
+
+// SetIssueRateLimit limits Issue to limit JAPIKeys per subject within any sliding window of the
+// given length, protecting the keystore and the JWKS endpoint from a runaway or abusive client.
+// Calls over the limit fail with a RateLimitError. A limit or window of 0 disables the limit,
+// which is the default.
+func (k *Keystore) SetIssueRateLimit(limit int, window time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.issueLimit = limit
+	k.issueWindow = window
+	clear(k.issuedAt)
+}
+
+// allowIssueLocked records an issuance for subject, returning a RateLimitError instead if the
+// subject has reached the rate limit. Subjects whose window has passed are dropped at most once
+// per window, so issuedAt does not grow with every subject ever seen. k.mu must be held.
+func (k *Keystore) allowIssueLocked(subject string) error {
+	if k.issueLimit <= 0 || k.issueWindow <= 0 {
+		return nil
+	}
+
+	now := k.now()
+	if now.Sub(k.issueSweptAt) >= k.issueWindow {
+		for s, times := range k.issuedAt {
+			if now.Sub(times[len(times)-1]) >= k.issueWindow {
+				delete(k.issuedAt, s)
+			}
+		}
+		k.issueSweptAt = now
+	}
+	recent := k.issuedAt[subject]
+	for len(recent) > 0 && now.Sub(recent[0]) >= k.issueWindow {
+		recent = recent[1:]
+	}
+	if len(recent) >= k.issueLimit {
+		k.issuedAt[subject] = recent
+		return errors.NewRateLimitError("issuance rate limit exceeded for subject")
+	}
+	k.issuedAt[subject] = append(recent, now)
+	return nil
+}
+
+// Issue creates a new JAPIKey and stores its public key.
+// Key IDs are guaranteed to be unique within the keystore: a key ID that is already stored
+// (or being issued concurrently) is regenerated, and an InternalError is returned only after
+// MaxKeyIDAttempts collisions in a row. Key IDs come from config.KeyIDGenerator if set.
+//
+// Once Rotate has been called, the JAPIKey is signed with the active key and carries its key
+// ID, so a config with a KeyIDGenerator is then rejected with a ValidationError rather than
+// ignored. Configs with an external Signer or a PrivateKey are always rejected, since those
+// determine the key ID. Issuance counts toward the subject's rate limit, if set with
+// SetIssueRateLimit, even if key generation then fails.
+func (k *Keystore) Issue(config Config) (*JAPIKey, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+	if config.Signer != nil {
+		return nil, errors.NewValidationError("keystore cannot issue keys with an external signer")
+	}
+	if config.PrivateKey != nil {
+		return nil, errors.NewValidationError("keystore cannot issue keys with a caller-supplied private key")
+	}
+
+	k.mu.Lock()
+	active := k.active
+	if active != nil && config.KeyIDGenerator != nil {
+		k.mu.Unlock()
+		return nil, errors.NewValidationError("keystore cannot use a key ID generator once Rotate has set an active key")
+	}
+	if err := k.allowIssueLocked(config.Subject); err != nil {
+		k.mu.Unlock()
+		return nil, err
+	}
+	k.mu.Unlock()
+	if active != nil {
+		config.Signer = active
+		return newExternallySignedJAPIKey(config)
+	}
+
+	generate := k.newKeyID
+	if config.KeyIDGenerator != nil {
+		generate = config.KeyIDGenerator
+	}
+	keyID, err := k.reserveKeyID(generate)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := newJAPIKey(config, keyID)
+	if err != nil {
+		k.mu.Lock()
+		delete(k.keys, keyID)
+		k.mu.Unlock()
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.keys[keyID] = &storedKey{publicKey: result.PublicKey, lastSignedAt: k.now()}
+	k.mu.Unlock()
+
+	return result, nil
+}
+
+// Rotate generates a new key, makes it the active key for future Issue calls and returns its
+// key ID. The previously active key stays available from GetPublicKey, so its outstanding
+// tokens still verify, until the retention policy or Prune removes it.
+// It returns uuid.Nil if a key could not be generated, leaving the active key unchanged.
+func (k *Keystore) Rotate() uuid.UUID {
+	keyID, err := k.reserveKeyID(k.newKeyID)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		k.mu.Lock()
+		delete(k.keys, keyID)
+		k.mu.Unlock()
+		return uuid.Nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	if k.active != nil {
+		if previous := k.keys[k.active.keyID]; previous != nil {
+			previous.lastSignedAt = now
+		}
+		k.retired = append(k.retired, k.active.keyID)
+	}
+	k.keys[keyID] = &storedKey{publicKey: &privateKey.PublicKey, lastSignedAt: now}
+	k.active = &keystoreSigner{privateKey: privateKey, keyID: keyID}
+	k.applyRetentionLocked(now)
+
+	return keyID
+}
+
+// applyRetentionLocked drops previously active keys beyond the retention limits. k.mu must be held.
+func (k *Keystore) applyRetentionLocked(now time.Time) {
+	kept := k.retired[:0]
+	for i, keyID := range k.retired {
+		key := k.keys[keyID]
+		if key == nil {
+			continue
+		}
+		tooMany := k.retainKeys > 0 && len(k.retired)-i > k.retainKeys
+		tooOld := k.retainFor > 0 && now.Sub(key.lastSignedAt) > k.retainFor
+		if tooMany || tooOld {
+			delete(k.keys, keyID)
+			continue
+		}
+		kept = append(kept, keyID)
+	}
+	k.retired = kept
+}
+
+// Prune drops every key that last signed a token before the cutoff: per-JAPIKey keys issued
+// before it, and previously active keys rotated out before it. The active key is never dropped.
+// It returns the number of keys removed.
+func (k *Keystore) Prune(before time.Time) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	removed := 0
+	for keyID, key := range k.keys {
+		// nil entries are key IDs reserved by an in-progress Issue or Rotate
+		if key == nil || (k.active != nil && keyID == k.active.keyID) {
+			continue
+		}
+		if key.lastSignedAt.Before(before) {
+			delete(k.keys, keyID)
+			removed++
+		}
+	}
+
+	kept := k.retired[:0]
+	for _, keyID := range k.retired {
+		if _, exists := k.keys[keyID]; exists {
+			kept = append(kept, keyID)
+		}
+	}
+	k.retired = kept
+
+	return removed
+}
+
+// reserveKeyID picks an unused key ID from generate and reserves it with a nil entry, so that
+// concurrent Issue calls cannot claim the same ID while the key pair is being generated.
+func (k *Keystore) reserveKeyID(generate func() uuid.UUID) (uuid.UUID, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for range MaxKeyIDAttempts {
+		keyID := generate()
+		if keyID == uuid.Nil {
+			continue
+		}
+		if _, exists := k.keys[keyID]; exists {
+			continue
+		}
+		k.keys[keyID] = nil
+		return keyID, nil
+	}
+
+	return uuid.Nil, errors.NewInternalError("failed to generate a unique key ID")
+}
+
+// GetPublicKey returns the stored public key for the given key ID.
+func (k *Keystore) GetPublicKey(keyID uuid.UUID) (*rsa.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key := k.keys[keyID]
+	if key == nil {
+		return nil, errors.NewKeyNotFoundError("key ID not found in keystore")
+	}
+
+	return key.publicKey, nil
+}
+
+// TrustedKeyFunc returns a JWKCallback that resolves key IDs directly from the keystore, for
+// services that issue and verify in the same process without a JWKS round trip. Every lookup
+// reads the current keys, so rotation, retention and Prune take effect immediately; unknown,
+// pruned and still-generating key IDs return a KeyNotFoundError. The callback is safe for
+// concurrent use alongside Issue, Rotate and Prune.
+func (k *Keystore) TrustedKeyFunc() JWKCallback {
+	return k.GetPublicKey
+}
+
+// keystoreSigner signs with the keystore's active key. The private key never leaves the keystore.
+type keystoreSigner struct {
+	privateKey *rsa.PrivateKey
+	keyID      uuid.UUID
+}
+
+func (s *keystoreSigner) Sign(signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+	return rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+}
+
+func (s *keystoreSigner) Public() *rsa.PublicKey {
+	return &s.privateKey.PublicKey
+}
+
+func (s *keystoreSigner) KeyID() uuid.UUID {
+	return s.keyID
+}
@@ -0,0 +1,474 @@
+package japikey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+func newTestKeystoreConfig(subject string) Config {
+	return Config{
+		Subject:   subject,
+		Issuer:    "https://example.com",
+		Audience:  "test-audience",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	}
+}
+
+func TestKeystore_Issue_StoresPublicKey(t *testing.T) {
+	keystore := NewKeystore()
+
+	result, err := keystore.Issue(newTestKeystoreConfig("test-user"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	publicKey, err := keystore.GetPublicKey(result.KeyID)
+	if err != nil {
+		t.Fatalf("Expected stored key, got error: %v", err)
+	}
+	if !jwks.PublicKeyEqual(publicKey, result.PublicKey) {
+		t.Error("Stored public key does not match issued key")
+	}
+
+	if _, err := keystore.GetPublicKey(uuid.New()); err == nil {
+		t.Error("Expected error for unknown key ID")
+	} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T", err)
+	}
+}
+
+func TestKeystore_Issue_RegeneratesDuplicateKeyID(t *testing.T) {
+	keystore := NewKeystore()
+	duplicate := uuid.New()
+	fresh := uuid.New()
+	candidates := []uuid.UUID{duplicate, duplicate, fresh}
+	keystore.newKeyID = func() uuid.UUID {
+		next := candidates[0]
+		candidates = candidates[1:]
+		return next
+	}
+
+	first, err := keystore.Issue(newTestKeystoreConfig("user-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	second, err := keystore.Issue(newTestKeystoreConfig("user-2"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if first.KeyID != duplicate {
+		t.Errorf("Expected first key ID %s, got %s", duplicate, first.KeyID)
+	}
+	if second.KeyID != fresh {
+		t.Errorf("Expected colliding key ID to be regenerated as %s, got %s", fresh, second.KeyID)
+	}
+}
+
+func TestKeystore_Issue_RepeatedCollisionsReturnInternalError(t *testing.T) {
+	keystore := NewKeystore()
+	duplicate := uuid.New()
+	keystore.newKeyID = func() uuid.UUID { return duplicate }
+
+	if _, err := keystore.Issue(newTestKeystoreConfig("user-1")); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	result, err := keystore.Issue(newTestKeystoreConfig("user-2"))
+	if result != nil {
+		t.Error("Expected result to be nil after repeated collisions")
+	}
+	if _, ok := err.(*errors.InternalError); !ok {
+		t.Errorf("Expected InternalError, got %T", err)
+	}
+}
+
+func TestKeystore_Issue_ConcurrentKeyIDsUnique(t *testing.T) {
+	keystore := NewKeystore()
+	numKeys := 5
+
+	var wg sync.WaitGroup
+	results := make(chan *JAPIKey, numKeys)
+	for i := range numKeys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := keystore.Issue(newTestKeystoreConfig(fmt.Sprintf("user-%d", i)))
+			if err != nil {
+				t.Errorf("Error issuing key: %v", err)
+				return
+			}
+			results <- result
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := make(map[uuid.UUID]bool)
+	for result := range results {
+		if seen[result.KeyID] {
+			t.Errorf("Duplicate KeyID found: %s", result.KeyID)
+		}
+		seen[result.KeyID] = true
+	}
+	if len(seen) != numKeys {
+		t.Errorf("Expected %d unique key IDs, got %d", numKeys, len(seen))
+	}
+}
+
+func TestKeystore_Rotate_IssueUsesActiveKey(t *testing.T) {
+	keystore := NewKeystore()
+
+	activeKID := keystore.Rotate()
+	if activeKID == uuid.Nil {
+		t.Fatal("Expected Rotate to return a key ID")
+	}
+
+	// The active key ID is known up front, so it can be embedded in the issuer
+	issuerConfig := func(subject string) Config {
+		config := newTestKeystoreConfig(subject)
+		config.Issuer = "https://example.com/" + activeKID.String()
+		return config
+	}
+	first, err := keystore.Issue(issuerConfig("user-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	second, err := keystore.Issue(issuerConfig("user-2"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if first.KeyID != activeKID || second.KeyID != activeKID {
+		t.Errorf("Expected both keys to be signed by %s, got %s and %s", activeKID, first.KeyID, second.KeyID)
+	}
+
+	config := VerifyConfig{BaseIssuerURL: "https://example.com/", Timeout: 5 * time.Second}
+	for _, issued := range []*JAPIKey{first, second} {
+		if _, err := Verify(issued.JWT, config, keystore.GetPublicKey); err != nil {
+			t.Errorf("Expected token signed by active key to verify, got: %v", err)
+		}
+	}
+}
+
+func TestKeystore_Rotate_RetainsPreviousKeys(t *testing.T) {
+	keystore := NewKeystore()
+
+	oldKID := keystore.Rotate()
+	issued, err := keystore.Issue(newTestKeystoreConfig("user-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	newKID := keystore.Rotate()
+	if newKID == oldKID {
+		t.Fatal("Expected Rotate to generate a new key ID")
+	}
+
+	publicKey, err := keystore.GetPublicKey(oldKID)
+	if err != nil {
+		t.Fatalf("Expected previous key to be retained, got: %v", err)
+	}
+	if !jwks.PublicKeyEqual(publicKey, issued.PublicKey) {
+		t.Error("Retained public key does not match the key that signed the token")
+	}
+
+	next, err := keystore.Issue(newTestKeystoreConfig("user-2"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if next.KeyID != newKID {
+		t.Errorf("Expected key issued after rotation to use %s, got %s", newKID, next.KeyID)
+	}
+}
+
+func TestKeystore_Rotate_RetentionCount(t *testing.T) {
+	keystore := NewKeystore()
+	keystore.SetRetention(1, 0)
+
+	first := keystore.Rotate()
+	second := keystore.Rotate()
+	third := keystore.Rotate()
+
+	if _, err := keystore.GetPublicKey(first); err == nil {
+		t.Error("Expected the oldest key to be dropped beyond the retention count")
+	}
+	for _, kid := range []uuid.UUID{second, third} {
+		if _, err := keystore.GetPublicKey(kid); err != nil {
+			t.Errorf("Expected key %s to be retained, got: %v", kid, err)
+		}
+	}
+}
+
+func TestKeystore_Rotate_RetentionAge(t *testing.T) {
+	keystore := NewKeystore()
+	now := time.Now()
+	keystore.now = func() time.Time { return now }
+	keystore.SetRetention(0, time.Hour)
+
+	first := keystore.Rotate()
+	second := keystore.Rotate() // first is rotated out now
+
+	now = now.Add(30 * time.Minute)
+	third := keystore.Rotate() // second is rotated out now
+	if _, err := keystore.GetPublicKey(first); err != nil {
+		t.Errorf("Expected key within the retention age to be retained, got: %v", err)
+	}
+
+	now = now.Add(31 * time.Minute)
+	keystore.Rotate()
+	if _, err := keystore.GetPublicKey(first); err == nil {
+		t.Error("Expected key rotated out over an hour ago to be dropped")
+	}
+	for _, kid := range []uuid.UUID{second, third} {
+		if _, err := keystore.GetPublicKey(kid); err != nil {
+			t.Errorf("Expected key %s to be retained, got: %v", kid, err)
+		}
+	}
+}
+
+func TestKeystore_Prune(t *testing.T) {
+	keystore := NewKeystore()
+	now := time.Now()
+	keystore.now = func() time.Time { return now }
+
+	perKey, err := keystore.Issue(newTestKeystoreConfig("user-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	retired := keystore.Rotate()
+
+	now = now.Add(time.Hour)
+	active := keystore.Rotate()
+	cutoff := now
+
+	now = now.Add(time.Hour)
+	removed := keystore.Prune(cutoff)
+	if removed != 1 {
+		t.Errorf("Expected 1 key to be pruned, got %d", removed)
+	}
+
+	if _, err := keystore.GetPublicKey(perKey.KeyID); err == nil {
+		t.Error("Expected key issued before the cutoff to be pruned")
+	}
+	// retired was rotated out at the cutoff, so its tokens may be newer than the cutoff
+	if _, err := keystore.GetPublicKey(retired); err != nil {
+		t.Errorf("Expected key rotated out at the cutoff to be retained, got: %v", err)
+	}
+
+	if removed := keystore.Prune(now.Add(time.Hour)); removed != 1 {
+		t.Errorf("Expected only the retired key to be pruned, got %d", removed)
+	}
+	if _, err := keystore.GetPublicKey(active); err != nil {
+		t.Errorf("Expected the active key never to be pruned, got: %v", err)
+	}
+}
+
+func TestKeystore_Issue_UsesKeyIDGenerator(t *testing.T) {
+	keystore := NewKeystore()
+	generated := uuid.New()
+
+	config := newTestKeystoreConfig("test-user")
+	config.KeyIDGenerator = func() uuid.UUID { return generated }
+
+	result, err := keystore.Issue(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.KeyID != generated {
+		t.Errorf("Expected key ID %s from the generator, got %s", generated, result.KeyID)
+	}
+
+	// A generator that keeps colliding still cannot produce a duplicate key ID
+	if _, err := keystore.Issue(config); err == nil {
+		t.Error("Expected error when the generator only returns a stored key ID")
+	} else if _, ok := err.(*errors.InternalError); !ok {
+		t.Errorf("Expected InternalError, got %T", err)
+	}
+}
+
+func TestKeystore_Issue_RejectsKeyIDGeneratorAfterRotate(t *testing.T) {
+	keystore := NewKeystore()
+	active := keystore.Rotate()
+
+	config := newTestKeystoreConfig("test-user")
+	config.KeyIDGenerator = uuid.New
+	_, err := keystore.Issue(config)
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+	}
+
+	// Without a generator the active key still signs
+	result, err := keystore.Issue(newTestKeystoreConfig("test-user"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.KeyID != active {
+		t.Errorf("Expected key ID %s of the active key, got %s", active, result.KeyID)
+	}
+}
+
+func TestKeystore_Issue_RejectsPrivateKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keystore := NewKeystore()
+	config := newTestKeystoreConfig("test-user")
+	config.PrivateKey = privateKey
+
+	for _, stage := range []string{"per-key", "after rotate"} {
+		if stage == "after rotate" {
+			keystore.Rotate()
+		}
+		_, err := keystore.Issue(config)
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("%s: expected ValidationError, got %T: %v", stage, err, err)
+		}
+	}
+}
+
+func TestKeystore_Issue_RateLimitPerSubject(t *testing.T) {
+	keystore := NewKeystore()
+	now := time.Now()
+	keystore.now = func() time.Time { return now }
+	keystore.Rotate() // sign with a shared key so the test does not generate a key pair per Issue
+	keystore.SetIssueRateLimit(2, time.Minute)
+
+	for i := range 2 {
+		if _, err := keystore.Issue(newTestKeystoreConfig("alice")); err != nil {
+			t.Fatalf("Expected issue %d within the limit to succeed, got: %v", i, err)
+		}
+	}
+
+	if _, err := keystore.Issue(newTestKeystoreConfig("alice")); err == nil {
+		t.Fatal("Expected error for issuance over the limit")
+	} else if _, ok := err.(*errors.RateLimitError); !ok {
+		t.Errorf("Expected RateLimitError, got %T", err)
+	}
+
+	if _, err := keystore.Issue(newTestKeystoreConfig("bob")); err != nil {
+		t.Errorf("Expected other subjects to be unaffected, got: %v", err)
+	}
+
+	// Rejected attempts do not extend the window
+	now = now.Add(time.Minute)
+	if _, err := keystore.Issue(newTestKeystoreConfig("alice")); err != nil {
+		t.Errorf("Expected issuance to succeed once the window has passed, got: %v", err)
+	}
+
+	keystore.SetIssueRateLimit(0, 0)
+	for range 5 {
+		if _, err := keystore.Issue(newTestKeystoreConfig("alice")); err != nil {
+			t.Fatalf("Expected no limit once disabled, got: %v", err)
+		}
+	}
+}
+
+func TestKeystore_Issue_RateLimitForgetsIdleSubjects(t *testing.T) {
+	keystore := NewKeystore()
+	now := time.Now()
+	keystore.now = func() time.Time { return now }
+	keystore.Rotate()
+	keystore.SetIssueRateLimit(2, time.Minute)
+
+	for _, subject := range []string{"alice", "bob", "carol"} {
+		if _, err := keystore.Issue(newTestKeystoreConfig(subject)); err != nil {
+			t.Fatalf("Expected issuance for %s to succeed, got: %v", subject, err)
+		}
+	}
+	if len(keystore.issuedAt) != 3 {
+		t.Fatalf("Expected 3 tracked subjects, got %d", len(keystore.issuedAt))
+	}
+
+	// Once the window has passed, the next issuance drops every idle subject
+	now = now.Add(time.Minute)
+	if _, err := keystore.Issue(newTestKeystoreConfig("dave")); err != nil {
+		t.Fatalf("Expected issuance to succeed, got: %v", err)
+	}
+	if len(keystore.issuedAt) != 1 {
+		t.Errorf("Expected only the active subject to be tracked, got %d", len(keystore.issuedAt))
+	}
+	if _, tracked := keystore.issuedAt["dave"]; !tracked {
+		t.Error("Expected the active subject to still be tracked")
+	}
+}
+
+func TestKeystore_TrustedKeyFunc_ReflectsRotationAndPrune(t *testing.T) {
+	keystore := NewKeystore()
+	keyFunc := keystore.TrustedKeyFunc()
+
+	first := keystore.Rotate()
+	config := newTestKeystoreConfig("user-1")
+	config.Issuer = "https://example.com/" + first.String()
+	apiKey, err := keystore.Issue(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	verifyConfig := VerifyConfig{BaseIssuerURL: "https://example.com/", Timeout: 5 * time.Second}
+	if _, err := Verify(apiKey.JWT, verifyConfig, keyFunc); err != nil {
+		t.Fatalf("Expected token to verify, got: %v", err)
+	}
+
+	// The retired key is still retained after rotation
+	second := keystore.Rotate()
+	if _, err := Verify(apiKey.JWT, verifyConfig, keyFunc); err != nil {
+		t.Errorf("Expected token signed by retained key to verify, got: %v", err)
+	}
+
+	// The callback obtained before pruning sees the removal immediately
+	keystore.Prune(time.Now().Add(time.Hour))
+	_, err = Verify(apiKey.JWT, verifyConfig, keyFunc)
+	if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError after prune, got %T: %v", err, err)
+	}
+	if _, err := keyFunc(second); err != nil {
+		t.Errorf("Expected the active key to resolve, got: %v", err)
+	}
+}
+
+func TestKeystore_TrustedKeyFunc_UnknownKeyID(t *testing.T) {
+	keyFunc := NewKeystore().TrustedKeyFunc()
+
+	_, err := keyFunc(uuid.New())
+	if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T: %v", err, err)
+	}
+}
+
+func TestKeystore_TrustedKeyFunc_ConcurrentUse(t *testing.T) {
+	keystore := NewKeystore()
+	keyFunc := keystore.TrustedKeyFunc()
+	active := keystore.Rotate()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch i % 4 {
+			case 0:
+				keystore.Rotate()
+			case 1:
+				if _, err := keystore.Issue(newTestKeystoreConfig(fmt.Sprintf("user-%d", i))); err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			default:
+				// Nothing is pruned, so the first active key stays resolvable throughout
+				if _, err := keyFunc(active); err != nil {
+					t.Errorf("Expected key to resolve, got: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
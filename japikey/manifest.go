@@ -0,0 +1,217 @@
+package japikey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// MaxManifestResponseSize is the maximum size of a fetched issuer manifest (1MB)
+const MaxManifestResponseSize = 1024 * 1024
+
+// IssuerManifestConfig configures loading an issuer allowlist from a manifest URL.
+// The manifest is a JSON array of issuer base URLs, e.g. ["https://a.example.com", "https://b.example.com"].
+type IssuerManifestConfig struct {
+	// ManifestURL is the URL of the manifest document
+	ManifestURL string
+
+	// RefreshInterval is how often the manifest is re-fetched in the background.
+	// 0 = no background refresh; call Refresh to update manually
+	RefreshInterval time.Duration
+
+	// Client is the HTTP client used for fetching the manifest and JWKS. nil = http.DefaultClient
+	Client *http.Client
+
+	// Timeout bounds each fetch. 0 = 5-second default applied
+	Timeout time.Duration
+
+	// CacheTTL is passed to each issuer's remote key callback (see RemoteKeyFuncConfig.CacheTTL).
+	// Callbacks, and so their caches, are kept across refreshes for issuers that stay listed.
+	// 0 = no caching
+	CacheTTL time.Duration
+}
+
+// IssuerManifest holds an issuer allowlist loaded from a manifest, along with a key callback
+// for each issuer. Tokens are verified with its Verify method, which only consults the issuer
+// the token names. It is safe for concurrent use.
+//
+// There is deliberately no composite JWKCallback: a callback sees only the kid, not the token's
+// iss, so one routing a kid across issuers would let a key served by one issuer verify a token
+// naming another, and would fetch from every issuer for each unknown kid. Routing by the iss base
+// in Verify avoids both.
+type IssuerManifest struct {
+	config IssuerManifestConfig
+
+	mu       sync.RWMutex
+	issuers  []string
+	keyFuncs []JWKCallback
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// LoadIssuerManifest fetches the manifest and, if RefreshInterval is set, starts refreshing it in
+// the background until Close is called. The initial fetch must succeed.
+func LoadIssuerManifest(ctx context.Context, config IssuerManifestConfig) (*IssuerManifest, error) {
+	if config.ManifestURL == "" {
+		return nil, errors.NewValidationError("manifest URL is required")
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	manifest := &IssuerManifest{
+		config: config,
+		stop:   make(chan struct{}),
+	}
+	if err := manifest.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.RefreshInterval > 0 {
+		go manifest.refreshLoop()
+	}
+
+	return manifest, nil
+}
+
+// Refresh re-fetches the manifest. On failure the previously loaded issuers are kept.
+func (m *IssuerManifest) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	body, err := fetchURL(ctx, m.config.Client, m.config.ManifestURL, MaxManifestResponseSize)
+	if err != nil {
+		if _, ok := err.(*errors.KeyNotFoundError); ok {
+			return errors.NewFetchError("manifest not found at " + m.config.ManifestURL)
+		}
+		return err
+	}
+
+	var issuers []string
+	if err := json.Unmarshal(body, &issuers); err != nil {
+		return errors.NewFetchError("manifest must be a JSON array of issuer base URLs")
+	}
+
+	// Keep the callbacks of issuers that are still listed, so their caches survive the refresh
+	m.mu.RLock()
+	existing := make(map[string]JWKCallback, len(m.issuers))
+	for i, issuer := range m.issuers {
+		existing[issuer] = m.keyFuncs[i]
+	}
+	m.mu.RUnlock()
+
+	keyFuncs := make([]JWKCallback, 0, len(issuers))
+	for _, issuer := range issuers {
+		if keyFunc, ok := existing[issuer]; ok {
+			keyFuncs = append(keyFuncs, keyFunc)
+			continue
+		}
+		if u, err := url.Parse(issuer); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.NewFetchError("manifest contains an invalid issuer URL: " + issuer)
+		}
+		keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{
+			BaseIssuerURL: issuer,
+			Client:        m.config.Client,
+			Timeout:       m.config.Timeout,
+			CacheTTL:      m.config.CacheTTL,
+		})
+		if err != nil {
+			return err
+		}
+		keyFuncs = append(keyFuncs, keyFunc)
+	}
+
+	m.mu.Lock()
+	m.issuers = issuers
+	m.keyFuncs = keyFuncs
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *IssuerManifest) refreshLoop() {
+	ticker := time.NewTicker(m.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			// Errors keep the previous issuers; the next tick retries
+			_ = m.Refresh(context.Background())
+		}
+	}
+}
+
+// Close stops the background refresh. It is safe to call more than once.
+func (m *IssuerManifest) Close() {
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+// BaseIssuerURLs returns the current issuer allowlist, suitable for VerifyConfig.BaseIssuerURLs.
+func (m *IssuerManifest) BaseIssuerURLs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.issuers)
+}
+
+// Verify verifies a token against the manifest. The key is looked up only at the issuer the
+// token names: its iss, minus the trailing key ID segment, must be one of the manifest's base
+// URLs, and the issuer options of config (BaseIssuerURL, BaseIssuerURLs, ExactIssuer and
+// DisableKidIssuerBinding) are replaced so that iss is bound to that base and the token's kid.
+// A key served by one issuer therefore never verifies a token naming another. Tokens naming an
+// issuer outside the manifest are rejected without any fetch. All other options of config apply.
+func (m *IssuerManifest) Verify(tokenString string, config VerifyConfig) (*VerificationResult, error) {
+	_, claims, err := ParseClaimsUnverified(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	issuer, _ := claims["iss"].(string)
+	if issuer == "" {
+		return nil, errors.NewIssuerFormatError("token missing issuer claim")
+	}
+
+	// The base is everything before the key ID segment; Verify checks the segment itself
+	tokenBase := issuer
+	if i := strings.LastIndex(issuer, "/"); i >= 0 {
+		tokenBase = issuer[:i]
+	}
+	if config.NormalizeIssuerURL {
+		tokenBase = strings.TrimSuffix(normalizeIssuerURL(tokenBase), "/")
+	}
+
+	m.mu.RLock()
+	issuers, keyFuncs := m.issuers, m.keyFuncs
+	m.mu.RUnlock()
+
+	for i, base := range issuers {
+		candidate := strings.TrimSuffix(base, "/")
+		if config.NormalizeIssuerURL {
+			candidate = strings.TrimSuffix(normalizeIssuerURL(candidate), "/")
+		}
+		if candidate != tokenBase {
+			continue
+		}
+
+		config.BaseIssuerURL = base
+		config.BaseIssuerURLs = nil
+		config.ExactIssuer = ""
+		config.DisableKidIssuerBinding = false
+		return Verify(tokenString, config, keyFuncs[i])
+	}
+
+	return nil, errors.NewValidationError(fmt.Sprintf("invalid issuer: %s, not listed in the issuer manifest", issuer))
+}
@@ -0,0 +1,302 @@
+package japikey
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// manifestServer serves a mutable issuer list as a manifest
+type manifestServer struct {
+	mu      sync.Mutex
+	issuers []string
+	status  int
+}
+
+func (m *manifestServer) set(status int, issuers ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = status
+	m.issuers = issuers
+}
+
+func (m *manifestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status != http.StatusOK {
+		w.WriteHeader(m.status)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(m.issuers)
+}
+
+func TestIssuerManifest_RoutesKeyIDToIssuer(t *testing.T) {
+	keyA, keyB := uuid.New(), uuid.New()
+	keysA := map[uuid.UUID]*rsa.PublicKey{}
+	keysB := map[uuid.UUID]*rsa.PublicKey{}
+	issuerA := newJWKSServer(t, keysA)
+	issuerB := newJWKSServer(t, keysB)
+
+	tokenA, pubKeyA, err := createTokenWithIssuer(issuerA.URL+"/"+keyA.String(), keyA)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	tokenB, pubKeyB, err := createTokenWithIssuer(issuerB.URL+"/"+keyB.String(), keyB)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keysA[keyA] = pubKeyA
+	keysB[keyB] = pubKeyB
+
+	manifestSrv := &manifestServer{}
+	manifestSrv.set(http.StatusOK, issuerA.URL, issuerB.URL)
+	server := httptest.NewServer(manifestSrv)
+	defer server.Close()
+
+	manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{ManifestURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	if !slices.Equal(manifest.BaseIssuerURLs(), []string{issuerA.URL, issuerB.URL}) {
+		t.Errorf("Unexpected issuers: %v", manifest.BaseIssuerURLs())
+	}
+
+	config := VerifyConfig{Timeout: 5 * time.Second}
+	for name, token := range map[string]string{"issuer A": tokenA, "issuer B": tokenB} {
+		if _, err := manifest.Verify(token, config); err != nil {
+			t.Errorf("Expected token from %s to verify, got: %v", name, err)
+		}
+	}
+
+	unknownToken, _, err := createTokenWithIssuer(issuerA.URL+"/"+uuid.New().String(), uuid.New())
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if _, err := manifest.Verify(unknownToken, config); err == nil {
+		t.Error("Expected error for unknown key ID")
+	}
+}
+
+func TestIssuerManifest_Verify_KeyBoundToNamedIssuer(t *testing.T) {
+	keyID := uuid.New()
+	keysA := map[uuid.UUID]*rsa.PublicKey{}
+	var fetchesB atomic.Int64
+	issuerA := newJWKSServer(t, keysA)
+	issuerB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchesB.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer issuerB.Close()
+
+	// Signed with a key issuer A serves, but claiming to come from issuer B
+	forged, publicKey, err := createTokenWithIssuer(issuerB.URL+"/"+keyID.String(), keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keysA[keyID] = publicKey
+
+	manifestSrv := &manifestServer{}
+	manifestSrv.set(http.StatusOK, issuerA.URL, issuerB.URL)
+	server := httptest.NewServer(manifestSrv)
+	defer server.Close()
+
+	manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{ManifestURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	// Caller-supplied issuer options cannot widen the lookup either
+	config := VerifyConfig{BaseIssuerURLs: manifest.BaseIssuerURLs(), Timeout: 5 * time.Second}
+	_, err = manifest.Verify(forged, config)
+	if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError from issuer B, got %T: %v", err, err)
+	}
+	if fetchesB.Load() != 1 {
+		t.Errorf("Expected the key to be looked up at issuer B only, got %d fetches", fetchesB.Load())
+	}
+}
+
+func TestIssuerManifest_Verify_IssuerNotListed(t *testing.T) {
+	var fetches atomic.Int64
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer issuer.Close()
+
+	manifestSrv := &manifestServer{}
+	manifestSrv.set(http.StatusOK, issuer.URL)
+	server := httptest.NewServer(manifestSrv)
+	defer server.Close()
+
+	manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{ManifestURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	keyID := uuid.New()
+	for name, iss := range map[string]string{
+		"other host":        "https://evil.example.com/" + keyID.String(),
+		"nested under base": issuer.URL + "/tenant/" + keyID.String(),
+		"missing issuer":    "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			token, _, err := createTokenWithIssuer(iss, keyID)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+			if _, err := manifest.Verify(token, VerifyConfig{}); err == nil {
+				t.Error("Expected error for issuer outside the manifest")
+			}
+		})
+	}
+	if fetches.Load() != 0 {
+		t.Errorf("Expected no fetches for unlisted issuers, got %d", fetches.Load())
+	}
+}
+
+func TestIssuerManifest_RefreshKeepsPreviousOnFailure(t *testing.T) {
+	manifestSrv := &manifestServer{}
+	manifestSrv.set(http.StatusOK, "https://a.example.com")
+	server := httptest.NewServer(manifestSrv)
+	defer server.Close()
+
+	manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{ManifestURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	manifestSrv.set(http.StatusOK, "https://a.example.com", "https://b.example.com")
+	if err := manifest.Refresh(context.Background()); err != nil {
+		t.Fatalf("Expected refresh to succeed, got: %v", err)
+	}
+	if len(manifest.BaseIssuerURLs()) != 2 {
+		t.Errorf("Expected 2 issuers after refresh, got %v", manifest.BaseIssuerURLs())
+	}
+
+	manifestSrv.set(http.StatusServiceUnavailable)
+	err = manifest.Refresh(context.Background())
+	if _, ok := err.(*errors.FetchError); !ok {
+		t.Errorf("Expected FetchError, got %T: %v", err, err)
+	}
+	if len(manifest.BaseIssuerURLs()) != 2 {
+		t.Errorf("Expected previous issuers to be kept, got %v", manifest.BaseIssuerURLs())
+	}
+}
+
+func TestIssuerManifest_RefreshKeepsKeyCaches(t *testing.T) {
+	keyID := uuid.New()
+	keys := map[uuid.UUID]*rsa.PublicKey{}
+	jwksServer := newJWKSServer(t, keys)
+	var fetches atomic.Int64
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		jwksServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer issuer.Close()
+
+	token, publicKey, err := createTokenWithIssuer(issuer.URL+"/"+keyID.String(), keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keys[keyID] = publicKey
+
+	manifestSrv := &manifestServer{}
+	manifestSrv.set(http.StatusOK, issuer.URL)
+	server := httptest.NewServer(manifestSrv)
+	defer server.Close()
+
+	manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{ManifestURL: server.URL, CacheTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	config := VerifyConfig{Timeout: 5 * time.Second}
+	if _, err := manifest.Verify(token, config); err != nil {
+		t.Fatalf("Expected token to verify, got: %v", err)
+	}
+
+	// Another issuer joins; the unchanged one keeps its cached JWKS
+	manifestSrv.set(http.StatusOK, issuer.URL, "https://b.example.com")
+	if err := manifest.Refresh(context.Background()); err != nil {
+		t.Fatalf("Expected refresh to succeed, got: %v", err)
+	}
+	if _, err := manifest.Verify(token, config); err != nil {
+		t.Fatalf("Expected token to verify after refresh, got: %v", err)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the cached JWKS to survive the refresh, got %d fetches", fetches.Load())
+	}
+}
+
+func TestIssuerManifest_BackgroundRefresh(t *testing.T) {
+	manifestSrv := &manifestServer{}
+	manifestSrv.set(http.StatusOK, "https://a.example.com")
+	server := httptest.NewServer(manifestSrv)
+	defer server.Close()
+
+	manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{
+		ManifestURL:     server.URL,
+		RefreshInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	defer manifest.Close()
+
+	manifestSrv.set(http.StatusOK, "https://b.example.com")
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if slices.Equal(manifest.BaseIssuerURLs(), []string{"https://b.example.com"}) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected background refresh to pick up new issuers, got %v", manifest.BaseIssuerURLs())
+}
+
+func TestLoadIssuerManifest_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"not found", http.NotFound},
+		{"not a JSON array", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"issuers":"nope"}`))
+		}},
+		{"invalid issuer URL", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`["not a url"]`))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			manifest, err := LoadIssuerManifest(context.Background(), IssuerManifestConfig{ManifestURL: server.URL})
+			if manifest != nil {
+				t.Error("Expected manifest to be nil")
+			}
+			if _, ok := err.(*errors.FetchError); !ok {
+				t.Errorf("Expected FetchError, got %T: %v", err, err)
+			}
+		})
+	}
+}
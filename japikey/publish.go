@@ -0,0 +1,150 @@
+package japikey
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+// DefaultMaxPublishedKeys is the cap on keys in the combined JWKS applied when
+// PublishJWKSConfig.MaxPublishedKeys is 0
+const DefaultMaxPublishedKeys = 100
+
+// PublishJWKSConfig configures PublishJWKS.
+type PublishJWKSConfig struct {
+	// Overwrite replaces files that already exist. By default an existing file fails the publish
+	// before anything is written.
+	Overwrite bool
+
+	// Combined additionally writes every key into one JWKS at .well-known/jwks.json. A static host
+	// cannot filter by the kid query parameter the JWKS router requires there, so unlike the
+	// router this file lists all keys; it is meant for generic JWKS consumers.
+	Combined bool
+
+	// MaxPublishedKeys caps the number of keys in the combined JWKS, so that keys accumulating
+	// without pruning cannot grow it without bound. When more keys are given, only the
+	// MaxPublishedKeys most recently active (see LastActive) are combined and a warning is logged,
+	// since pruning is overdue. Per-kid files are written for every key regardless.
+	//
+	// With a Keystore, SetRetention bounds the previously active keys kept after Rotate, and Prune
+	// drops older ones; keep this cap above the retained count (plus the active key) so that it
+	// only trips when retention is not keeping up. 0 = DefaultMaxPublishedKeys applied.
+	MaxPublishedKeys int
+
+	// LastActive optionally gives the time each key last signed a token, e.g. when it was rotated
+	// out, to pick the keys kept by MaxPublishedKeys. Keys without an entry rank least recent.
+	LastActive map[uuid.UUID]time.Time
+}
+
+// PublishJWKS writes a static key-hosting tree into dir, for serving from object storage or a CDN
+// without running the JWKS router. Each key is written as a single-key JWKS at
+// <kid>/.well-known/jwks.json, the same layout the router serves, so verifiers using an issuer
+// base URL pointing at the hosted tree work unchanged.
+//
+// Every key is validated and, unless config.Overwrite is set, every target checked for an
+// existing file before anything is written. Invalid input returns a ValidationError; a failed
+// write returns an InternalError and may leave earlier files in place.
+func PublishJWKS(dir string, keys map[uuid.UUID]*rsa.PublicKey, config PublishJWKSConfig) error {
+	if dir == "" {
+		return errors.NewValidationError("publish directory cannot be empty")
+	}
+	if len(keys) == 0 {
+		return errors.NewValidationError("at least one key is required")
+	}
+
+	// Sorted so that the combined file and any error are deterministic
+	kids := make([]uuid.UUID, 0, len(keys))
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	slices.SortFunc(kids, func(a, b uuid.UUID) int {
+		return bytes.Compare(a[:], b[:])
+	})
+
+	type publishedFile struct {
+		path string
+		data []byte
+	}
+	var files []publishedFile
+	sets := make([]*jwks.JWKS, 0, len(kids))
+	for _, kid := range kids {
+		keySet, err := jwks.NewJWKS(keys[kid], kid)
+		if err != nil {
+			return errors.NewValidationError("invalid key " + kid.String() + ": " + err.Error())
+		}
+		data, err := keySet.MarshalJSON()
+		if err != nil {
+			return errors.NewInternalError("failed to encode JWKS for key " + kid.String())
+		}
+		sets = append(sets, keySet)
+		files = append(files, publishedFile{
+			path: filepath.Join(dir, kid.String(), ".well-known", "jwks.json"),
+			data: data,
+		})
+	}
+
+	if config.Combined {
+		data, err := jwks.MarshalKeySet(mostRecentlyActive(kids, sets, config))
+		if err != nil {
+			return errors.NewInternalError("failed to encode combined JWKS")
+		}
+		files = append(files, publishedFile{path: filepath.Join(dir, ".well-known", "jwks.json"), data: data})
+	}
+
+	if !config.Overwrite {
+		for _, file := range files {
+			if _, err := os.Lstat(file.path); err == nil {
+				return errors.NewValidationError("refusing to overwrite existing file " + file.path)
+			}
+		}
+	}
+
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0o755); err != nil {
+			return errors.NewInternalError("failed to create directory for " + file.path + ": " + err.Error())
+		}
+		if err := os.WriteFile(file.path, file.data, 0o644); err != nil {
+			return errors.NewInternalError("failed to write " + file.path + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// mostRecentlyActive returns the sets, in kid order, of at most config.MaxPublishedKeys keys,
+// keeping the most recently active, and logs a warning if any were left out.
+func mostRecentlyActive(kids []uuid.UUID, sets []*jwks.JWKS, config PublishJWKSConfig) []*jwks.JWKS {
+	limit := config.MaxPublishedKeys
+	if limit <= 0 {
+		limit = DefaultMaxPublishedKeys
+	}
+	if len(sets) <= limit {
+		return sets
+	}
+	log.Printf("[JAPIKey] Combined JWKS capped at %d of %d keys; prune retired keys", limit, len(sets))
+
+	// Rank by recency, newest first; kids break ties so that the selection is deterministic
+	order := make([]int, len(kids))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return config.LastActive[kids[b]].Compare(config.LastActive[kids[a]])
+	})
+	kept := order[:limit]
+	slices.Sort(kept)
+
+	selected := make([]*jwks.JWKS, 0, limit)
+	for _, i := range kept {
+		selected = append(selected, sets[i])
+	}
+	return selected
+}
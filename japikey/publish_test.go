@@ -0,0 +1,239 @@
+package japikey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+func newPublishKeys(t *testing.T, n int) map[uuid.UUID]*rsa.PublicKey {
+	t.Helper()
+	keys := make(map[uuid.UUID]*rsa.PublicKey, n)
+	for range n {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		keys[uuid.New()] = &privateKey.PublicKey
+	}
+	return keys
+}
+
+func TestPublishJWKS_WritesRouteLayout(t *testing.T) {
+	dir := t.TempDir()
+	keys := newPublishKeys(t, 3)
+
+	if err := PublishJWKS(dir, keys, PublishJWKSConfig{Combined: true}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for kid, publicKey := range keys {
+		data, err := os.ReadFile(filepath.Join(dir, kid.String(), ".well-known", "jwks.json"))
+		if err != nil {
+			t.Fatalf("Expected JWKS file for %s: %v", kid, err)
+		}
+		if err := jwks.VerifyMatchesKey(data, publicKey, kid); err != nil {
+			t.Errorf("Expected published JWKS to match key %s, got: %v", kid, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".well-known", "jwks.json"))
+	if err != nil {
+		t.Fatalf("Expected combined JWKS file: %v", err)
+	}
+	var combined struct {
+		Keys []struct {
+			Kid uuid.UUID `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &combined); err != nil {
+		t.Fatalf("Failed to decode combined JWKS: %v", err)
+	}
+	if len(combined.Keys) != len(keys) {
+		t.Fatalf("Expected %d keys in combined JWKS, got %d", len(keys), len(combined.Keys))
+	}
+	for i, key := range combined.Keys {
+		if _, ok := keys[key.Kid]; !ok {
+			t.Errorf("Unexpected kid %s in combined JWKS", key.Kid)
+		}
+		if i > 0 && combined.Keys[i-1].Kid.String() >= key.Kid.String() {
+			t.Error("Expected combined keys sorted by kid")
+		}
+	}
+}
+
+func TestPublishJWKS_CombinedIsOptional(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := PublishJWKS(dir, newPublishKeys(t, 1), PublishJWKSConfig{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".well-known", "jwks.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no combined file, got: %v", err)
+	}
+}
+
+func TestPublishJWKS_CombinedCappedToMostRecentlyActive(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	dir := t.TempDir()
+	keys := newPublishKeys(t, 4)
+	now := time.Now()
+	lastActive := map[uuid.UUID]time.Time{}
+	var newest, newer uuid.UUID
+	i := 0
+	for kid := range keys {
+		switch i {
+		case 0:
+			newest = kid
+			lastActive[kid] = now
+		case 1:
+			newer = kid
+			lastActive[kid] = now.Add(-time.Hour)
+		case 2:
+			lastActive[kid] = now.Add(-2 * time.Hour)
+		}
+		i++
+	}
+
+	err := PublishJWKS(dir, keys, PublishJWKSConfig{Combined: true, MaxPublishedKeys: 2, LastActive: lastActive})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".well-known", "jwks.json"))
+	if err != nil {
+		t.Fatalf("Expected combined JWKS file: %v", err)
+	}
+	var combined struct {
+		Keys []struct {
+			Kid uuid.UUID `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &combined); err != nil {
+		t.Fatalf("Failed to decode combined JWKS: %v", err)
+	}
+	var published []uuid.UUID
+	for _, key := range combined.Keys {
+		published = append(published, key.Kid)
+	}
+	if len(published) != 2 || !slices.Contains(published, newest) || !slices.Contains(published, newer) {
+		t.Errorf("Expected the 2 most recently active keys %s and %s, got %v", newest, newer, published)
+	}
+	if !strings.Contains(logs.String(), "capped at 2 of 4 keys") {
+		t.Errorf("Expected a warning about the cap, got log %q", logs.String())
+	}
+
+	// Every key still gets its per-kid file
+	for kid := range keys {
+		if _, err := os.Stat(filepath.Join(dir, kid.String(), ".well-known", "jwks.json")); err != nil {
+			t.Errorf("Expected JWKS file for %s: %v", kid, err)
+		}
+	}
+}
+
+func TestPublishJWKS_RefusesOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	keys := newPublishKeys(t, 1)
+	if err := PublishJWKS(dir, keys, PublishJWKSConfig{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Nothing is written when any target exists, including files for new keys
+	var newKid uuid.UUID
+	for kid, publicKey := range newPublishKeys(t, 1) {
+		keys[kid] = publicKey
+		newKid = kid
+	}
+	err := PublishJWKS(dir, keys, PublishJWKSConfig{})
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, newKid.String())); !os.IsNotExist(err) {
+		t.Errorf("Expected no files written for %s, got: %v", newKid, err)
+	}
+
+	if err := PublishJWKS(dir, keys, PublishJWKSConfig{Overwrite: true}); err != nil {
+		t.Fatalf("Expected overwrite to succeed, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, newKid.String(), ".well-known", "jwks.json")); err != nil {
+		t.Errorf("Expected JWKS file for %s: %v", newKid, err)
+	}
+}
+
+func TestPublishJWKS_InvalidInput(t *testing.T) {
+	validKey := newPublishKeys(t, 1)
+
+	tests := []struct {
+		name string
+		dir  string
+		keys map[uuid.UUID]*rsa.PublicKey
+	}{
+		{"empty directory", "", validKey},
+		{"no keys", t.TempDir(), nil},
+		{"nil kid", t.TempDir(), map[uuid.UUID]*rsa.PublicKey{uuid.Nil: {}}},
+		{"nil key", t.TempDir(), map[uuid.UUID]*rsa.PublicKey{uuid.New(): nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PublishJWKS(tt.dir, tt.keys, PublishJWKSConfig{})
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestPublishJWKS_StaticHostingRoundTrip(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keyID, err := jwks.ThumbprintKeyID(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to derive key ID: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := PublishJWKS(dir, map[uuid.UUID]*rsa.PublicKey{keyID: &privateKey.PublicKey}, PublishJWKSConfig{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	apiKey, err := NewJAPIKey(Config{
+		Subject:    "test-user",
+		Issuer:     server.URL + "/" + keyID.String(),
+		Audience:   "test-audience",
+		ExpiresAt:  time.Now().Add(time.Hour),
+		PrivateKey: privateKey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{BaseIssuerURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create remote key func: %v", err)
+	}
+	if _, err := Verify(apiKey.JWT, VerifyConfig{BaseIssuerURL: server.URL}, keyFunc); err != nil {
+		t.Errorf("Expected token to verify against the statically hosted JWKS, got: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package japikey
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"io"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// randomnessSampleSize is the number of bytes read per sample by CheckRandomness
+const randomnessSampleSize = 32
+
+// CheckRandomness is a startup self-test of the system's randomness source. It reads random
+// samples, generates a key pair and round-trips a signature with it, so that a server with a
+// broken entropy source can fail fast at boot instead of failing (or worse, issuing weak keys)
+// at runtime. Any failure is reported as a RandomnessError.
+func CheckRandomness() error {
+	return checkRandomness(rand.Reader)
+}
+
+func checkRandomness(reader io.Reader) error {
+	first := make([]byte, randomnessSampleSize)
+	second := make([]byte, randomnessSampleSize)
+	if _, err := io.ReadFull(reader, first); err != nil {
+		return errors.NewRandomnessError("failed to read from the randomness source")
+	}
+	if _, err := io.ReadFull(reader, second); err != nil {
+		return errors.NewRandomnessError("failed to read from the randomness source")
+	}
+	// Two identical samples, or an all-zero sample, are vanishingly unlikely from a working source
+	if bytes.Equal(first, second) || bytes.Equal(first, make([]byte, randomnessSampleSize)) {
+		return errors.NewRandomnessError("randomness source returned repeated output")
+	}
+
+	privateKey, err := rsa.GenerateKey(reader, 2048)
+	if err != nil {
+		return errors.NewRandomnessError("failed to generate a test RSA key pair")
+	}
+	if err := privateKey.Validate(); err != nil {
+		return errors.NewRandomnessError("generated test RSA key pair is invalid")
+	}
+
+	digest := sha256.Sum256(first)
+	signature, err := rsa.SignPKCS1v15(reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return errors.NewRandomnessError("failed to sign with the test RSA key pair")
+	}
+	if err := rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return errors.NewRandomnessError("test RSA key pair failed to verify its own signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package japikey
+
+import (
+	"bytes"
+	stderrors "errors"
+	"testing"
+	"testing/iotest"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// constantReader returns the same byte forever, like a stuck entropy source
+type constantReader byte
+
+func (r constantReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestCheckRandomness_Succeeds(t *testing.T) {
+	if err := CheckRandomness(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestCheckRandomness_DetectsBrokenSources(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader func() error
+	}{
+		{name: "read error", reader: func() error {
+			return checkRandomness(iotest.ErrReader(stderrors.New("no /dev/urandom")))
+		}},
+		{name: "short read", reader: func() error {
+			return checkRandomness(bytes.NewReader([]byte{1, 2, 3}))
+		}},
+		{name: "all zeros", reader: func() error {
+			return checkRandomness(constantReader(0))
+		}},
+		{name: "stuck output", reader: func() error {
+			return checkRandomness(constantReader(0xAB))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.reader()
+			if err == nil {
+				t.Fatal("Expected error for broken randomness source")
+			}
+			if _, ok := err.(*errors.RandomnessError); !ok {
+				t.Errorf("Expected RandomnessError, got %T: %v", err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package japikey
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+// MaxJWKSResponseSize is the maximum size of a fetched JWKS document (64KB)
+const MaxJWKSResponseSize = 64 * 1024
+
+// RemoteKeyFuncConfig configures a JWKCallback that fetches keys from an issuer's JWKS endpoint.
+type RemoteKeyFuncConfig struct {
+	// BaseIssuerURL is the base URL of the issuer; the key for kid is fetched from
+	// BaseIssuerURL/kid/.well-known/jwks.json
+	BaseIssuerURL string
+
+	// StaticJWKSURL fetches a single JWKS from this fixed URL instead of templating the kid into
+	// the path, for issuers that publish one static key. The token's kid must match the key's kid.
+	// Mutually exclusive with BaseIssuerURL.
+	StaticJWKSURL string
+
+	// Client is the HTTP client used for fetching. nil = http.DefaultClient
+	Client *http.Client
+
+	// Timeout bounds each fetch. 0 = 5-second default applied
+	Timeout time.Duration
+
+	// CacheTTL reuses each fetched and parsed JWKS for this long, so lookups within the TTL skip
+	// both the HTTP fetch and JSON parsing. Only successful fetches are cached, and at most
+	// DefaultKeyCacheSize JWKS documents are held. 0 = no caching.
+	// Concurrent lookups of the same URL always share a single fetch, cached or not.
+	CacheTTL time.Duration
+}
+
+// NewRemoteKeyFunc creates a JWKCallback that fetches the JWKS for each key ID from the issuer.
+// A 404 response or a kid that does not match the fetched key maps to KeyNotFoundError;
+// any other failure maps to FetchError.
+func NewRemoteKeyFunc(config RemoteKeyFuncConfig) (JWKCallback, error) {
+	if config.BaseIssuerURL == "" && config.StaticJWKSURL == "" {
+		return nil, errors.NewValidationError("base issuer URL or static JWKS URL is required")
+	}
+	if config.BaseIssuerURL != "" && config.StaticJWKSURL != "" {
+		return nil, errors.NewValidationError("base issuer URL and static JWKS URL are mutually exclusive")
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	fetcher := &jwksFetcher{
+		config:   config,
+		now:      time.Now,
+		cached:   make(map[string]cachedJWKS),
+		inFlight: make(map[string]*jwksFetch),
+	}
+
+	return func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		url := config.StaticJWKSURL
+		if url == "" {
+			url = jwksURL(config.BaseIssuerURL, keyID)
+		}
+
+		keySet, err := fetcher.get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		// GetPublicKey also sanity-checks that a static key belongs to the token's kid
+		return keySet.GetPublicKey(keyID)
+	}, nil
+}
+
+// jwksFetcher fetches and parses JWKS documents, sharing concurrent fetches of the same URL and
+// optionally caching the parsed result. Parsed JWKS values are shared read-only between callers.
+type jwksFetcher struct {
+	config RemoteKeyFuncConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	cached   map[string]cachedJWKS
+	inFlight map[string]*jwksFetch
+}
+
+type cachedJWKS struct {
+	keySet    *jwks.JWKS
+	expiresAt time.Time
+}
+
+// jwksFetch is an in-flight fetch; keySet and err are set before done is closed.
+type jwksFetch struct {
+	done   chan struct{}
+	keySet *jwks.JWKS
+	err    error
+}
+
+func (f *jwksFetcher) get(url string) (*jwks.JWKS, error) {
+	f.mu.Lock()
+	if entry, ok := f.cached[url]; ok {
+		if f.now().Before(entry.expiresAt) {
+			f.mu.Unlock()
+			return entry.keySet, nil
+		}
+		delete(f.cached, url)
+	}
+	if fetch, ok := f.inFlight[url]; ok {
+		f.mu.Unlock()
+		<-fetch.done
+		return fetch.keySet, fetch.err
+	}
+	fetch := &jwksFetch{done: make(chan struct{})}
+	f.inFlight[url] = fetch
+	f.mu.Unlock()
+
+	fetch.keySet, fetch.err = f.fetch(url)
+
+	f.mu.Lock()
+	delete(f.inFlight, url)
+	if fetch.err == nil && f.config.CacheTTL > 0 {
+		f.storeLocked(url, fetch.keySet)
+	}
+	f.mu.Unlock()
+	close(fetch.done)
+
+	return fetch.keySet, fetch.err
+}
+
+func (f *jwksFetcher) fetch(url string) (*jwks.JWKS, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.Timeout)
+	defer cancel()
+
+	body, err := fetchURL(ctx, f.config.Client, url, MaxJWKSResponseSize)
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &jwks.JWKS{}
+	if err := keySet.UnmarshalJSON(body); err != nil {
+		return nil, errors.NewFetchError("issuer returned an invalid JWKS")
+	}
+	return keySet, nil
+}
+
+// storeLocked caches a parsed JWKS, dropping expired entries when full. f.mu must be held.
+func (f *jwksFetcher) storeLocked(url string, keySet *jwks.JWKS) {
+	now := f.now()
+	if len(f.cached) >= DefaultKeyCacheSize {
+		for cachedURL, entry := range f.cached {
+			if !now.Before(entry.expiresAt) {
+				delete(f.cached, cachedURL)
+			}
+		}
+		if len(f.cached) >= DefaultKeyCacheSize {
+			return
+		}
+	}
+	f.cached[url] = cachedJWKS{keySet: keySet, expiresAt: now.Add(f.config.CacheTTL)}
+}
+
+// JWKSURLForToken returns the URL of the JWKS a verifier should fetch for a token from
+// baseIssuer, matching the JWKS router's route pattern. The token is decoded WITHOUT verification;
+// its kid must be a UUID and its issuer must be baseIssuer/kid, so a token naming another issuer
+// never directs the fetch. Malformed tokens return the same typed errors as Verify.
+func JWKSURLForToken(tokenString, baseIssuer string) (string, error) {
+	if baseIssuer == "" {
+		return "", errors.NewValidationError("base issuer URL is required")
+	}
+
+	header, claims, err := ParseClaimsUnverified(tokenString)
+	if err != nil {
+		return "", err
+	}
+	keyID, err := extractKeyIDFromHeader(header)
+	if err != nil {
+		return "", err
+	}
+	issuer, err := jwt.MapClaims(claims).GetIssuer()
+	if err != nil {
+		return "", errors.NewIssuerFormatError("Invalid issuer")
+	}
+	if err := validateIssuer(issuer, VerifyConfig{BaseIssuerURL: baseIssuer}, keyID); err != nil {
+		return "", err
+	}
+
+	return jwksURL(baseIssuer, keyID), nil
+}
+
+// jwksURL returns the URL of the JWKS for keyID, matching the JWKS router's route pattern.
+func jwksURL(baseIssuerURL string, keyID uuid.UUID) string {
+	return strings.TrimSuffix(baseIssuerURL, "/") + "/" + keyID.String() + "/.well-known/jwks.json"
+}
+
+// fetchURL performs a GET request and returns the body, limited to maxSize bytes.
+func fetchURL(ctx context.Context, client *http.Client, url string, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.NewFetchError("invalid fetch URL")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.NewFetchError("failed to fetch " + url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.NewKeyNotFoundError("not found at " + url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.NewFetchError(fmt.Sprintf("unexpected status %d fetching %s", resp.StatusCode, url))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, errors.NewFetchError("failed to read response from " + url)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, errors.NewFetchError(fmt.Sprintf("response from %s exceeds maximum size of %d bytes", url, maxSize))
+	}
+
+	return body, nil
+}
@@ -0,0 +1,424 @@
+package japikey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+// newJWKSServer serves the JWKS for each key under /{kid}/.well-known/jwks.json
+func newJWKSServer(t *testing.T, keys map[uuid.UUID]*rsa.PublicKey) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{kid}/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		kid, err := uuid.Parse(r.PathValue("kid"))
+		if err != nil || keys[kid] == nil {
+			http.NotFound(w, r)
+			return
+		}
+		keySet, err := jwks.NewJWKS(keys[kid], kid)
+		if err != nil {
+			t.Errorf("Failed to create JWKS: %v", err)
+			return
+		}
+		body, _ := keySet.MarshalJSON()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewRemoteKeyFunc_VerifiesTokenFromIssuer(t *testing.T) {
+	keyID := uuid.New()
+
+	keys := map[uuid.UUID]*rsa.PublicKey{}
+	server := newJWKSServer(t, keys)
+
+	tokenString, pubKey, err := createTokenWithIssuer(server.URL+"/"+keyID.String(), keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keys[keyID] = pubKey
+
+	keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{BaseIssuerURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create remote key func: %v", err)
+	}
+
+	result, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: server.URL, Timeout: 5 * time.Second}, keyFunc)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.KeyID != keyID {
+		t.Errorf("Expected key ID %s, got %s", keyID, result.KeyID)
+	}
+}
+
+func TestNewRemoteKeyFunc_ErrorMapping(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/missing/", http.NotFound)
+	mux.HandleFunc("/broken/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/garbage/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":"nope"}`))
+	})
+	mux.HandleFunc("/huge/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat(" ", MaxJWKSResponseSize+1)))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"404 maps to KeyNotFoundError", "/missing", "KeyNotFoundError"},
+		{"5xx maps to FetchError", "/broken", "FetchError"},
+		{"invalid JWKS maps to FetchError", "/garbage", "FetchError"},
+		{"oversized response maps to FetchError", "/huge", "FetchError"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{BaseIssuerURL: server.URL + tt.path})
+			if err != nil {
+				t.Fatalf("Failed to create remote key func: %v", err)
+			}
+
+			_, err = keyFunc(uuid.New())
+			switch tt.expected {
+			case "KeyNotFoundError":
+				if _, ok := err.(*errors.KeyNotFoundError); !ok {
+					t.Errorf("Expected KeyNotFoundError, got %T: %v", err, err)
+				}
+			case "FetchError":
+				if _, ok := err.(*errors.FetchError); !ok {
+					t.Errorf("Expected FetchError, got %T: %v", err, err)
+				}
+			}
+		})
+	}
+
+	t.Run("unreachable issuer maps to FetchError", func(t *testing.T) {
+		unreachable := httptest.NewServer(http.NotFoundHandler())
+		unreachable.Close()
+
+		keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{BaseIssuerURL: unreachable.URL})
+		if err != nil {
+			t.Fatalf("Failed to create remote key func: %v", err)
+		}
+		if _, err := keyFunc(uuid.New()); err == nil {
+			t.Error("Expected error for unreachable issuer")
+		} else if _, ok := err.(*errors.FetchError); !ok {
+			t.Errorf("Expected FetchError, got %T", err)
+		}
+	})
+
+	t.Run("missing base URL", func(t *testing.T) {
+		if _, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{}); err == nil {
+			t.Error("Expected error for missing base issuer URL")
+		}
+	})
+}
+
+func TestVerify_RemoteFetchFailureIsFetchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	keyID := uuid.New()
+	tokenString, _, err := createTokenWithIssuer(server.URL+"/"+keyID.String(), keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{BaseIssuerURL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create remote key func: %v", err)
+	}
+
+	_, err = Verify(tokenString, VerifyConfig{BaseIssuerURL: server.URL}, keyFunc)
+	var fetchErr *errors.FetchError
+	if !stderrors.As(err, &fetchErr) {
+		t.Errorf("Expected FetchError for an issuer outage, got %T: %v", err, err)
+	}
+}
+
+func TestNewRemoteKeyFunc_StaticJWKSURL(t *testing.T) {
+	keyID := uuid.New()
+	tokenString, pubKey, err := createTokenWithIssuer("https://example.com/"+keyID.String(), keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	var requestedPaths []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/static/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		requestedPaths = append(requestedPaths, r.URL.Path)
+		keySet, err := jwks.NewJWKS(pubKey, keyID)
+		if err != nil {
+			t.Errorf("Failed to create JWKS: %v", err)
+			return
+		}
+		body, _ := keySet.MarshalJSON()
+		_, _ = w.Write(body)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{StaticJWKSURL: server.URL + "/static/jwks.json"})
+	if err != nil {
+		t.Fatalf("Failed to create remote key func: %v", err)
+	}
+
+	t.Run("matching kid verifies", func(t *testing.T) {
+		result, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result.KeyID != keyID {
+			t.Errorf("Expected key ID %s, got %s", keyID, result.KeyID)
+		}
+	})
+
+	t.Run("mismatched kid maps to KeyNotFoundError", func(t *testing.T) {
+		if _, err := keyFunc(uuid.New()); err == nil {
+			t.Error("Expected error for kid that does not match the static key")
+		} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+			t.Errorf("Expected KeyNotFoundError, got %T: %v", err, err)
+		}
+	})
+
+	for _, path := range requestedPaths {
+		if path != "/static/jwks.json" {
+			t.Errorf("Expected only the static URL to be fetched, got %s", path)
+		}
+	}
+	if len(requestedPaths) != 2 {
+		t.Errorf("Expected 2 fetches, got %d", len(requestedPaths))
+	}
+
+	t.Run("both URLs configured", func(t *testing.T) {
+		_, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{
+			BaseIssuerURL: server.URL,
+			StaticJWKSURL: server.URL + "/static/jwks.json",
+		})
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError, got %T", err)
+		}
+	})
+}
+
+// newCountingJWKSServer serves a single key at every path, counting requests. If release is
+// non-nil, each request blocks until it is closed.
+func newCountingJWKSServer(t testing.TB, publicKey *rsa.PublicKey, keyID uuid.UUID, fetches *atomic.Int32, release chan struct{}) *httptest.Server {
+	t.Helper()
+	keySet, err := jwks.NewJWKS(publicKey, keyID)
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	body, _ := keySet.MarshalJSON()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if release != nil {
+			<-release
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewRemoteKeyFunc_CacheTTL(t *testing.T) {
+	signer := newTestSigner(t)
+
+	t.Run("parsed JWKS reused within TTL", func(t *testing.T) {
+		var fetches atomic.Int32
+		server := newCountingJWKSServer(t, signer.publicKey, signer.keyID, &fetches, nil)
+
+		keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{StaticJWKSURL: server.URL, CacheTTL: time.Minute})
+		if err != nil {
+			t.Fatalf("Failed to create remote key func: %v", err)
+		}
+
+		var first *rsa.PublicKey
+		for range 5 {
+			publicKey, err := keyFunc(signer.keyID)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if first == nil {
+				first = publicKey
+			} else if publicKey != first {
+				t.Error("Expected the parsed key to be shared between lookups")
+			}
+		}
+		if got := fetches.Load(); got != 1 {
+			t.Errorf("Expected 1 fetch, got %d", got)
+		}
+	})
+
+	t.Run("refetched after TTL", func(t *testing.T) {
+		var fetches atomic.Int32
+		server := newCountingJWKSServer(t, signer.publicKey, signer.keyID, &fetches, nil)
+
+		keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{StaticJWKSURL: server.URL, CacheTTL: 20 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Failed to create remote key func: %v", err)
+		}
+
+		if _, err := keyFunc(signer.keyID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if _, err := keyFunc(signer.keyID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if got := fetches.Load(); got != 2 {
+			t.Errorf("Expected 2 fetches, got %d", got)
+		}
+	})
+
+	t.Run("concurrent misses share one fetch", func(t *testing.T) {
+		var fetches atomic.Int32
+		release := make(chan struct{})
+		server := newCountingJWKSServer(t, signer.publicKey, signer.keyID, &fetches, release)
+
+		keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{StaticJWKSURL: server.URL})
+		if err != nil {
+			t.Fatalf("Failed to create remote key func: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := keyFunc(signer.keyID); err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			}()
+		}
+
+		// Give the goroutines time to join the in-flight fetch before releasing it
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if got := fetches.Load(); got != 1 {
+			t.Errorf("Expected 1 fetch, got %d", got)
+		}
+	})
+
+	t.Run("errors not cached", func(t *testing.T) {
+		var fetches atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			http.NotFound(w, r)
+		}))
+		defer server.Close()
+
+		keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{BaseIssuerURL: server.URL, CacheTTL: time.Minute})
+		if err != nil {
+			t.Fatalf("Failed to create remote key func: %v", err)
+		}
+		for range 2 {
+			if _, err := keyFunc(signer.keyID); err == nil {
+				t.Fatal("Expected error for missing key")
+			}
+		}
+		if got := fetches.Load(); got != 2 {
+			t.Errorf("Expected 2 fetches, got %d", got)
+		}
+	})
+}
+
+// BenchmarkRemoteKeyFunc_Concurrent compares allocations per lookup with and without caching
+// of the parsed JWKS. Run with: go test -bench RemoteKeyFunc -benchmem ./japikey/
+func BenchmarkRemoteKeyFunc_Concurrent(b *testing.B) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keyID := uuid.New()
+
+	for _, bench := range []struct {
+		name     string
+		cacheTTL time.Duration
+	}{
+		{"uncached", 0},
+		{"cached", time.Hour},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var fetches atomic.Int32
+			server := newCountingJWKSServer(b, &privateKey.PublicKey, keyID, &fetches, nil)
+
+			keyFunc, err := NewRemoteKeyFunc(RemoteKeyFuncConfig{StaticJWKSURL: server.URL, CacheTTL: bench.cacheTTL})
+			if err != nil {
+				b.Fatalf("Failed to create remote key func: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := keyFunc(keyID); err != nil {
+						b.Errorf("Expected no error, got: %v", err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(fetches.Load())/float64(b.N), "fetches/op")
+		})
+	}
+}
+
+func TestJWKSURLForToken(t *testing.T) {
+	tokenString, _, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	expected := "https://example.com/" + keyID.String() + "/.well-known/jwks.json"
+
+	for _, base := range []string{"https://example.com", "https://example.com/"} {
+		url, err := JWKSURLForToken(tokenString, base)
+		if err != nil {
+			t.Fatalf("Expected no error for base %s, got: %v", base, err)
+		}
+		if url != expected {
+			t.Errorf("Expected %s, got %s", expected, url)
+		}
+	}
+
+	if _, err := JWKSURLForToken(tokenString, "https://other.example.com"); err == nil {
+		t.Error("Expected error for a token from another issuer")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+
+	if _, err := JWKSURLForToken("not-a-token", "https://example.com"); err == nil {
+		t.Error("Expected error for a malformed token")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+
+	if _, err := JWKSURLForToken(tokenString, ""); err == nil {
+		t.Error("Expected error for an empty base issuer")
+	}
+}
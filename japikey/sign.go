@@ -3,6 +3,12 @@ package japikey
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -17,6 +23,52 @@ type Config struct {
 	Audience  string
 	ExpiresAt time.Time
 	Claims    jwt.MapClaims
+
+	// NotBefore optionally sets the nbf claim, so the token only becomes valid at that time. It
+	// must not be after ExpiresAt. Zero = no nbf claim.
+	NotBefore time.Time
+
+	// Confirmation optionally binds the token to a client key via the cnf claim
+	Confirmation *Confirmation
+
+	// AllowedAudiences restricts which audiences may be minted. An empty list allows any audience.
+	AllowedAudiences []string
+
+	// AudiencePolicy optionally enforces a tenancy rule between Issuer and Audience at mint time,
+	// e.g. that the audience is a subdomain of the issuer's host, catching misconfigured tokens
+	// before they are distributed. It is only called once both are set. Returning an error rejects
+	// the config with a ValidationError. nil = no policy.
+	AudiencePolicy func(issuer, audience string) error
+
+	// ReservedClaims lists claim names that Claims may not set, for claims the platform intends to
+	// control itself. An entry ending in "*" matches every claim with that prefix (e.g. "internal_*").
+	ReservedClaims []string
+
+	// IncludeKeyThumbprint adds the RFC 7638 thumbprint of the signing key to the token header,
+	// binding the token to that exact key rather than just its kid. See VerifyConfig.RequireKeyThumbprint.
+	IncludeKeyThumbprint bool
+
+	// MaxLifetime caps how far in the future ExpiresAt may be. 0 = no limit.
+	MaxLifetime time.Duration
+
+	// Signer optionally signs the token with an external key (e.g. in an HSM or KMS) instead of
+	// a freshly generated local key. The key ID and public key are taken from the signer.
+	// Local keys always use the public exponent 65537, since crypto/rsa does not expose the
+	// choice; a signer may use any odd exponent from 3 up to 2^31-1, e.g. when a
+	// compliance regime requires a specific one.
+	Signer Signer
+
+	// PrivateKey optionally signs the token with an existing key instead of a freshly generated
+	// one, e.g. to issue several API keys under one signing key or to use a pre-provisioned key.
+	// It must be at least 2048 bits. The key ID is ThumbprintKeyID of its public key, so every
+	// token signed with it shares a kid. It cannot be combined with Signer or KeyIDGenerator.
+	// nil = a new key is generated for each token.
+	PrivateKey *rsa.PrivateKey
+
+	// KeyIDGenerator optionally generates the key ID of a locally generated key, e.g. UUIDv7
+	// for time-ordered key IDs that sort by creation time. It must not return uuid.Nil, and it
+	// cannot be combined with Signer, which supplies its own key ID. nil = uuid.New (random v4).
+	KeyIDGenerator func() uuid.UUID
 }
 
 type JAPIKey struct {
@@ -42,26 +94,70 @@ func NewJAPIKey(config Config) (*JAPIKey, error) {
 		return nil, err
 	}
 
+	if config.Signer != nil {
+		return newExternallySignedJAPIKey(config)
+	}
+
+	if config.PrivateKey != nil {
+		keyID, err := jwks.ThumbprintKeyID(&config.PrivateKey.PublicKey)
+		if err != nil {
+			return nil, errors.NewInternalError("failed to derive key ID from private key")
+		}
+		return signJAPIKey(config, keyID, config.PrivateKey)
+	}
+
+	keyID := uuid.New()
+	if config.KeyIDGenerator != nil {
+		keyID = config.KeyIDGenerator()
+		if keyID == uuid.Nil {
+			return nil, errors.NewValidationError("key ID generator returned an empty key ID")
+		}
+	}
+
+	return newJAPIKey(config, keyID)
+}
+
+// NewServiceJAPIKey mints a minimal token for service-to-service (machine) identity: it carries
+// only the mandatory claims plus iat, no custom claims, and expires after ttl, which should be
+// kept short. The issuer is issuerBase/kid, so the token verifies against issuerBase.
+func NewServiceJAPIKey(subject, issuerBase, audience string, ttl time.Duration) (*JAPIKey, error) {
+	if ttl <= 0 {
+		return nil, errors.NewValidationError("ttl must be positive")
+	}
+
+	keyID := uuid.New()
+	now := time.Now()
+	config := Config{
+		Subject:   subject,
+		Issuer:    expectedIssuerFor(issuerBase, keyID),
+		Audience:  audience,
+		ExpiresAt: now.Add(ttl),
+		Claims:    jwt.MapClaims{"iat": now.Unix()},
+	}
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return newJAPIKey(config, keyID)
+}
+
+// newJAPIKey generates the key pair and signs the token for an already validated config,
+// using the given key ID.
+func newJAPIKey(config Config, keyID uuid.UUID) (*JAPIKey, error) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		return nil, errors.NewInternalError("failed to generate RSA key pair")
 	}
 
-	keyID := uuid.New()
+	return signJAPIKey(config, keyID, privateKey)
+}
 
-	claims := jwt.MapClaims{}
-	for k, v := range config.Claims {
-		claims[k] = v
+// signJAPIKey signs the token for an already validated config with privateKey, under the given key ID.
+func signJAPIKey(config Config, keyID uuid.UUID, privateKey *rsa.PrivateKey) (*JAPIKey, error) {
+	token, err := newToken(config, keyID, &privateKey.PublicKey)
+	if err != nil {
+		return nil, err
 	}
-	// Add the mandatory claims last, to ensure that user-provided claims cannot override them
-	claims["sub"] = config.Subject
-	claims["iss"] = config.Issuer
-	claims["aud"] = config.Audience
-	claims["exp"] = config.ExpiresAt.Unix()
-	claims["ver"] = "japikey-v1"
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	token.Header["kid"] = keyID
 
 	jwtString, err := token.SignedString(privateKey)
 	if err != nil {
@@ -77,14 +173,201 @@ func NewJAPIKey(config Config) (*JAPIKey, error) {
 	return result, nil
 }
 
+// EstimateTokenSize returns the length of the token NewJAPIKey would produce for config, without
+// generating a key, so callers adding many claims can compare it with MaxTokenSize before signing.
+// It is an estimate: a later expiry or claims that serialize differently may change the length by
+// a few bytes. The config is not otherwise validated; use ValidateConfig for that.
+func EstimateTokenSize(config Config) (int, error) {
+	keyID := uuid.Nil
+	// A 2048-bit modulus stands in for the locally generated key; only its size matters
+	publicKey := &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 2047), E: 65537}
+	if config.Signer != nil {
+		keyID = config.Signer.KeyID()
+		if signerKey := config.Signer.Public(); signerKey != nil {
+			publicKey = signerKey
+		}
+	}
+	if config.PrivateKey != nil {
+		publicKey = &config.PrivateKey.PublicKey
+		if thumbprintKeyID, err := jwks.ThumbprintKeyID(publicKey); err == nil {
+			keyID = thumbprintKeyID
+		}
+	}
+
+	if _, err := json.Marshal(config.Claims); err != nil {
+		return 0, errors.NewValidationError("claims must be JSON serializable")
+	}
+	token, err := newToken(config, keyID, publicKey)
+	if err != nil {
+		return 0, err
+	}
+	signingString, err := token.SigningString()
+	if err != nil {
+		return 0, errors.NewValidationError("failed to encode token")
+	}
+
+	signatureSize := base64.RawURLEncoding.EncodedLen(publicKey.Size())
+	return len(signingString) + len(".") + signatureSize, nil
+}
+
+// newToken builds the unsigned token for a config, to be signed by publicKey's private key.
+func newToken(config Config, keyID uuid.UUID, publicKey *rsa.PublicKey) (*jwt.Token, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, buildClaims(config))
+	// typ is set explicitly rather than relying on the library default, so strict verifiers can
+	// require it. kid is always the string form of the UUID, which Verify expects.
+	token.Header[TypeHeader] = TokenType
+	token.Header[KeyIDHeader] = keyID.String()
+
+	if config.IncludeKeyThumbprint {
+		thumbprint, err := jwks.Thumbprint(publicKey)
+		if err != nil {
+			return nil, errors.NewInternalError("failed to compute key thumbprint")
+		}
+		token.Header[KeyThumbprintHeader] = thumbprint
+	}
+
+	return token, nil
+}
+
+// buildClaims returns the token claims for a config.
+func buildClaims(config Config) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	for k, v := range config.Claims {
+		claims[k] = v
+	}
+	// Add the mandatory claims last, to ensure that user-provided claims cannot override them
+	claims["sub"] = config.Subject
+	claims["iss"] = config.Issuer
+	claims["aud"] = config.Audience
+	claims["exp"] = config.ExpiresAt.Unix()
+	claims["ver"] = "japikey-v1"
+	if !config.NotBefore.IsZero() {
+		claims["nbf"] = config.NotBefore.Unix()
+	}
+	if config.Confirmation != nil {
+		claims[ConfirmationClaim] = config.Confirmation.toClaim()
+	}
+	return claims
+}
+
+// reservedClaimsIn returns the names in claims that match a reserved pattern, sorted.
+func reservedClaimsIn(claims jwt.MapClaims, reserved []string) []string {
+	var names []string
+	for name := range claims {
+		for _, pattern := range reserved {
+			prefix, isPrefix := strings.CutSuffix(pattern, "*")
+			if name == pattern || (isPrefix && strings.HasPrefix(name, prefix)) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
 func validateConfig(config Config) error {
+	if problems := configProblems(config); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// ValidateConfig runs every non-cryptographic check on a config without paying for key
+// generation, e.g. to give fast feedback on a form. It returns a ConfigError that lists every
+// problem found rather than stopping at the first one. NewJAPIKey runs the same checks.
+func ValidateConfig(config Config) error {
+	if problems := configProblems(config); len(problems) > 0 {
+		return errors.NewConfigError(problems)
+	}
+	return nil
+}
+
+// configProblems returns a ValidationError for every problem with the config, in a stable order.
+func configProblems(config Config) []error {
+	var problems []error
+
 	if config.Subject == "" {
-		return errors.NewValidationError("subject cannot be empty")
+		problems = append(problems, errors.NewValidationError("subject cannot be empty"))
 	}
 
-	if config.ExpiresAt.Before(time.Now()) {
-		return errors.NewValidationError("expiration time must be in the future")
+	now := time.Now()
+	if config.ExpiresAt.Before(now) {
+		problems = append(problems, errors.NewValidationError("expiration time must be in the future"))
+	} else if config.MaxLifetime > 0 && config.ExpiresAt.Sub(now) > config.MaxLifetime {
+		problems = append(problems, errors.NewValidationError("expiration time exceeds the maximum lifetime"))
 	}
 
-	return nil
+	if !config.NotBefore.IsZero() && config.NotBefore.After(config.ExpiresAt) {
+		problems = append(problems, errors.NewValidationError("not before time cannot be after the expiration time"))
+	}
+
+	if config.Issuer == "" {
+		problems = append(problems, errors.NewValidationError("issuer cannot be empty"))
+	} else if issuerURL, err := url.Parse(config.Issuer); err != nil ||
+		(issuerURL.Scheme != "https" && issuerURL.Scheme != "http") || issuerURL.Host == "" {
+		problems = append(problems, errors.NewValidationError("issuer must be an absolute http or https URL"))
+	}
+
+	if config.Audience == "" {
+		problems = append(problems, errors.NewValidationError("audience cannot be empty"))
+	} else if strings.TrimSpace(config.Audience) != config.Audience {
+		problems = append(problems, errors.NewValidationError("audience cannot have leading or trailing whitespace"))
+	}
+
+	if config.AudiencePolicy != nil && config.Issuer != "" && config.Audience != "" {
+		if err := config.AudiencePolicy(config.Issuer, config.Audience); err != nil {
+			if validationErr, ok := err.(*errors.ValidationError); ok {
+				problems = append(problems, validationErr)
+			} else {
+				problems = append(problems, errors.NewValidationError("audience rejected by audience policy: "+err.Error()))
+			}
+		}
+	}
+
+	for _, name := range reservedClaimsIn(config.Claims, config.ReservedClaims) {
+		problems = append(problems, errors.NewValidationError("claim '"+name+"' is reserved and cannot be set"))
+	}
+
+	if _, err := json.Marshal(config.Claims); err != nil {
+		problems = append(problems, errors.NewValidationError("claims must be JSON serializable"))
+	}
+
+	if config.Confirmation != nil && config.Confirmation.isEmpty() {
+		problems = append(problems, errors.NewValidationError("confirmation must contain at least one thumbprint"))
+	}
+
+	if len(config.AllowedAudiences) > 0 && !slices.Contains(config.AllowedAudiences, config.Audience) {
+		problems = append(problems, errors.NewValidationError("audience is not in the allowed audiences list"))
+	}
+
+	if config.Signer != nil {
+		if publicKey := config.Signer.Public(); publicKey == nil {
+			problems = append(problems, errors.NewValidationError("signer public key cannot be nil"))
+		} else if err := jwks.CheckPublicExponent(publicKey.E); err != nil {
+			problems = append(problems, err)
+		}
+		if config.Signer.KeyID() == uuid.Nil {
+			problems = append(problems, errors.NewValidationError("signer key ID cannot be empty"))
+		}
+		if config.KeyIDGenerator != nil {
+			problems = append(problems, errors.NewValidationError("key ID generator cannot be used with an external signer"))
+		}
+	}
+
+	if config.PrivateKey != nil {
+		if config.PrivateKey.N == nil || config.PrivateKey.N.BitLen() < 2048 {
+			problems = append(problems, errors.NewValidationError("private key must be at least 2048 bits"))
+		} else if err := jwks.CheckPublicExponent(config.PrivateKey.E); err != nil {
+			problems = append(problems, err)
+		}
+		if config.Signer != nil {
+			problems = append(problems, errors.NewValidationError("private key cannot be used with an external signer"))
+		}
+		if config.KeyIDGenerator != nil {
+			problems = append(problems, errors.NewValidationError("key ID generator cannot be used with a private key, whose key ID is derived from it"))
+		}
+	}
+
+	return problems
 }
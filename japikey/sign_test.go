@@ -1,7 +1,10 @@
 package japikey
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -11,6 +14,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
 )
 
 func TestNewJAPIKey_WithValidInputs_ReturnsValidJWT(t *testing.T) {
@@ -965,3 +969,702 @@ func TestJAPIKey_ToJWKS_ValidateAgainstJWXTool(t *testing.T) {
 		t.Error("jwx tool returned empty output for valid JWK")
 	}
 }
+
+func TestNewJAPIKey_WithAllowedAudiences(t *testing.T) {
+	tests := []struct {
+		name             string
+		audience         string
+		allowedAudiences []string
+		expectError      bool
+	}{
+		{"empty allowlist allows any audience", "anything", nil, false},
+		{"audience in allowlist", "api", []string{"web", "api"}, false},
+		{"audience not in allowlist", "admin", []string{"web", "api"}, true},
+		{"empty audience not in allowlist", "", []string{"web"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			config := Config{
+				Subject:          "test-user",
+				Issuer:           "https://example.com",
+				Audience:         tt.audience,
+				ExpiresAt:        time.Now().Add(1 * time.Hour),
+				AllowedAudiences: tt.allowedAudiences,
+			}
+
+			// Act
+			result, err := NewJAPIKey(config)
+
+			// Assert
+			if !tt.expectError {
+				if err != nil {
+					t.Errorf("Expected no error, but got: %v", err)
+				}
+				return
+			}
+
+			if result != nil {
+				t.Error("Expected result to be nil for disallowed audience")
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestNewJAPIKey_WithConfirmation_EmbedsCnfClaim(t *testing.T) {
+	// Arrange
+	config := Config{
+		Subject:      "test-user",
+		Issuer:       "https://example.com",
+		Audience:     "test-audience",
+		ExpiresAt:    time.Now().Add(1 * time.Hour),
+		Confirmation: &Confirmation{JKT: "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"},
+		Claims: jwt.MapClaims{
+			"cnf": "user-provided",
+		},
+	}
+
+	// Act
+	result, err := NewJAPIKey(config)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	// Assert
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(result.JWT, claims); err != nil {
+		t.Fatalf("Failed to parse JWT: %v", err)
+	}
+
+	cnf, ok := claims["cnf"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected cnf claim to be an object, got %v", claims["cnf"])
+	}
+	if cnf["jkt"] != config.Confirmation.JKT {
+		t.Errorf("Expected jkt %s, got %v", config.Confirmation.JKT, cnf["jkt"])
+	}
+	if _, exists := cnf["x5t#S256"]; exists {
+		t.Error("Expected unset x5t#S256 to be omitted")
+	}
+}
+
+func TestNewJAPIKey_WithEmptyConfirmation_ReturnsValidationError(t *testing.T) {
+	// Arrange
+	config := Config{
+		Subject:      "test-user",
+		Issuer:       "https://example.com",
+		Audience:     "test-audience",
+		ExpiresAt:    time.Now().Add(1 * time.Hour),
+		Confirmation: &Confirmation{},
+	}
+
+	// Act
+	result, err := NewJAPIKey(config)
+
+	// Assert
+	if result != nil {
+		t.Error("Expected result to be nil for empty confirmation")
+	}
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+}
+
+func TestValidateConfig_ListsAllProblems(t *testing.T) {
+	// Arrange
+	config := Config{
+		ExpiresAt: time.Now().Add(-1 * time.Hour),
+	}
+
+	// Act
+	err := ValidateConfig(config)
+
+	// Assert
+	configErr, ok := err.(*errors.ConfigError)
+	if !ok {
+		t.Fatalf("Expected ConfigError, got %T", err)
+	}
+
+	expectedMessages := []string{
+		"subject cannot be empty",
+		"expiration time must be in the future",
+		"issuer cannot be empty",
+		"audience cannot be empty",
+	}
+	unwrapped := configErr.Unwrap()
+	if len(unwrapped) != len(expectedMessages) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expectedMessages), len(unwrapped), err)
+	}
+	for i, problem := range unwrapped {
+		if _, ok := problem.(*errors.ValidationError); !ok {
+			t.Errorf("Expected problem %d to be a ValidationError, got %T", i, problem)
+		}
+		if problem.Error() != expectedMessages[i] {
+			t.Errorf("Expected problem %d to be %q, got %q", i, expectedMessages[i], problem.Error())
+		}
+		if !strings.Contains(configErr.Error(), expectedMessages[i]) {
+			t.Errorf("Expected aggregated message to contain %q", expectedMessages[i])
+		}
+	}
+
+	var validationErr *errors.ValidationError
+	if !stderrors.As(err, &validationErr) {
+		t.Error("Expected errors.As to find a ValidationError")
+	}
+}
+
+func TestValidateConfig_ValidConfigReturnsNil(t *testing.T) {
+	config := Config{
+		Subject:   "test-user",
+		Issuer:    "https://example.com",
+		Audience:  "test-audience",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	}
+
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestNewJAPIKey_WithEmptyIssuerOrAudience_ReturnsValidationError(t *testing.T) {
+	for _, config := range []Config{
+		{Subject: "test-user", Audience: "test-audience", ExpiresAt: time.Now().Add(1 * time.Hour)},
+		{Subject: "test-user", Issuer: "https://example.com", ExpiresAt: time.Now().Add(1 * time.Hour)},
+	} {
+		result, err := NewJAPIKey(config)
+		if result != nil {
+			t.Error("Expected result to be nil")
+		}
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError, got %T", err)
+		}
+	}
+}
+
+func TestValidateConfig_FormatChecks(t *testing.T) {
+	validConfig := func() Config {
+		return Config{
+			Subject:   "test-user",
+			Issuer:    "https://example.com",
+			Audience:  "test-audience",
+			ExpiresAt: time.Now().Add(1 * time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		expected string
+	}{
+		{"relative issuer", func(c *Config) { c.Issuer = "example.com" }, "issuer must be an absolute http or https URL"},
+		{"non-http issuer", func(c *Config) { c.Issuer = "ftp://example.com" }, "issuer must be an absolute http or https URL"},
+		{"audience with whitespace", func(c *Config) { c.Audience = " test-audience" }, "audience cannot have leading or trailing whitespace"},
+		{"unserializable claims", func(c *Config) { c.Claims = jwt.MapClaims{"fn": func() {}} }, "claims must be JSON serializable"},
+		{"lifetime too long", func(c *Config) {
+			c.MaxLifetime = 30 * time.Minute
+		}, "expiration time exceeds the maximum lifetime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.modify(&config)
+
+			err := ValidateConfig(config)
+			configErr, ok := err.(*errors.ConfigError)
+			if !ok {
+				t.Fatalf("Expected ConfigError, got %T: %v", err, err)
+			}
+			problems := configErr.Unwrap()
+			if len(problems) != 1 || problems[0].Error() != tt.expected {
+				t.Errorf("Expected only %q, got %v", tt.expected, problems)
+			}
+
+			if _, err := NewJAPIKey(config); err == nil {
+				t.Error("Expected NewJAPIKey to reject the config")
+			} else if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError from NewJAPIKey, got %T", err)
+			}
+		})
+	}
+
+	t.Run("lifetime within limit", func(t *testing.T) {
+		config := validConfig()
+		config.MaxLifetime = 2 * time.Hour
+		if err := ValidateConfig(config); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+}
+
+func TestNewJAPIKey_WithReservedClaims(t *testing.T) {
+	newConfig := func(claims jwt.MapClaims) Config {
+		return Config{
+			Subject:        "test-user",
+			Issuer:         "https://example.com",
+			Audience:       "test-audience",
+			ExpiresAt:      time.Now().Add(1 * time.Hour),
+			Claims:         claims,
+			ReservedClaims: []string{"tenant", "internal_*"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		expected []string
+	}{
+		{"exact match", jwt.MapClaims{"tenant": "acme"}, []string{"claim 'tenant' is reserved and cannot be set"}},
+		{"prefix match", jwt.MapClaims{"internal_role": "admin"}, []string{"claim 'internal_role' is reserved and cannot be set"}},
+		{"multiple matches sorted", jwt.MapClaims{"tenant": "acme", "internal_a": 1}, []string{
+			"claim 'internal_a' is reserved and cannot be set",
+			"claim 'tenant' is reserved and cannot be set",
+		}},
+		{"unreserved claims allowed", jwt.MapClaims{"tenant_name": "acme", "internal": true, "role": "user"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newConfig(tt.claims)
+
+			result, err := NewJAPIKey(config)
+			if tt.expected == nil {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if result == nil {
+					t.Fatal("Expected result")
+				}
+				return
+			}
+
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Message != tt.expected[0] {
+				t.Errorf("Expected %q, got %q", tt.expected[0], validationErr.Message)
+			}
+
+			configErr, ok := ValidateConfig(config).(*errors.ConfigError)
+			if !ok {
+				t.Fatal("Expected ConfigError from ValidateConfig")
+			}
+			problems := configErr.Unwrap()
+			if len(problems) != len(tt.expected) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.expected), problems)
+			}
+			for i, problem := range problems {
+				if problem.Error() != tt.expected[i] {
+					t.Errorf("Expected %q, got %q", tt.expected[i], problem.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestNewJAPIKey_HeaderDeclaresTypeAndStringKeyID(t *testing.T) {
+	signer := newTestSigner(t)
+
+	locallySigned, err := NewJAPIKey(Config{
+		Subject:   "test-user",
+		Issuer:    "https://example.com",
+		Audience:  "test-audience",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	externallySigned, err := NewJAPIKey(newTestSignerConfig(signer))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	for _, result := range []*JAPIKey{locallySigned, externallySigned} {
+		header, _, err := ParseClaimsUnverified(result.JWT)
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		if header[TypeHeader] != TokenType {
+			t.Errorf("Expected typ header %s, got %v", TokenType, header[TypeHeader])
+		}
+		if kid, ok := header[KeyIDHeader].(string); !ok || kid != result.KeyID.String() {
+			t.Errorf("Expected kid header %q as a string, got %#v", result.KeyID.String(), header[KeyIDHeader])
+		}
+	}
+
+	// Minted tokens satisfy a verifier that requires the typ header
+	config := VerifyConfig{BaseIssuerURL: "https://example.com", RequireTypeHeader: true}
+	if _, err := Verify(externallySigned.JWT, config, mockKeyFunc(signer.publicKey)); err != nil {
+		t.Errorf("Expected minted token to verify with RequireTypeHeader, got: %v", err)
+	}
+}
+
+func TestNewJAPIKey_KeyIDGenerator(t *testing.T) {
+	config := Config{
+		Subject:        "test-user",
+		Issuer:         "https://example.com",
+		Audience:       "test-audience",
+		ExpiresAt:      time.Now().Add(1 * time.Hour),
+		KeyIDGenerator: func() uuid.UUID { return uuid.Must(uuid.NewV7()) },
+	}
+
+	result, err := NewJAPIKey(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.KeyID.Version() != 7 {
+		t.Errorf("Expected a version 7 key ID, got version %d", result.KeyID.Version())
+	}
+	header, _, err := ParseClaimsUnverified(result.JWT)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if header[KeyIDHeader] != result.KeyID.String() {
+		t.Errorf("Expected kid header %s, got %v", result.KeyID, header[KeyIDHeader])
+	}
+
+	config.KeyIDGenerator = func() uuid.UUID { return uuid.Nil }
+	if _, err := NewJAPIKey(config); err == nil {
+		t.Error("Expected error for a generator returning uuid.Nil")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+
+	signerConfig := newTestSignerConfig(newTestSigner(t))
+	signerConfig.KeyIDGenerator = uuid.New
+	if _, err := NewJAPIKey(signerConfig); err == nil {
+		t.Error("Expected error for a generator combined with an external signer")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+}
+
+func TestNewJAPIKey_PrivateKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keyID, err := jwks.ThumbprintKeyID(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to derive key ID: %v", err)
+	}
+
+	config := Config{
+		Subject:    "test-user",
+		Issuer:     "https://example.com/" + keyID.String(),
+		Audience:   "test-audience",
+		ExpiresAt:  time.Now().Add(1 * time.Hour),
+		PrivateKey: privateKey,
+	}
+
+	first, err := NewJAPIKey(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	config.Subject = "other-user"
+	second, err := NewJAPIKey(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for _, apiKey := range []*JAPIKey{first, second} {
+		if apiKey.KeyID != keyID {
+			t.Errorf("Expected key ID %s derived from the key, got %s", keyID, apiKey.KeyID)
+		}
+		if !apiKey.PublicKey.Equal(&privateKey.PublicKey) {
+			t.Error("Expected the public key of the provided private key")
+		}
+		verifyConfig := VerifyConfig{BaseIssuerURL: "https://example.com", KeyIDIsThumbprint: true}
+		if _, err := Verify(apiKey.JWT, verifyConfig, mockKeyFunc(&privateKey.PublicKey)); err != nil {
+			t.Errorf("Expected token to verify with the provided key, got: %v", err)
+		}
+	}
+
+	if size, err := EstimateTokenSize(config); err != nil || size != len(second.JWT) {
+		t.Errorf("Expected estimated size %d, got %d (err: %v)", len(second.JWT), size, err)
+	}
+}
+
+func TestNewJAPIKey_PrivateKeyValidation(t *testing.T) {
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	base := Config{
+		Subject:   "test-user",
+		Issuer:    "https://example.com",
+		Audience:  "test-audience",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+	}{
+		{"key under 2048 bits", func(c *Config) { c.PrivateKey = smallKey }},
+		{"combined with signer", func(c *Config) {
+			c.PrivateKey = privateKey
+			c.Signer = newTestSigner(t)
+		}},
+		{"combined with key ID generator", func(c *Config) {
+			c.PrivateKey = privateKey
+			c.KeyIDGenerator = uuid.New
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base
+			tt.mutate(&config)
+
+			result, err := NewJAPIKey(config)
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if result != nil {
+				t.Error("Expected no result")
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestNewJAPIKey_NotBefore(t *testing.T) {
+	keyID := uuid.New()
+	newConfig := func(notBefore time.Time) Config {
+		return Config{
+			Subject:        "test-user",
+			Issuer:         "https://example.com/" + keyID.String(),
+			Audience:       "test-audience",
+			ExpiresAt:      time.Now().Add(2 * time.Hour),
+			NotBefore:      notBefore,
+			KeyIDGenerator: func() uuid.UUID { return keyID },
+		}
+	}
+	verifyConfig := VerifyConfig{BaseIssuerURL: "https://example.com"}
+
+	t.Run("future nbf rejected", func(t *testing.T) {
+		notBefore := time.Now().Add(time.Hour)
+		result, err := NewJAPIKey(newConfig(notBefore))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		_, claims, err := ParseClaimsUnverified(result.JWT)
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		if nbf, ok := claims["nbf"].(float64); !ok || int64(nbf) != notBefore.Unix() {
+			t.Errorf("Expected nbf %d, got %v", notBefore.Unix(), claims["nbf"])
+		}
+
+		_, err = Verify(result.JWT, verifyConfig, mockKeyFunc(result.PublicKey))
+		validationErr, ok := err.(*errors.ValidationError)
+		if !ok {
+			t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+		}
+		if validationErr.Code != "NotBeforeError" {
+			t.Errorf("Expected code NotBeforeError, got %s", validationErr.Code)
+		}
+	})
+
+	t.Run("past nbf accepted", func(t *testing.T) {
+		result, err := NewJAPIKey(newConfig(time.Now().Add(-time.Minute)))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := Verify(result.JWT, verifyConfig, mockKeyFunc(result.PublicKey)); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("zero omits nbf", func(t *testing.T) {
+		result, err := NewJAPIKey(newConfig(time.Time{}))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		_, claims, err := ParseClaimsUnverified(result.JWT)
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		if _, ok := claims["nbf"]; ok {
+			t.Errorf("Expected no nbf claim, got %v", claims["nbf"])
+		}
+	})
+
+	t.Run("after expiration rejected", func(t *testing.T) {
+		config := newConfig(time.Now().Add(3 * time.Hour))
+		if _, err := NewJAPIKey(config); err == nil {
+			t.Error("Expected error for nbf after exp")
+		} else if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError, got %T", err)
+		}
+	})
+}
+
+func TestNewServiceJAPIKey(t *testing.T) {
+	result, err := NewServiceJAPIKey("billing-service", "https://example.com", "ledger", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL:  "https://example.com",
+		Timeout:        5 * time.Second,
+		RequireSubject: true,
+	}
+	verified, err := Verify(result.JWT, config, mockKeyFunc(result.PublicKey))
+	if err != nil {
+		t.Fatalf("Expected service token to verify, got: %v", err)
+	}
+	if verified.Claims["iss"] != "https://example.com/"+result.KeyID.String() {
+		t.Errorf("Expected issuer built from the key ID, got %v", verified.Claims["iss"])
+	}
+	if _, ok := verified.Claims["iat"]; !ok {
+		t.Error("Expected iat to be set")
+	}
+	if len(verified.Claims) != 6 {
+		t.Errorf("Expected only sub, iss, aud, exp, iat and ver, got %v", verified.Claims)
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Minute} {
+		if _, err := NewServiceJAPIKey("billing-service", "https://example.com", "ledger", ttl); err == nil {
+			t.Errorf("Expected error for ttl %v", ttl)
+		} else if _, ok := err.(*errors.ValidationError); !ok {
+			t.Errorf("Expected ValidationError, got %T", err)
+		}
+	}
+
+	if _, err := NewServiceJAPIKey("", "https://example.com", "ledger", time.Minute); err == nil {
+		t.Error("Expected error for an empty subject")
+	}
+}
+
+func TestNewJAPIKey_WithAudiencePolicy(t *testing.T) {
+	// Audience must be the issuer's host or one of its subdomains
+	subdomainPolicy := func(issuer, audience string) error {
+		host := strings.TrimPrefix(issuer, "https://")
+		if audience != host && !strings.HasSuffix(audience, "."+host) {
+			return errors.NewValidationError("audience must be a subdomain of the issuer")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		audience    string
+		policy      func(issuer, audience string) error
+		expectError string
+	}{
+		{name: "no policy allows any audience", audience: "other.test"},
+		{name: "subdomain allowed", audience: "api.example.com", policy: subdomainPolicy},
+		{name: "other host rejected", audience: "api.example.org", policy: subdomainPolicy, expectError: "audience must be a subdomain of the issuer"},
+		{
+			name:     "plain error wrapped",
+			audience: "api.example.com",
+			policy: func(issuer, audience string) error {
+				return stderrors.New("tenant lookup failed")
+			},
+			expectError: "audience rejected by audience policy: tenant lookup failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				Subject:        "test-user",
+				Issuer:         "https://example.com",
+				Audience:       tt.audience,
+				ExpiresAt:      time.Now().Add(1 * time.Hour),
+				AudiencePolicy: tt.policy,
+			}
+
+			_, err := NewJAPIKey(config)
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, but got: %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Message != tt.expectError {
+				t.Errorf("Expected %q, got %q", tt.expectError, validationErr.Message)
+			}
+		})
+	}
+
+	called := false
+	config := Config{Subject: "test-user", ExpiresAt: time.Now().Add(time.Hour), AudiencePolicy: func(issuer, audience string) error {
+		called = true
+		return nil
+	}}
+	if err := ValidateConfig(config); err == nil {
+		t.Error("Expected error for a config missing issuer and audience")
+	}
+	if called {
+		t.Error("Expected the policy not to be called without an issuer and audience")
+	}
+}
+
+func TestEstimateTokenSize(t *testing.T) {
+	largeClaims := jwt.MapClaims{}
+	for i := range 50 {
+		largeClaims[fmt.Sprintf("claim_%d", i)] = strings.Repeat("x", 40)
+	}
+
+	configs := map[string]Config{
+		"minimal": {
+			Subject:   "test-user",
+			Issuer:    "https://example.com",
+			Audience:  "test-audience",
+			ExpiresAt: time.Now().Add(1 * time.Hour),
+		},
+		"thumbprint and claims": {
+			Subject:              "test-user",
+			Issuer:               "https://example.com",
+			Audience:             "test-audience",
+			ExpiresAt:            time.Now().Add(1 * time.Hour),
+			Claims:               largeClaims,
+			IncludeKeyThumbprint: true,
+		},
+		"external signer": newTestSignerConfig(newTestSignerWithExponent(t, 3)),
+	}
+
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			estimate, err := EstimateTokenSize(config)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			result, err := NewJAPIKey(config)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+			if diff := estimate - len(result.JWT); diff < -4 || diff > 4 {
+				t.Errorf("Expected estimate %d within 4 bytes of actual size %d", estimate, len(result.JWT))
+			}
+		})
+	}
+
+	if size, _ := EstimateTokenSize(configs["thumbprint and claims"]); size <= MaxTokenSize {
+		t.Errorf("Expected large claims to exceed MaxTokenSize, estimated %d", size)
+	}
+
+	if _, err := EstimateTokenSize(Config{Claims: jwt.MapClaims{"bad": make(chan int)}}); err == nil {
+		t.Error("Expected error for unserializable claims")
+	}
+}
@@ -0,0 +1,59 @@
+package japikey
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+// Signer performs the RSA signing operation for a key held outside the process, such as in an
+// HSM or cloud KMS, so that the private key never touches process memory.
+type Signer interface {
+	// Sign returns the RS256 (RSASSA-PKCS1-v1_5 with SHA-256) signature of signingInput.
+	// The signer is responsible for hashing the input.
+	Sign(signingInput []byte) ([]byte, error)
+
+	// Public returns the public key matching the signing key.
+	Public() *rsa.PublicKey
+
+	// KeyID returns the key ID to publish the public key under.
+	KeyID() uuid.UUID
+}
+
+// newExternallySignedJAPIKey builds the token for an already validated config and delegates
+// signing to config.Signer. The signature is checked against the signer's public key, so a
+// misconfigured signer cannot produce tokens that would fail verification.
+func newExternallySignedJAPIKey(config Config) (*JAPIKey, error) {
+	keyID := config.Signer.KeyID()
+	publicKey := config.Signer.Public()
+
+	token, err := newToken(config, keyID, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signingInput, err := token.SigningString()
+	if err != nil {
+		return nil, errors.NewInternalError("failed to build JWT signing input")
+	}
+
+	signature, err := config.Signer.Sign([]byte(signingInput))
+	if err != nil {
+		return nil, errors.NewInternalError("external signer failed to sign JWT")
+	}
+
+	if err := jwt.SigningMethodRS256.Verify(signingInput, signature, publicKey); err != nil {
+		return nil, errors.NewInternalError("external signer produced a signature that does not match its public key")
+	}
+
+	result := &JAPIKey{
+		JWT:       signingInput + "." + base64.RawURLEncoding.EncodeToString(signature),
+		PublicKey: publicKey,
+		KeyID:     keyID,
+	}
+
+	return result, nil
+}
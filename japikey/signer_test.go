@@ -0,0 +1,376 @@
+package japikey
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	stderrors "errors"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+// testSigner is an in-memory stand-in for an HSM or KMS backed signer.
+type testSigner struct {
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	keyID      uuid.UUID
+	err        error
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return &testSigner{privateKey: privateKey, publicKey: &privateKey.PublicKey, keyID: uuid.New()}
+}
+
+func (s *testSigner) Sign(signingInput []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	digest := sha256.Sum256(signingInput)
+	return rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+}
+
+func (s *testSigner) Public() *rsa.PublicKey {
+	return s.publicKey
+}
+
+func (s *testSigner) KeyID() uuid.UUID {
+	return s.keyID
+}
+
+func newTestSignerConfig(signer Signer) Config {
+	return Config{
+		Subject:   "test-user",
+		Issuer:    "https://example.com/" + signer.KeyID().String(),
+		Audience:  "test-audience",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+		Signer:    signer,
+	}
+}
+
+func TestNewJAPIKey_WithSigner_TokenVerifies(t *testing.T) {
+	signer := newTestSigner(t)
+
+	result, err := NewJAPIKey(newTestSignerConfig(signer))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.KeyID != signer.keyID {
+		t.Errorf("Expected key ID %s, got %s", signer.keyID, result.KeyID)
+	}
+	if result.PublicKey != signer.publicKey {
+		t.Error("Expected public key to be the signer's public key")
+	}
+
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		if keyID != signer.keyID {
+			return nil, errors.NewKeyNotFoundError("unknown key ID")
+		}
+		return signer.publicKey, nil
+	}
+	verified, err := Verify(result.JWT, VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc)
+	if err != nil {
+		t.Fatalf("Expected token to verify, got: %v", err)
+	}
+	if verified.Claims["sub"] != "test-user" {
+		t.Errorf("Expected sub test-user, got %v", verified.Claims["sub"])
+	}
+}
+
+// newTestSignerWithExponent builds a signer whose key uses the given public exponent, which
+// rsa.GenerateKey cannot do since it always uses 65537.
+func newTestSignerWithExponent(t *testing.T, exponent int) *testSigner {
+	t.Helper()
+	e := big.NewInt(int64(exponent))
+	one := big.NewInt(1)
+
+	var p, q *big.Int
+	for p == nil || q == nil || p.Cmp(q) == 0 {
+		candidate, err := rand.Prime(rand.Reader, 1024)
+		if err != nil {
+			t.Fatalf("Failed to generate prime: %v", err)
+		}
+		// e must be invertible modulo p-1 for the key to exist
+		if new(big.Int).GCD(nil, nil, e, new(big.Int).Sub(candidate, one)).Cmp(one) != 0 {
+			continue
+		}
+		if p == nil {
+			p = candidate
+		} else {
+			q = candidate
+		}
+	}
+
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	privateKey := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: exponent},
+		D:         new(big.Int).ModInverse(e, phi),
+		Primes:    []*big.Int{p, q},
+	}
+	privateKey.Precompute()
+	if err := privateKey.Validate(); err != nil {
+		t.Fatalf("Failed to build RSA key with exponent %d: %v", exponent, err)
+	}
+
+	return &testSigner{privateKey: privateKey, publicKey: &privateKey.PublicKey, keyID: uuid.New()}
+}
+
+func TestNewJAPIKey_WithSigner_NonDefaultExponent(t *testing.T) {
+	for _, exponent := range []int{3, 65539} {
+		t.Run(strconv.Itoa(exponent), func(t *testing.T) {
+			signer := newTestSignerWithExponent(t, exponent)
+
+			result, err := NewJAPIKey(newTestSignerConfig(signer))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			// Publish and re-read the key the way a verifier would
+			published, err := jwks.NewJWKS(result.PublicKey, result.KeyID)
+			if err != nil {
+				t.Fatalf("Failed to create JWKS: %v", err)
+			}
+			data, err := published.MarshalJSON()
+			if err != nil {
+				t.Fatalf("Failed to marshal JWKS: %v", err)
+			}
+			keySet := &jwks.JWKS{}
+			if err := keySet.UnmarshalJSON(data); err != nil {
+				t.Fatalf("Failed to unmarshal JWKS: %v", err)
+			}
+
+			verified, err := Verify(result.JWT, VerifyConfig{BaseIssuerURL: "https://example.com"}, keySet.KeyFunc())
+			if err != nil {
+				t.Fatalf("Expected token to verify, got: %v", err)
+			}
+			if verified.KeyID != signer.keyID {
+				t.Errorf("Expected key ID %s, got %s", signer.keyID, verified.KeyID)
+			}
+		})
+	}
+}
+
+func TestNewJAPIKey_WithSigner_SignerError(t *testing.T) {
+	signer := newTestSigner(t)
+	signer.err = stderrors.New("kms unavailable")
+
+	_, err := NewJAPIKey(newTestSignerConfig(signer))
+	if err == nil {
+		t.Fatal("Expected error when signer fails")
+	}
+	if _, ok := err.(*errors.InternalError); !ok {
+		t.Errorf("Expected InternalError, got %T", err)
+	}
+}
+
+func TestNewJAPIKey_WithSigner_MismatchedPublicKey(t *testing.T) {
+	signer := newTestSigner(t)
+	signer.publicKey = &newTestSigner(t).privateKey.PublicKey
+
+	_, err := NewJAPIKey(newTestSignerConfig(signer))
+	if err == nil {
+		t.Fatal("Expected error when signature does not match the signer's public key")
+	}
+	if _, ok := err.(*errors.InternalError); !ok {
+		t.Errorf("Expected InternalError, got %T", err)
+	}
+}
+
+func TestNewJAPIKey_WithSigner_InvalidSigner(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *testSigner)
+	}{
+		{name: "nil public key", modify: func(s *testSigner) { s.publicKey = nil }},
+		{name: "nil key ID", modify: func(s *testSigner) { s.keyID = uuid.Nil }},
+		{name: "even exponent", modify: func(s *testSigner) { s.publicKey = &rsa.PublicKey{N: s.publicKey.N, E: 65536} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := newTestSigner(t)
+			config := newTestSignerConfig(signer)
+			tt.modify(signer)
+
+			_, err := NewJAPIKey(config)
+			if err == nil {
+				t.Fatal("Expected validation error")
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestKeystore_Issue_RejectsSigner(t *testing.T) {
+	keystore := NewKeystore()
+
+	_, err := keystore.Issue(newTestSignerConfig(newTestSigner(t)))
+	if err == nil {
+		t.Fatal("Expected error for config with an external signer")
+	}
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+}
+
+func TestKeyThumbprintBinding(t *testing.T) {
+	signer := newTestSigner(t)
+	config := newTestSignerConfig(signer)
+	config.IncludeKeyThumbprint = true
+
+	bound, err := NewJAPIKey(config)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	unbound, err := NewJAPIKey(newTestSignerConfig(signer))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	header, _, err := ParseClaimsUnverified(bound.JWT)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	expected, err := jwks.Thumbprint(signer.publicKey)
+	if err != nil {
+		t.Fatalf("Failed to compute thumbprint: %v", err)
+	}
+	if header[KeyThumbprintHeader] != expected {
+		t.Errorf("Expected jkt header %s, got %v", expected, header[KeyThumbprintHeader])
+	}
+
+	rogueKey := &newTestSigner(t).privateKey.PublicKey
+	keyFuncFor := func(publicKey *rsa.PublicKey) JWKCallback {
+		return func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+			return publicKey, nil
+		}
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		publicKey     *rsa.PublicKey
+		require       bool
+		expectedError string
+	}{
+		{"bound token with matching key", bound.JWT, signer.publicKey, false, ""},
+		{"bound token with matching key and required", bound.JWT, signer.publicKey, true, ""},
+		{"bound token with rogue key", bound.JWT, rogueKey, false, "token key thumbprint does not match the retrieved key"},
+		{"unbound token when not required", unbound.JWT, signer.publicKey, false, ""},
+		{"unbound token when required", unbound.JWT, signer.publicKey, true, "token missing key thumbprint header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifyConfig := VerifyConfig{BaseIssuerURL: "https://example.com", RequireKeyThumbprint: tt.require}
+			_, err := Verify(tt.token, verifyConfig, keyFuncFor(tt.publicKey))
+
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Message != tt.expectedError {
+				t.Errorf("Expected %q, got %q", tt.expectedError, validationErr.Message)
+			}
+		})
+	}
+}
+
+func TestNewJAPIKey_IncludeKeyThumbprint(t *testing.T) {
+	result, err := NewJAPIKey(Config{
+		Subject:              "test-user",
+		Issuer:               "https://example.com",
+		Audience:             "test-audience",
+		ExpiresAt:            time.Now().Add(1 * time.Hour),
+		IncludeKeyThumbprint: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	header, _, err := ParseClaimsUnverified(result.JWT)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	expected, err := jwks.Thumbprint(result.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to compute thumbprint: %v", err)
+	}
+	if header[KeyThumbprintHeader] != expected {
+		t.Errorf("Expected jkt header %s, got %v", expected, header[KeyThumbprintHeader])
+	}
+}
+
+func TestKeyIDIsThumbprint(t *testing.T) {
+	signer := newTestSigner(t)
+	thumbprintKeyID, err := jwks.ThumbprintKeyID(signer.publicKey)
+	if err != nil {
+		t.Fatalf("Failed to derive key ID: %v", err)
+	}
+	signer.keyID = thumbprintKeyID
+
+	pinned, err := NewJAPIKey(newTestSignerConfig(signer))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	random, err := NewJAPIKey(newTestSignerConfig(newTestSigner(t)))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	// A rogue key registered under the pinned kid, signing its own token
+	rogue := newTestSigner(t)
+	rogue.keyID = thumbprintKeyID
+	spoofed, err := NewJAPIKey(newTestSignerConfig(rogue))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		token       *JAPIKey
+		enabled     bool
+		expectValid bool
+	}{
+		{name: "thumbprint key ID accepted", token: pinned, enabled: true, expectValid: true},
+		{name: "random key ID rejected", token: random, enabled: true, expectValid: false},
+		{name: "spoofed key ID rejected", token: spoofed, enabled: true, expectValid: false},
+		{name: "random key ID accepted by default", token: random, expectValid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifyConfig := VerifyConfig{BaseIssuerURL: "https://example.com", KeyIDIsThumbprint: tt.enabled}
+			_, err := Verify(tt.token.JWT, verifyConfig, mockKeyFunc(tt.token.PublicKey))
+
+			if tt.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
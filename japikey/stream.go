@@ -0,0 +1,150 @@
+package japikey
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	japikeyerrors "github.com/susu-dot-dev/japikey/errors"
+)
+
+// BatchResult is the outcome of verifying one token from a stream.
+type BatchResult struct {
+	// Index is the position of the token in the input stream, starting at 0
+	Index int
+
+	// Result is the verification result, or nil if verification failed
+	Result *VerificationResult
+
+	// Err is the verification error, or nil if the token verified
+	Err error
+}
+
+// VerifyReader reads a single token from r, such as a request body or pipe, and verifies it like
+// Verify. At most MaxTokenSize+1 bytes are read, so oversized input is rejected with a
+// ValidationError coded TokenSizeError without buffering it. Surrounding whitespace, such as a
+// trailing newline, is ignored. A failed read, such as a client disconnect, returns an
+// InternalError wrapping the read error. If ctx is done before or after the read, its error is
+// returned.
+func VerifyReader(ctx context.Context, r io.Reader, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxTokenSize+1))
+	if err != nil {
+		return nil, japikeyerrors.NewInternalErrorWithCause("failed to read token", err)
+	}
+	if len(data) > MaxTokenSize {
+		return nil, japikeyerrors.NewTokenSizeError(fmt.Sprintf("token size exceeds maximum allowed size of %d bytes", MaxTokenSize))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return Verify(strings.TrimSpace(string(data)), config, keyFunc)
+}
+
+// VerifyStream verifies tokens read from in using a pool of workers, emitting one BatchResult per
+// token on the returned channel. Results arrive in completion order, not input order; use
+// BatchResult.Index to correlate them. The output channel is closed once in is closed and every
+// token has been verified, or when ctx is cancelled, in which case pending tokens are dropped.
+// workers <= 0 = 1 worker.
+//
+// Concurrent lookups of the same key ID share a single keyFunc call, but nothing is cached: each
+// key ID is looked up once per burst of concurrent lookups, and again once that burst completes.
+// To reuse keys across the stream, pass the GetPublicKey method of a CachingKeyFunc as keyFunc.
+func VerifyStream(ctx context.Context, in <-chan string, config VerifyConfig, keyFunc JWKCallback, workers int) <-chan BatchResult {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	type job struct {
+		index int
+		token string
+	}
+
+	jobs := make(chan job)
+	out := make(chan BatchResult)
+	keyFunc = dedupKeyFunc(keyFunc)
+
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case <-ctx.Done():
+				return
+			case token, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- job{index: index, token: token}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for j := range jobs {
+				result, err := Verify(j.token, config, keyFunc)
+				select {
+				case out <- BatchResult{Index: j.index, Result: result, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// keyLookup is an in-flight or completed call to a JWKCallback.
+type keyLookup struct {
+	done      chan struct{}
+	publicKey *rsa.PublicKey
+	err       error
+}
+
+// dedupKeyFunc wraps keyFunc so that concurrent lookups of the same key ID share one call.
+// Results are not retained once the call completes, so revocations are still observed.
+func dedupKeyFunc(keyFunc JWKCallback) JWKCallback {
+	var mu sync.Mutex
+	inFlight := make(map[uuid.UUID]*keyLookup)
+
+	return func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		mu.Lock()
+		if lookup, ok := inFlight[keyID]; ok {
+			mu.Unlock()
+			<-lookup.done
+			return lookup.publicKey, lookup.err
+		}
+		lookup := &keyLookup{done: make(chan struct{})}
+		inFlight[keyID] = lookup
+		mu.Unlock()
+
+		lookup.publicKey, lookup.err = keyFunc(keyID)
+
+		mu.Lock()
+		delete(inFlight, keyID)
+		mu.Unlock()
+		close(lookup.done)
+
+		return lookup.publicKey, lookup.err
+	}
+}
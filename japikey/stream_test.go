@@ -0,0 +1,226 @@
+package japikey
+
+import (
+	"context"
+	"crypto/rsa"
+	stderrors "errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+func TestVerifyStream_VerifiesAllTokens(t *testing.T) {
+	validToken, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return pubKey, nil
+	}
+
+	tokens := []string{validToken, "not-a-token", validToken, validToken, "also.not.valid"}
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, token := range tokens {
+			in <- token
+		}
+	}()
+
+	out := VerifyStream(context.Background(), in, VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc, 3)
+
+	seen := make(map[int]BatchResult)
+	for result := range out {
+		if _, dup := seen[result.Index]; dup {
+			t.Errorf("Duplicate result for index %d", result.Index)
+		}
+		seen[result.Index] = result
+	}
+
+	if len(seen) != len(tokens) {
+		t.Fatalf("Expected %d results, got %d", len(tokens), len(seen))
+	}
+	for index, token := range tokens {
+		result := seen[index]
+		if token == validToken {
+			if result.Err != nil || result.Result == nil {
+				t.Errorf("Expected token %d to verify, got %v", index, result.Err)
+			}
+		} else if result.Err == nil || result.Result != nil {
+			t.Errorf("Expected token %d to fail verification", index)
+		}
+	}
+}
+
+func TestVerifyStream_WithCachingKeyFunc(t *testing.T) {
+	validToken, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	var calls atomic.Int64
+	cache := NewCachingKeyFunc(func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		calls.Add(1)
+		return pubKey, nil
+	}, CachingKeyFuncConfig{})
+
+	// Tokens are fed one at a time, so every lookup after the first is a separate burst
+	config := VerifyConfig{BaseIssuerURL: "https://example.com"}
+	for range 3 {
+		in := make(chan string, 1)
+		in <- validToken
+		close(in)
+		for result := range VerifyStream(context.Background(), in, config, cache.GetPublicKey, 1) {
+			if result.Err != nil {
+				t.Fatalf("Expected token to verify, got: %v", result.Err)
+			}
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the caching key func to be looked up once, got %d", calls.Load())
+	}
+}
+
+func TestVerifyStream_ClosesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan string) // never closed
+
+	out := VerifyStream(ctx, in, VerifyConfig{BaseIssuerURL: "https://example.com"}, func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return nil, errors.NewKeyNotFoundError("unused")
+	}, 2)
+
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("Expected no results after cancellation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected output channel to close after cancellation")
+	}
+}
+
+func TestDedupKeyFunc_SharesConcurrentLookups(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	keyFunc := dedupKeyFunc(func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		calls.Add(1)
+		<-release
+		return &rsa.PublicKey{}, nil
+	})
+
+	keyID := uuid.New()
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := keyFunc(keyID); err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		}()
+	}
+
+	// Give the goroutines time to join the in-flight lookup before releasing it
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 underlying lookup, got %d", got)
+	}
+
+	// Completed lookups are not retained
+	if _, err := keyFunc(keyID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected a fresh lookup after completion, got %d calls", got)
+	}
+}
+
+// endlessReader yields an unbounded stream of bytes, counting how many were read
+type endlessReader struct {
+	read int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	r.read += len(p)
+	return len(p), nil
+}
+
+type failingReader struct{}
+
+var errConnectionReset = stderrors.New("connection reset")
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errConnectionReset
+}
+
+func TestVerifyReader(t *testing.T) {
+	validToken, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	config := VerifyConfig{BaseIssuerURL: "https://example.com/"}
+
+	t.Run("valid token with trailing newline", func(t *testing.T) {
+		result, err := VerifyReader(context.Background(), strings.NewReader(validToken+"\n"), config, mockKeyFunc(pubKey))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result.Claims["sub"] != "test-user" {
+			t.Errorf("Expected sub test-user, got %v", result.Claims["sub"])
+		}
+	})
+
+	t.Run("oversized input rejected during the read", func(t *testing.T) {
+		reader := &endlessReader{}
+		_, err := VerifyReader(context.Background(), reader, config, mockKeyFunc(pubKey))
+		validationErr, ok := err.(*errors.ValidationError)
+		if !ok {
+			t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+		}
+		if validationErr.Code != "TokenSizeError" {
+			t.Errorf("Expected code TokenSizeError, got %s", validationErr.Code)
+		}
+		if reader.read > 2*(MaxTokenSize+1) {
+			t.Errorf("Expected the read to stop near MaxTokenSize, read %d bytes", reader.read)
+		}
+	})
+
+	t.Run("input at the size limit is not a size error", func(t *testing.T) {
+		_, err := VerifyReader(context.Background(), strings.NewReader(strings.Repeat("a", MaxTokenSize)), config, mockKeyFunc(pubKey))
+		if validationErr, ok := err.(*errors.ValidationError); ok && validationErr.Code == "TokenSizeError" {
+			t.Errorf("Expected input of exactly MaxTokenSize to pass the size check, got: %v", err)
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		_, err := VerifyReader(context.Background(), failingReader{}, config, mockKeyFunc(pubKey))
+		if _, ok := err.(*errors.InternalError); !ok {
+			t.Errorf("Expected InternalError, got %T: %v", err, err)
+		}
+		if !stderrors.Is(err, errConnectionReset) {
+			t.Errorf("Expected the read error to be reachable through the returned error, got: %v", err)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := VerifyReader(ctx, strings.NewReader(validToken), config, mockKeyFunc(pubKey))
+		if !stderrors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got: %v", err)
+		}
+	})
+}
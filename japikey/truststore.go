@@ -0,0 +1,156 @@
+package japikey
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
+)
+
+// TrustStore verifies JAPIKeys offline against pre-loaded keys of known issuers, for air-gapped
+// or edge verifiers that cannot fetch a JWKS at runtime. Each key is trusted for exactly one
+// issuer base URL. It is safe for concurrent use.
+type TrustStore struct {
+	mu      sync.RWMutex
+	keys    map[uuid.UUID]trustedKey
+	issuers []string
+}
+
+// trustedKey is a pre-loaded public key together with the issuer base URL it is trusted for.
+type trustedKey struct {
+	issuer    string
+	publicKey *rsa.PublicKey
+}
+
+// trustStoreFile is the format of a file read by LoadTrustStore: an issuer base URL and the JWKS
+// published for one of its keys, e.g.
+// {"issuer": "https://example.com", "jwks": {"keys": [{"kty": "RSA", "kid": "...", "n": "...", "e": "AQAB"}]}}
+type trustStoreFile struct {
+	Issuer string          `json:"issuer"`
+	JWKS   json.RawMessage `json:"jwks"`
+}
+
+// NewTrustStore creates an empty TrustStore.
+func NewTrustStore() *TrustStore {
+	return &TrustStore{keys: make(map[uuid.UUID]trustedKey)}
+}
+
+// LoadTrustStore creates a TrustStore from every .json file directly in dir of fsys, such as an
+// embed.FS bundled into the binary. Each file holds an issuer base URL and a JWKS (see
+// trustStoreFile); other files and subdirectories are ignored. Any invalid file fails the load.
+func LoadTrustStore(fsys fs.FS, dir string) (*TrustStore, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.NewValidationError("failed to read trust store directory: " + err.Error())
+	}
+
+	store := NewTrustStore()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name := path.Join(dir, entry.Name())
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, errors.NewValidationError("failed to read trust store file " + name + ": " + err.Error())
+		}
+
+		var file trustStoreFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, errors.NewValidationError("trust store file " + name + " is not valid JSON")
+		}
+		keySet := &jwks.JWKS{}
+		if err := keySet.UnmarshalJSON(file.JWKS); err != nil {
+			return nil, errors.NewValidationError("trust store file " + name + " has an invalid JWKS: " + err.Error())
+		}
+		if err := store.Add(file.Issuer, keySet); err != nil {
+			return nil, errors.NewValidationError("trust store file " + name + ": " + err.Error())
+		}
+	}
+
+	return store, nil
+}
+
+// Add trusts the key in keySet for tokens from issuerBase. A key ID can only be trusted for one
+// issuer, since the issuer is derived from it during verification.
+func (s *TrustStore) Add(issuerBase string, keySet *jwks.JWKS) error {
+	if u, err := url.Parse(issuerBase); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.NewValidationError("issuer must be an absolute http or https URL")
+	}
+	if keySet == nil {
+		return errors.NewValidationError("JWKS cannot be nil")
+	}
+	keyID := keySet.GetKeyID()
+	publicKey, err := keySet.GetPublicKey(keyID)
+	if err != nil || publicKey == nil {
+		return errors.NewValidationError("JWKS must contain a public key")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.keys[keyID]; ok {
+		if existing.issuer != issuerBase || !jwks.PublicKeyEqual(existing.publicKey, publicKey) {
+			return errors.NewValidationError("key ID " + keyID.String() + " is already trusted with a different issuer or key")
+		}
+		return nil
+	}
+	s.keys[keyID] = trustedKey{issuer: issuerBase, publicKey: publicKey}
+	if !slices.Contains(s.issuers, issuerBase) {
+		s.issuers = append(s.issuers, issuerBase)
+	}
+	return nil
+}
+
+// BaseIssuerURLs returns every trusted issuer base URL, suitable for VerifyConfig.BaseIssuerURLs.
+func (s *TrustStore) BaseIssuerURLs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.issuers)
+}
+
+// KeyFunc returns a JWKCallback serving the trusted keys, without any network access. Unknown key
+// IDs return a KeyNotFoundError. Used with BaseIssuerURLs, a key trusted for one issuer would
+// also verify a token naming another trusted issuer; TrustStore.Verify prevents that.
+func (s *TrustStore) KeyFunc() JWKCallback {
+	return func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		s.mu.RLock()
+		key, ok := s.keys[keyID]
+		s.mu.RUnlock()
+		if !ok {
+			return nil, errors.NewKeyNotFoundError("key ID not found in trust store")
+		}
+		return key.publicKey, nil
+	}
+}
+
+// Verify verifies a token offline against the trusted keys. The issuer is derived from the
+// token's kid: the token must come from the issuer its key is trusted for, so the issuer options
+// of config (BaseIssuerURL, BaseIssuerURLs and ExactIssuer) are replaced with that issuer. All
+// other options of config apply.
+func (s *TrustStore) Verify(tokenString string, config VerifyConfig) (*VerificationResult, error) {
+	keyID, err := PeekKeyID(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	key, ok := s.keys[keyID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errors.NewKeyNotFoundError("key ID not found in trust store")
+	}
+
+	config.BaseIssuerURL = key.issuer
+	config.BaseIssuerURLs = nil
+	config.ExactIssuer = ""
+	return Verify(tokenString, config, s.KeyFunc())
+}
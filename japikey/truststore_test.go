@@ -0,0 +1,128 @@
+package japikey
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+func newTrustStoreFile(t *testing.T, issuer string, key *JAPIKey) *fstest.MapFile {
+	t.Helper()
+	keySet, err := key.ToJWKS()
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	keySetJSON, err := keySet.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	data, err := json.Marshal(map[string]interface{}{"issuer": issuer, "jwks": json.RawMessage(keySetJSON)})
+	if err != nil {
+		t.Fatalf("Failed to marshal trust store file: %v", err)
+	}
+	return &fstest.MapFile{Data: data}
+}
+
+func newTrustStoreKey(t *testing.T, issuerBase string) *JAPIKey {
+	t.Helper()
+	key, err := NewServiceJAPIKey("test-user", issuerBase, "test-audience", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	return key
+}
+
+func TestLoadTrustStore_VerifiesOffline(t *testing.T) {
+	alpha := newTrustStoreKey(t, "https://alpha.example.com")
+	beta := newTrustStoreKey(t, "https://beta.example.com")
+	untrusted := newTrustStoreKey(t, "https://alpha.example.com")
+
+	fsys := fstest.MapFS{
+		"trust/alpha.json":      newTrustStoreFile(t, "https://alpha.example.com", alpha),
+		"trust/beta.json":       newTrustStoreFile(t, "https://beta.example.com", beta),
+		"trust/README.md":       &fstest.MapFile{Data: []byte("ignored")},
+		"trust/nested/bad.json": &fstest.MapFile{Data: []byte("ignored")},
+	}
+
+	store, err := LoadTrustStore(fsys, "trust")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if issuers := store.BaseIssuerURLs(); len(issuers) != 2 {
+		t.Errorf("Expected 2 trusted issuers, got %v", issuers)
+	}
+
+	config := VerifyConfig{Timeout: 5 * time.Second}
+	for _, key := range []*JAPIKey{alpha, beta} {
+		if _, err := store.Verify(key.JWT, config); err != nil {
+			t.Errorf("Expected trusted token to verify, got: %v", err)
+		}
+	}
+
+	if _, err := store.Verify(untrusted.JWT, config); err == nil {
+		t.Error("Expected error for a token whose key is not trusted")
+	} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T", err)
+	}
+
+	// KeyFunc works with plain Verify too
+	config.BaseIssuerURLs = store.BaseIssuerURLs()
+	if _, err := Verify(alpha.JWT, config, store.KeyFunc()); err != nil {
+		t.Errorf("Expected trusted token to verify with KeyFunc, got: %v", err)
+	}
+}
+
+func TestTrustStore_KeysBoundToIssuer(t *testing.T) {
+	// A token naming beta as its issuer, signed by a key only trusted for alpha
+	spoofed := newTrustStoreKey(t, "https://beta.example.com")
+	keySet, err := spoofed.ToJWKS()
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+
+	store := NewTrustStore()
+	if err := store.Add("https://alpha.example.com", keySet); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	config := VerifyConfig{BaseIssuerURL: "https://beta.example.com", Timeout: 5 * time.Second}
+	if _, err := store.Verify(spoofed.JWT, config); err == nil {
+		t.Error("Expected error for a token from a different issuer than its key is trusted for")
+	}
+
+	if err := store.Add("https://beta.example.com", keySet); err == nil {
+		t.Error("Expected error for trusting a key ID for a second issuer")
+	}
+	if err := store.Add("https://alpha.example.com", keySet); err != nil {
+		t.Errorf("Expected re-adding the same key to succeed, got: %v", err)
+	}
+	if err := store.Add("not a url", keySet); err == nil {
+		t.Error("Expected error for an invalid issuer")
+	}
+}
+
+func TestLoadTrustStore_InvalidFiles(t *testing.T) {
+	key := newTrustStoreKey(t, "https://alpha.example.com")
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"missing directory", fstest.MapFS{}},
+		{"invalid JSON", fstest.MapFS{"trust/bad.json": &fstest.MapFile{Data: []byte("{")}}},
+		{"invalid JWKS", fstest.MapFS{"trust/bad.json": &fstest.MapFile{Data: []byte(`{"issuer":"https://alpha.example.com","jwks":{"keys":[]}}`)}}},
+		{"invalid issuer", fstest.MapFS{"trust/bad.json": newTrustStoreFile(t, "alpha.example.com", key)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadTrustStore(tt.fsys, "trust")
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package japikey
+
+import (
+	"crypto/sha256"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// DefaultVerifiedTokenCacheSize is the maximum number of entries applied when NewVerifiedTokenCache gets 0
+const DefaultVerifiedTokenCacheSize = 10000
+
+// Verifier bundles a VerifyConfig and key callback for verifying many tokens.
+type Verifier struct {
+	Config  VerifyConfig
+	KeyFunc JWKCallback
+
+	// Cache optionally returns results for recently verified tokens without re-running
+	// signature verification. A hit also skips the KeyActivation and OnVerified hooks; entries
+	// are capped at the token's exp and, if Config.MaxTokenAge is set, at iat+MaxTokenAge, so no
+	// deadline the config enforces is outlived. nil = every token is fully verified.
+	Cache *VerifiedTokenCache
+}
+
+// Verify verifies the token with the verifier's config and key callback, consulting the cache first.
+func (v *Verifier) Verify(tokenString string) (*VerificationResult, error) {
+	var generation uint64
+	if v.Cache != nil {
+		if result := v.Cache.get(tokenString); result != nil {
+			return result, nil
+		}
+		generation = v.Cache.currentGeneration()
+	}
+
+	result, err := Verify(tokenString, v.Config, v.KeyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Cache != nil {
+		v.Cache.put(tokenString, result, v.Config, generation)
+	}
+	return result, nil
+}
+
+// VerifiedTokenCache caches successful verification results, keyed by a SHA-256 hash of the token.
+//
+// Caching trades revocation immediacy for latency: a cached token keeps verifying for up to the
+// TTL after its key is revoked, unless the revocation is delivered to Invalidate (e.g. by
+// subscribing it to a RevocationNotifier). A verification still in flight when Invalidate or
+// Clear is called is returned but not cached. Keep the TTL short. Entries never outlive the
+// token's own exp claim, nor its MaxTokenAge when the verifying config sets one. Results are deep copies, so callers may modify them freely. It is safe
+// for concurrent use.
+type VerifiedTokenCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]verifiedTokenEntry
+	// generation counts Invalidate and Clear calls; results of verifications that started
+	// before the latest are not cached
+	generation uint64
+}
+
+type verifiedTokenEntry struct {
+	result    *VerificationResult
+	expiresAt time.Time
+}
+
+// NewVerifiedTokenCache creates a cache holding results for ttl. maxEntries bounds the cache
+// size; 0 = DefaultVerifiedTokenCacheSize applied. When full, new results are not cached.
+func NewVerifiedTokenCache(ttl time.Duration, maxEntries int) *VerifiedTokenCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultVerifiedTokenCacheSize
+	}
+	return &VerifiedTokenCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[[sha256.Size]byte]verifiedTokenEntry),
+	}
+}
+
+func (c *VerifiedTokenCache) get(tokenString string) *VerificationResult {
+	key := sha256.Sum256([]byte(tokenString))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil
+	}
+
+	// Copy so callers can't modify the cached result
+	return copyVerificationResult(entry.result)
+}
+
+func (c *VerifiedTokenCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// put caches result, verified under config, unless Invalidate or Clear has been called since
+// the verification began at generation, in which case the result may predate a revocation.
+func (c *VerifiedTokenCache) put(tokenString string, result *VerificationResult, config VerifyConfig, generation uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+	expiresAt := now.Add(c.ttl)
+	// Never serve a token from cache past its own expiry
+	if exp, err := timeClaim(result.Claims, "exp", true); err == nil && exp != nil && exp.Before(expiresAt) {
+		expiresAt = *exp
+	}
+	// Nor past the age Verify would start rejecting it at; without iat (e.g. redacted) that
+	// deadline is unknown, so the result is not cached
+	if config.MaxTokenAge > 0 {
+		iat, err := timeClaim(result.Claims, "iat", true)
+		if err != nil || iat == nil {
+			return
+		}
+		if deadline := iat.Add(config.MaxTokenAge); deadline.Before(expiresAt) {
+			expiresAt = deadline
+		}
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	key := sha256.Sum256([]byte(tokenString))
+	cached := copyVerificationResult(result)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+
+	if len(c.entries) >= c.maxEntries {
+		c.sweepLocked(now)
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = verifiedTokenEntry{result: cached, expiresAt: expiresAt}
+}
+
+// sweepLocked drops expired entries. c.mu must be held.
+func (c *VerifiedTokenCache) sweepLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate drops every cached result for the given key ID. Its signature matches
+// KeyInvalidator, so it can be subscribed directly to a RevocationNotifier.
+func (c *VerifiedTokenCache) Invalidate(kid string) {
+	keyID, err := uuid.Parse(kid)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.result.KeyID == keyID {
+			delete(c.entries, key)
+		}
+	}
+	c.generation++
+}
+
+// Clear drops every cached result.
+func (c *VerifiedTokenCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.generation++
+}
+
+// copyVerificationResult returns a copy of result sharing no mutable state with it other than
+// the public key. Nested claim values, such as permission lists and objects, are copied too.
+func copyVerificationResult(result *VerificationResult) *VerificationResult {
+	copied := *result
+	if result.Claims != nil {
+		copied.Claims = copyClaimValue(map[string]interface{}(result.Claims)).(map[string]interface{})
+	}
+	if result.Confirmation != nil {
+		confirmation := *result.Confirmation
+		copied.Confirmation = &confirmation
+	}
+	if result.Timings != nil {
+		timings := *result.Timings
+		copied.Timings = &timings
+	}
+	return &copied
+}
+
+// copyClaimValue deep-copies the maps and slices of a decoded claim value.
+func copyClaimValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyClaimValue(item)
+		}
+		return copied
+	case jwt.MapClaims:
+		return jwt.MapClaims(copyClaimValue(map[string]interface{}(v)).(map[string]interface{}))
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyClaimValue(item)
+		}
+		return copied
+	case []string:
+		return slices.Clone(v)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,301 @@
+package japikey
+
+import (
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// countingKeyFunc returns the public key and counts how often it was asked for it
+func countingKeyFunc(pubKey *rsa.PublicKey, calls *int) JWKCallback {
+	return func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		*calls++
+		return pubKey, nil
+	}
+}
+
+func newTestVerifier(pubKey *rsa.PublicKey, calls *int, cache *VerifiedTokenCache) *Verifier {
+	return &Verifier{
+		Config: VerifyConfig{
+			BaseIssuerURL: "https://example.com/",
+			Timeout:       5 * time.Second,
+		},
+		KeyFunc: countingKeyFunc(pubKey, calls),
+		Cache:   cache,
+	}
+}
+
+func TestVerifier_WithoutCache_AlwaysVerifies(t *testing.T) {
+	tokenString, pubKey, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	calls := 0
+	verifier := newTestVerifier(pubKey, &calls, nil)
+	for range 3 {
+		if _, err := verifier.Verify(tokenString); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 key lookups without cache, got %d", calls)
+	}
+}
+
+func TestVerifier_CacheHit_SkipsVerification(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	calls := 0
+	verifier := newTestVerifier(pubKey, &calls, NewVerifiedTokenCache(time.Minute, 0))
+
+	first, err := verifier.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	first.Claims["sub"] = "tampered"
+
+	second, err := verifier.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 key lookup with cache, got %d", calls)
+	}
+	if second.KeyID != keyID {
+		t.Errorf("Expected cached key ID %s, got %s", keyID, second.KeyID)
+	}
+	if second.Claims["sub"] != "test-user" {
+		t.Errorf("Expected cached claims to be isolated from callers, got sub %v", second.Claims["sub"])
+	}
+}
+
+func TestVerifier_CacheHit_NestedClaimsIsolated(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{
+		"permissions": []interface{}{"read"},
+		"org":         map[string]interface{}{"id": "org-1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	calls := 0
+	verifier := newTestVerifier(pubKey, &calls, NewVerifiedTokenCache(time.Minute, 0))
+
+	first, err := verifier.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	first.Claims["permissions"].([]interface{})[0] = "admin"
+	first.Claims["org"].(map[string]interface{})["id"] = "org-2"
+
+	second, err := verifier.Verify(tokenString)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 key lookup with cache, got %d", calls)
+	}
+	if got := second.Claims["permissions"].([]interface{})[0]; got != "read" {
+		t.Errorf("Expected cached permissions to be isolated from callers, got %v", got)
+	}
+	if got := second.Claims["org"].(map[string]interface{})["id"]; got != "org-1" {
+		t.Errorf("Expected cached nested claims to be isolated from callers, got %v", got)
+	}
+}
+
+func TestVerifier_FailuresNotCached(t *testing.T) {
+	tokenString := createInvalidToken()
+	_, pubKey, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	calls := 0
+	cache := NewVerifiedTokenCache(time.Minute, 0)
+	verifier := newTestVerifier(pubKey, &calls, cache)
+	for range 2 {
+		if _, err := verifier.Verify(tokenString); err == nil {
+			t.Fatal("Expected error for invalid token")
+		}
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected no cached entries, got %d", len(cache.entries))
+	}
+}
+
+func TestVerifiedTokenCache_Expiry(t *testing.T) {
+	now := time.Now()
+
+	t.Run("entry expires after TTL", func(t *testing.T) {
+		tokenString, pubKey, _, err := createValidToken()
+		if err != nil {
+			t.Fatalf("Failed to create valid token: %v", err)
+		}
+
+		calls := 0
+		cache := NewVerifiedTokenCache(time.Minute, 0)
+		cache.now = func() time.Time { return now }
+		verifier := newTestVerifier(pubKey, &calls, cache)
+
+		_, _ = verifier.Verify(tokenString)
+		cache.now = func() time.Time { return now.Add(2 * time.Minute) }
+		_, _ = verifier.Verify(tokenString)
+		if calls != 2 {
+			t.Errorf("Expected re-verification after TTL, got %d lookups", calls)
+		}
+	})
+
+	t.Run("entry bounded by token exp", func(t *testing.T) {
+		tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"exp": now.Add(30 * time.Second).Unix()})
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		calls := 0
+		cache := NewVerifiedTokenCache(time.Hour, 0)
+		cache.now = func() time.Time { return now }
+		verifier := newTestVerifier(pubKey, &calls, cache)
+
+		_, _ = verifier.Verify(tokenString)
+		cache.now = func() time.Time { return now.Add(time.Minute) }
+		if result := cache.get(tokenString); result != nil {
+			t.Error("Expected entry to expire with the token")
+		}
+	})
+
+	t.Run("entry bounded by MaxTokenAge", func(t *testing.T) {
+		tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"iat": now.Add(-50 * time.Minute).Unix()})
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		calls := 0
+		cache := NewVerifiedTokenCache(time.Hour, 0)
+		cache.now = func() time.Time { return now }
+		verifier := newTestVerifier(pubKey, &calls, cache)
+		verifier.Config.MaxTokenAge = time.Hour
+
+		if _, err := verifier.Verify(tokenString); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		cache.now = func() time.Time { return now.Add(9 * time.Minute) }
+		if cache.get(tokenString) == nil {
+			t.Error("Expected entry to be served before the token reaches MaxTokenAge")
+		}
+		// The token is now older than MaxTokenAge, which Verify would reject
+		cache.now = func() time.Time { return now.Add(11 * time.Minute) }
+		if cache.get(tokenString) != nil {
+			t.Error("Expected entry to expire once the token exceeds MaxTokenAge")
+		}
+	})
+
+	t.Run("MaxTokenAge without iat not cached", func(t *testing.T) {
+		tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"iat": now.Unix()})
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		calls := 0
+		cache := NewVerifiedTokenCache(time.Hour, 0)
+		verifier := newTestVerifier(pubKey, &calls, cache)
+		verifier.Config.MaxTokenAge = time.Hour
+		verifier.Config.RedactClaims = []string{"iat"}
+
+		if _, err := verifier.Verify(tokenString); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if cache.get(tokenString) != nil {
+			t.Error("Expected no entry when the age deadline cannot be determined")
+		}
+	})
+
+	t.Run("zero TTL disables caching", func(t *testing.T) {
+		tokenString, pubKey, _, err := createValidToken()
+		if err != nil {
+			t.Fatalf("Failed to create valid token: %v", err)
+		}
+
+		calls := 0
+		verifier := newTestVerifier(pubKey, &calls, NewVerifiedTokenCache(0, 0))
+		_, _ = verifier.Verify(tokenString)
+		_, _ = verifier.Verify(tokenString)
+		if calls != 2 {
+			t.Errorf("Expected 2 lookups with zero TTL, got %d", calls)
+		}
+	})
+}
+
+func TestVerifiedTokenCache_MaxEntries(t *testing.T) {
+	cache := NewVerifiedTokenCache(time.Minute, 1)
+	result := &VerificationResult{Claims: jwt.MapClaims{}, KeyID: uuid.New()}
+
+	cache.put("token-a", result, VerifyConfig{}, cache.currentGeneration())
+	cache.put("token-b", result, VerifyConfig{}, cache.currentGeneration())
+
+	if cache.get("token-a") == nil {
+		t.Error("Expected first entry to be cached")
+	}
+	if cache.get("token-b") != nil {
+		t.Error("Expected entry beyond capacity not to be cached")
+	}
+}
+
+func TestVerifiedTokenCache_InvalidateOnRevocation(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	calls := 0
+	cache := NewVerifiedTokenCache(time.Minute, 0)
+	verifier := newTestVerifier(pubKey, &calls, cache)
+
+	_, _ = verifier.Verify(tokenString)
+	cache.Invalidate(uuid.New().String())
+	if cache.get(tokenString) == nil {
+		t.Error("Expected unrelated invalidation to keep the entry")
+	}
+
+	cache.Invalidate(keyID.String())
+	if cache.get(tokenString) != nil {
+		t.Error("Expected entry to be dropped after revocation of its key")
+	}
+
+	_, _ = verifier.Verify(tokenString)
+	cache.Clear()
+	if cache.get(tokenString) != nil {
+		t.Error("Expected Clear to drop every entry")
+	}
+}
+
+func TestVerifiedTokenCache_InvalidateDuringVerify(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	cache := NewVerifiedTokenCache(time.Minute, 0)
+	verifier := &Verifier{
+		Config: VerifyConfig{BaseIssuerURL: "https://example.com/", Timeout: 5 * time.Second},
+		KeyFunc: func(uuid.UUID) (*rsa.PublicKey, error) {
+			// The revocation lands while the verification is in flight
+			cache.Invalidate(keyID.String())
+			return pubKey, nil
+		},
+		Cache: cache,
+	}
+
+	if _, err := verifier.Verify(tokenString); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cache.get(tokenString) != nil {
+		t.Error("Expected a verification racing Invalidate not to be cached")
+	}
+}
@@ -1,15 +1,25 @@
 package japikey
 
 import (
+	"context"
 	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	japikeyerrors "github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
 )
 
 // JWKCallback is a function that retrieves the JWK (JSON Web Key) given the key ID.
@@ -17,6 +27,27 @@ import (
 // for signature verification.
 type JWKCallback func(keyID uuid.UUID) (*rsa.PublicKey, error)
 
+// JWKSetCallback is a function that retrieves the whole JWKS holding the key for the key ID, for
+// sources that naturally return a set, such as a fetched JWKS document. See VerifyWithSet.
+type JWKSetCallback func(keyID uuid.UUID) (*jwks.JWKS, error)
+
+// KeyCandidate is a public key that may have signed a token, as returned by a JWKCandidatesCallback.
+type KeyCandidate struct {
+	KeyID     uuid.UUID
+	PublicKey *rsa.PublicKey
+
+	// CreatedAt is when the key was created, used to order candidates; zero if unknown
+	CreatedAt time.Time
+}
+
+// JWKCandidatesCallback is a function that retrieves every key that may have signed a token with
+// the key ID, e.g. while two keys briefly share acceptance during rotation. See VerifyWithCandidates.
+type JWKCandidatesCallback func(keyID uuid.UUID) ([]KeyCandidate, error)
+
+// JWKHistoryCallback is a function that retrieves every key material the key ID has had, for
+// issuers that rotate key material in place under an unchanged kid. See VerifyWithKeyHistory.
+type JWKHistoryCallback func(keyID uuid.UUID) ([]*rsa.PublicKey, error)
+
 // VerificationResult holds the result of a successful token verification.
 type VerificationResult struct {
 	// Claims contains the validated claims from the token
@@ -24,6 +55,142 @@ type VerificationResult struct {
 
 	// KeyID is the key identifier from the token header
 	KeyID uuid.UUID
+
+	// Confirmation is the token's cnf claim, or nil if it has none or, without a
+	// VerifyConfig.ConfirmationCheck, if it is not a well-formed confirmation
+	Confirmation *Confirmation
+
+	// Algorithm is the signing algorithm that verified the token's signature
+	Algorithm string
+
+	// PublicKey is the key returned by the key callback that verified the token's signature, e.g.
+	// to log its thumbprint or learn which candidate matched. It holds only public material.
+	PublicKey *rsa.PublicKey
+
+	// Timings breaks down where verification spent its time; nil unless VerifyConfig.CollectTimings is set
+	Timings *Timings
+}
+
+// NewVerificationResult builds a VerificationResult without verifying a token, so that tests of
+// downstream code consuming results can fabricate one. The claims are copied, and Confirmation is
+// taken from a well-formed cnf claim. Other fields are left zero and may be set directly.
+//
+// It performs no verification; never use it to turn untrusted claims into an authorization result.
+func NewVerificationResult(claims map[string]interface{}, kid uuid.UUID, algorithm string) *VerificationResult {
+	result := &VerificationResult{
+		Claims:    jwt.MapClaims(maps.Clone(claims)),
+		KeyID:     kid,
+		Algorithm: algorithm,
+	}
+	if result.Claims == nil {
+		result.Claims = jwt.MapClaims{}
+	}
+	if confirmation, err := extractConfirmation(result.Claims); err == nil {
+		result.Confirmation = confirmation
+	}
+	return result
+}
+
+// String returns the named claim as a string. A ValidationError is returned if the claim is
+// absent or is not a string.
+func (r *VerificationResult) String(claim string) (string, error) {
+	if r == nil || r.Claims == nil {
+		return "", japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' is missing", claim))
+	}
+	raw, ok := r.Claims[claim]
+	if !ok {
+		return "", japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' is missing", claim))
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		return "", japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' must be a string", claim))
+	}
+	return value, nil
+}
+
+// StringSlice returns the named claim as a []string, converting the []interface{} that JSON
+// arrays decode to. A ValidationError is returned if the claim is absent or is not an array of
+// strings.
+func (r *VerificationResult) StringSlice(claim string) ([]string, error) {
+	if r == nil || r.Claims == nil {
+		return nil, japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' is missing", claim))
+	}
+	raw, ok := r.Claims[claim]
+	if !ok {
+		return nil, japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' is missing", claim))
+	}
+
+	switch values := raw.(type) {
+	case []string:
+		return slices.Clone(values), nil
+	case []interface{}:
+		result := make([]string, 0, len(values))
+		for _, valueRaw := range values {
+			value, ok := valueRaw.(string)
+			if !ok {
+				return nil, japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' must contain only strings", claim))
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	default:
+		return nil, japikeyerrors.NewValidationError(fmt.Sprintf("claim '%s' must be an array of strings", claim))
+	}
+}
+
+// Audiences returns the aud claim as a slice, normalizing its single-string and array forms.
+// A missing aud, or one of any other shape, returns nil; an empty array returns an empty,
+// non-nil slice, so a token that names no audience can be told apart from one without the claim.
+func (r *VerificationResult) Audiences() []string {
+	if r == nil {
+		return nil
+	}
+	return audienceClaim(r.Claims)
+}
+
+// audienceClaim returns the aud claim as a slice, with the semantics of VerificationResult.Audiences.
+func audienceClaim(claims jwt.MapClaims) []string {
+	switch audience := claims["aud"].(type) {
+	case string:
+		return []string{audience}
+	case []string:
+		return slices.Clone(audience)
+	case []interface{}:
+		result := make([]string, 0, len(audience))
+		for _, entryRaw := range audience {
+			entry, ok := entryRaw.(string)
+			if !ok {
+				return nil
+			}
+			result = append(result, entry)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// Timings is the time spent in each phase of a successful verification, for performance
+// debugging, e.g. to tell whether latency is dominated by the key callback or the RSA verify.
+type Timings struct {
+	// Structural covers the size, segment and header checks done before parsing
+	Structural time.Duration
+
+	// Parse covers decoding the token's header and claims
+	Parse time.Duration
+
+	// KeyLookup covers the JWKCallback and the key thumbprint check
+	KeyLookup time.Duration
+
+	// Signature covers the RSA signature verification
+	Signature time.Duration
+
+	// Claims covers validating the claims after the signature has been verified
+	Claims time.Duration
+
+	// Total is the whole verification, excluding the OnVerified hook
+	Total time.Duration
 }
 
 // VerifyConfig holds the configuration for verifying a JAPIKey.
@@ -32,9 +199,153 @@ type VerifyConfig struct {
 	// BaseIssuerURL is the base URL for the issuer that should be present in the token
 	BaseIssuerURL string
 
+	// BaseIssuerURLs is an allowlist of additional base URLs; the token's issuer may match any
+	// of them or BaseIssuerURL. At least one base URL must be configured, unless ExactIssuer is set.
+	BaseIssuerURLs []string
+
+	// ExactIssuer pins the full issuer, including the kid, that the token must carry, e.g. for a
+	// single-key deployment or key pinning. It is stricter than base matching, and the issuer must
+	// still end in the token's kid unless DisableKidIssuerBinding is set. It cannot be combined
+	// with BaseIssuerURL or BaseIssuerURLs.
+	ExactIssuer string
+
 	// Timeout is the timeout for retrieving cryptographic keys from the callback function
 	// It should be a value > 0
 	Timeout time.Duration
+
+	// Leeway is the clock skew tolerated when validating exp, nbf and iat.
+	// The default of 0 keeps all time checks strict; negative values are treated as 0.
+	Leeway time.Duration
+
+	// MaxFutureSkew is the clock skew tolerated for nbf and iat values that lie in the future,
+	// e.g. when the issuer's clock runs slightly ahead. When set, it replaces Leeway for those
+	// two claims only, so expiry stays governed by Leeway alone. Negative values are treated as 0.
+	MaxFutureSkew time.Duration
+
+	// NbfLeeway and IatLeeway tune the future skew tolerated for nbf and iat independently, e.g.
+	// to accept an nbf set slightly ahead while staying strict about iat. Each takes precedence
+	// over MaxFutureSkew, which in turn takes precedence over Leeway, for its claim only.
+	// 0 = fall back to MaxFutureSkew, or Leeway if that is not set either. Negative values are
+	// treated as 0.
+	NbfLeeway time.Duration
+	IatLeeway time.Duration
+
+	// RequireKeyThumbprint rejects tokens without a jkt header (see Config.IncludeKeyThumbprint).
+	// A jkt header that is present is always checked against the thumbprint of the key returned
+	// by the callback, so a different key registered under a colliding kid cannot verify the token.
+	RequireKeyThumbprint bool
+
+	// KeyIDIsThumbprint requires the token's kid to be the key ID derived from the thumbprint of
+	// the key the callback returns (see ThumbprintKeyID), e.g. for tokens signed by a Signer whose
+	// KeyID is ThumbprintKeyID(Public()). This ties the kid to the key itself, so a different key
+	// can never be registered under it. The default of false accepts any kid.
+	KeyIDIsThumbprint bool
+
+	// RequireTypeHeader rejects tokens whose typ header is missing or is not "JWT" (compared
+	// case-insensitively, as RFC 7515 recommends). Tokens minted by this package always carry it.
+	// The default of false ignores typ.
+	RequireTypeHeader bool
+
+	// RequiredType rejects tokens whose typ header is missing or not exactly this value (e.g.
+	// "at+jwt" or "JWT"; the comparison is case-sensitive), for single-format deployments. When set
+	// it takes the place of RequireTypeHeader's check. The default of "" imposes no requirement.
+	RequiredType string
+
+	// SingleAudienceOnly rejects tokens whose aud claim is an array, even one with a single entry,
+	// for strict services that treat multi-audience tokens as suspicious. An aud that is neither
+	// a string nor an array is rejected too. Tokens minted by this package always carry a single
+	// string audience. The default of false accepts either form; a missing aud is always accepted.
+	SingleAudienceOnly bool
+
+	// RequireSubject rejects tokens whose sub claim is missing or empty, for services that key
+	// authorization on the subject. The default of false accepts subject-less tokens, e.g.
+	// service tokens identified by other claims.
+	RequireSubject bool
+
+	// TolerateStringTimestamps accepts exp, nbf and iat given as strings, holding either Unix
+	// seconds or an RFC 3339 date, for interop with non-compliant issuers. Unparseable strings are
+	// still rejected. The default of false only accepts numeric timestamps, as RFC 7519 requires.
+	TolerateStringTimestamps bool
+
+	// MaxTokenAge rejects tokens whose iat is further in the past than this, regardless of their
+	// exp, limiting how long a leaked long-lived token stays usable. When set, iat is required.
+	// 0 = no limit.
+	MaxTokenAge time.Duration
+
+	// MaxTimestamp is the latest Unix time, in seconds, accepted in the exp, nbf and iat claims.
+	// Later values, and negative ones, are rejected as invalid claims rather than read as absurd or
+	// overflowing times. 0 = DefaultMaxTimestamp (the end of year 9999) applied.
+	MaxTimestamp int64
+
+	// ForwardReservedClaims rejects tokens carrying any of these claims, so that tokens stay
+	// forward-compatible with a later token version that defines them with different semantics.
+	// An entry ending in "*" matches every claim with that prefix, as in Config.ReservedClaims.
+	// ReservedClaimsAfter(MaxSupportedVersion) gives the names reserved so far. Empty = no check.
+	ForwardReservedClaims []string
+
+	// RequiredScopes lists entries that must all be granted by the token, either through the
+	// space-delimited scope claim or the permissions array claim. Empty means no scope check.
+	RequiredScopes []string
+
+	// NormalizeIssuerURL enables URL-aware issuer comparison: the scheme and host are compared
+	// case-insensitively, a single trailing dot on a fully-qualified host (example.com.) is
+	// ignored, and so are default ports (:443 for https, :80 for http).
+	// The default of false keeps the strict exact string match.
+	NormalizeIssuerURL bool
+
+	// DisableKidIssuerBinding drops the requirement that the issuer end in the token's kid, for
+	// federated verification of third-party tokens whose issuer format differs. The issuer must
+	// still equal an allowed base URL or lie under its path, and the signature is still verified
+	// against the key the callback returns for the kid. The default of false keeps the binding.
+	DisableKidIssuerBinding bool
+
+	// KeyIDClaim names a payload claim to read the key ID from, for non-standard issuers that put
+	// it there instead of in the kid header. When the claim is present it is used, and a kid header
+	// that is also present must agree with it; when absent, the header is used. The key ID is still
+	// bound to the issuer as usual. The default of "" reads the header only.
+	KeyIDClaim string
+
+	// MaxIssuerLength is the maximum length in bytes of the iss claim. Longer issuers are rejected
+	// before any issuer comparison, bounding the work done on attacker-controlled strings.
+	// 0 = DefaultMaxIssuerLength applied.
+	MaxIssuerLength int
+
+	// RequireHTTPSIssuer rejects tokens whose issuer does not use the https scheme, since the key
+	// material for an http issuer would be fetched over plaintext. The default of false keeps
+	// accepting http issuers for backward compatibility (e.g. local test issuers); enable it in
+	// production.
+	RequireHTTPSIssuer bool
+
+	// MaxClaimDepth is the maximum nesting depth of the claims, where the claims object itself
+	// has depth 1 and every nested object or array adds one level.
+	// 0 = DefaultMaxClaimDepth applied.
+	MaxClaimDepth int
+
+	// KeyActivation optionally returns when the signing key for a kid becomes active (e.g. from
+	// KeyLookupResult.NotBefore), so that a key pre-published ahead of rotation only verifies
+	// tokens once it is active; the zero time means active immediately. Activation is independent
+	// of the token's own nbf claim: both must have passed, each within Leeway. An error from the
+	// callback rejects the token. nil = no activation gating.
+	KeyActivation func(keyID uuid.UUID) (time.Time, error)
+
+	// ConfirmationCheck optionally enforces the token's cnf claim, e.g. by comparing its jkt
+	// against the thumbprint of the key the client proved possession of. When set, a malformed
+	// cnf is rejected. nil = no check, and a cnf that does not parse is ignored.
+	ConfirmationCheck ConfirmationCheck
+
+	// OnVerified is optionally called with the result once every other check has passed, to run
+	// final assertions. Returning an error rejects the token. nil = no hook.
+	OnVerified func(result *VerificationResult) error
+
+	// RedactClaims lists claims removed from VerificationResult.Claims before Verify returns, e.g.
+	// so PII such as email never leaves the auth layer. Redaction happens after every check,
+	// including OnVerified, so it can never affect a verification decision; the signature still
+	// covers the full token, and only the returned map is trimmed.
+	RedactClaims []string
+
+	// CollectTimings records how long each verification phase took in VerificationResult.Timings.
+	// When false, no clock readings are taken.
+	CollectTimings bool
 }
 
 // validateVersion validates the version claim from MapClaims.
@@ -49,111 +360,588 @@ func validateVersion(claims jwt.MapClaims) error {
 		return japikeyerrors.NewValidationError("token version claim must be a string")
 	}
 
-	if version != "japikey-v1" {
-		return japikeyerrors.NewValidationError(fmt.Sprintf("invalid version: %s, expected japikey-v1", version))
+	number, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+
+	if number > maxVersion() {
+		return japikeyerrors.NewValidationError(fmt.Sprintf("unsupported version: %s, maximum supported is %s%d", version, VersionPrefix, maxVersion()))
 	}
 
 	return nil
 }
 
-// validateIssuer validates that the issuer claim exactly matches baseIssuerURL/keyID.
-// baseIssuerURL is required for security - issuer validation is mandatory.
-func validateIssuer(issuer string, baseIssuerURL string, keyID uuid.UUID) error {
-	if baseIssuerURL == "" {
+// validateIssuer validates that the issuer claim exactly matches baseIssuerURL/keyID for one of
+// the configured base URLs, or only that it is under one of them if DisableKidIssuerBinding is set.
+// If ExactIssuer is set instead, the issuer must equal it.
+// A base URL or exact issuer is required for security - issuer validation is mandatory.
+func validateIssuer(issuer string, config VerifyConfig, keyID uuid.UUID) error {
+	var baseURLs []string
+	for _, baseURL := range append([]string{config.BaseIssuerURL}, config.BaseIssuerURLs...) {
+		if baseURL != "" {
+			baseURLs = append(baseURLs, baseURL)
+		}
+	}
+	if config.ExactIssuer != "" && len(baseURLs) > 0 {
+		return japikeyerrors.NewInternalError("exact issuer cannot be combined with base issuer URLs")
+	}
+	if config.ExactIssuer == "" && len(baseURLs) == 0 {
 		return japikeyerrors.NewInternalError("base issuer URL is required for issuer validation")
 	}
 
 	if issuer == "" {
-		return japikeyerrors.NewValidationError("token missing issuer claim")
+		return japikeyerrors.NewIssuerFormatError("token missing issuer claim")
 	}
 
-	// Normalize baseIssuerURL to always end with /
-	normalizedBaseURL := baseIssuerURL
-	if !strings.HasSuffix(normalizedBaseURL, "/") {
-		normalizedBaseURL += "/"
+	maxLength := config.MaxIssuerLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxIssuerLength
+	}
+	if len(issuer) > maxLength {
+		return japikeyerrors.NewValidationError(fmt.Sprintf("token issuer exceeds maximum length of %d bytes", maxLength))
 	}
 
-	// Expected issuer is exactly baseIssuerURL/keyID
-	expectedIssuer := normalizedBaseURL + keyID.String()
+	if config.RequireHTTPSIssuer {
+		if issuerURL, err := url.Parse(issuer); err != nil || !strings.EqualFold(issuerURL.Scheme, "https") {
+			return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, must use https", issuer))
+		}
+	}
 
-	// Exact string match
-	if issuer != expectedIssuer {
-		return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, expected %s", issuer, expectedIssuer))
+	actualIssuer := issuer
+	if config.NormalizeIssuerURL {
+		actualIssuer = normalizeIssuerURL(actualIssuer)
 	}
 
+	if config.ExactIssuer != "" {
+		return validateExactIssuer(issuer, actualIssuer, config, keyID)
+	}
+
+	if config.DisableKidIssuerBinding {
+		return validateIssuerBase(issuer, actualIssuer, baseURLs, config.NormalizeIssuerURL)
+	}
+
+	var expectedIssuer string
+	for _, baseURL := range baseURLs {
+		expectedIssuer = expectedIssuerFor(baseURL, keyID)
+		if config.NormalizeIssuerURL {
+			expectedIssuer = normalizeIssuerURL(expectedIssuer)
+		}
+
+		// Exact string match
+		if actualIssuer == expectedIssuer {
+			return nil
+		}
+	}
+
+	// A mismatch caused by a missing or malformed key ID segment is reported separately from a wrong issuer
+	if missingKeyIDSegment(issuer, baseURLs) {
+		return japikeyerrors.NewIssuerFormatError(fmt.Sprintf("invalid issuer: %s, issuer missing key ID path segment", issuer))
+	}
+	if !hasKeyIDSegment(issuer) {
+		return japikeyerrors.NewIssuerFormatError(fmt.Sprintf("invalid issuer: %s, last path segment is not a valid key ID", issuer))
+	}
+
+	if len(baseURLs) > 1 {
+		return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, expected one of the allowed issuers", issuer))
+	}
+	return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, expected %s", issuer, expectedIssuer))
+}
+
+// validateExactIssuer validates that actualIssuer equals config.ExactIssuer and, unless
+// DisableKidIssuerBinding is set, that the pinned issuer ends in the token's kid.
+func validateExactIssuer(issuer, actualIssuer string, config VerifyConfig, keyID uuid.UUID) error {
+	expectedIssuer := config.ExactIssuer
+	if config.NormalizeIssuerURL {
+		expectedIssuer = normalizeIssuerURL(expectedIssuer)
+	}
+	if actualIssuer != expectedIssuer {
+		return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, expected %s", issuer, config.ExactIssuer))
+	}
+
+	if !config.DisableKidIssuerBinding && !strings.HasSuffix(expectedIssuer, "/"+keyID.String()) {
+		return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, does not end in the token's key ID", issuer))
+	}
 	return nil
 }
 
+// validateIssuerBase validates that actualIssuer equals one of baseURLs or lies under its path,
+// without binding it to the kid. The match respects path segment boundaries, so
+// https://example.com.evil.test is not under https://example.com.
+func validateIssuerBase(issuer, actualIssuer string, baseURLs []string, normalize bool) error {
+	for _, baseURL := range baseURLs {
+		base := strings.TrimSuffix(baseURL, "/")
+		if normalize {
+			base = strings.TrimSuffix(normalizeIssuerURL(base), "/")
+		}
+
+		if actualIssuer == base || strings.HasPrefix(actualIssuer, base+"/") {
+			return nil
+		}
+	}
+
+	return japikeyerrors.NewValidationError(fmt.Sprintf("invalid issuer: %s, expected an issuer under one of the allowed base URLs", issuer))
+}
+
+// missingKeyIDSegment reports whether issuer stops where its key ID segment should begin: it is a
+// bare host with no path, or it is one of the base URLs itself.
+func missingKeyIDSegment(issuer string, baseURLs []string) bool {
+	trimmed := strings.TrimSuffix(issuer, "/")
+	if u, err := url.Parse(trimmed); err == nil && u.Host != "" && u.Path == "" {
+		return true
+	}
+	for _, baseURL := range baseURLs {
+		if trimmed == strings.TrimSuffix(baseURL, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeyIDSegment reports whether the last path segment of issuer parses as a UUID.
+func hasKeyIDSegment(issuer string) bool {
+	segment := issuer[strings.LastIndex(issuer, "/")+1:]
+	_, err := uuid.Parse(segment)
+	return err == nil
+}
+
+// expectedIssuerFor returns the issuer a token with keyID must carry under baseIssuerURL,
+// which is exactly baseIssuerURL/keyID.
+func expectedIssuerFor(baseIssuerURL string, keyID uuid.UUID) string {
+	// Normalize baseIssuerURL to always end with /
+	if !strings.HasSuffix(baseIssuerURL, "/") {
+		baseIssuerURL += "/"
+	}
+	return baseIssuerURL + keyID.String()
+}
+
+// normalizeIssuerURL canonicalizes an issuer URL for comparison. url.Parse lowercases the
+// scheme; the host is lowercased and loses one trailing FQDN dot, and the scheme's default port
+// is stripped here. Values that cannot be parsed as an absolute URL are returned unchanged, so
+// they still fall back to an exact match.
+func normalizeIssuerURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	hostname := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(hostname, port)
+	case strings.Contains(hostname, ":"):
+		u.Host = "[" + hostname + "]"
+	default:
+		u.Host = hostname
+	}
+
+	return u.String()
+}
+
 // extractKeyIDFromHeader extracts and validates the key ID from the token header.
 func extractKeyIDFromHeader(header map[string]interface{}) (uuid.UUID, error) {
 	keyIDRaw, ok := header[KeyIDHeader]
 	if !ok {
-		return uuid.Nil, japikeyerrors.NewValidationError("token header missing key ID")
+		return uuid.Nil, japikeyerrors.NewKeyIDFormatError("token header missing key ID")
 	}
 
 	keyIDStr, ok := keyIDRaw.(string)
 	if !ok {
-		return uuid.Nil, japikeyerrors.NewValidationError("token header contains invalid key ID type")
+		return uuid.Nil, japikeyerrors.NewKeyIDFormatError("token header contains invalid key ID type")
 	}
 
 	keyID, err := uuid.Parse(keyIDStr)
 	if err != nil {
-		return uuid.Nil, japikeyerrors.NewValidationError("token header contains invalid key ID format")
+		return uuid.Nil, japikeyerrors.NewKeyIDFormatError("token header contains invalid key ID format")
+	}
+
+	return keyID, nil
+}
+
+// extractKeyID extracts the key ID from the claim named claimName if it is set and present,
+// otherwise from the header. A header kid present alongside the claim must name the same key.
+func extractKeyID(header map[string]interface{}, claims jwt.MapClaims, claimName string) (uuid.UUID, error) {
+	if claimName == "" {
+		return extractKeyIDFromHeader(header)
+	}
+	keyIDRaw, ok := claims[claimName]
+	if !ok {
+		return extractKeyIDFromHeader(header)
+	}
+
+	keyIDStr, ok := keyIDRaw.(string)
+	if !ok {
+		return uuid.Nil, japikeyerrors.NewKeyIDFormatError(fmt.Sprintf("token key ID claim '%s' must be a string", claimName))
+	}
+	keyID, err := uuid.Parse(keyIDStr)
+	if err != nil {
+		return uuid.Nil, japikeyerrors.NewKeyIDFormatError(fmt.Sprintf("token key ID claim '%s' has an invalid key ID format", claimName))
+	}
+
+	if _, ok := header[KeyIDHeader]; ok {
+		headerKeyID, err := extractKeyIDFromHeader(header)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if headerKeyID != keyID {
+			return uuid.Nil, japikeyerrors.NewKeyIDFormatError(fmt.Sprintf("token header key ID does not match key ID claim '%s'", claimName))
+		}
 	}
 
 	return keyID, nil
 }
 
 // validateJAPIKeyClaims validates JAPIKey-specific requirements on the claims.
-func validateJAPIKeyClaims(claims jwt.MapClaims, baseIssuerURL string, keyID uuid.UUID) error {
+func validateJAPIKeyClaims(claims jwt.MapClaims, config VerifyConfig, keyID uuid.UUID) error {
 	if err := validateVersion(claims); err != nil {
 		return err
 	}
 
 	issuer, err := claims.GetIssuer()
 	if err != nil {
-		return japikeyerrors.NewValidationError("Invalid issuer")
+		return japikeyerrors.NewIssuerFormatError("Invalid issuer")
+	}
+
+	if err := validateIssuer(issuer, config, keyID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateSubject validates that the sub claim is present and not empty.
+func validateSubject(claims jwt.MapClaims) error {
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return japikeyerrors.NewValidationError("token subject is required")
+	}
+	return nil
+}
+
+// validateSingleAudience validates that the aud claim, if present, is a single string.
+func validateSingleAudience(claims jwt.MapClaims) error {
+	audience, exists := claims["aud"]
+	if !exists {
+		return nil
+	}
+	if _, ok := audience.(string); !ok {
+		return japikeyerrors.NewValidationError("token audience must be a single value")
+	}
+	return nil
+}
+
+// validateForwardReservedClaims validates that the token carries none of the reserved claims.
+func validateForwardReservedClaims(claims jwt.MapClaims, reserved []string) error {
+	if names := reservedClaimsIn(claims, reserved); len(names) > 0 {
+		return japikeyerrors.NewValidationError("claim '" + names[0] + "' is reserved by a future token version")
+	}
+	return nil
+}
+
+// validateTimeClaims validates the exp, nbf and iat claims against now.
+// exp is required and tolerates Leeway; nbf and iat are optional and tolerate NbfLeeway and
+// IatLeeway respectively if set, otherwise MaxFutureSkew if set, otherwise Leeway.
+// iat is required when MaxTokenAge is set. Each is rejected if outside 0 to MaxTimestamp.
+func validateTimeClaims(claims jwt.MapClaims, config VerifyConfig, now time.Time) error {
+	leeway := max(config.Leeway, 0)
+	futureSkew := leeway
+	if config.MaxFutureSkew > 0 {
+		futureSkew = config.MaxFutureSkew
+	}
+	nbfSkew, iatSkew := futureSkew, futureSkew
+	if config.NbfLeeway > 0 {
+		nbfSkew = config.NbfLeeway
+	}
+	if config.IatLeeway > 0 {
+		iatSkew = config.IatLeeway
+	}
+	maxTimestamp := config.MaxTimestamp
+	if maxTimestamp <= 0 {
+		maxTimestamp = DefaultMaxTimestamp
+	}
+	boundedTimeClaim := func(name string) (*time.Time, error) {
+		t, err := timeClaim(claims, name, config.TolerateStringTimestamps)
+		if err != nil || t == nil {
+			return t, err
+		}
+		if t.Unix() < 0 || t.Unix() > maxTimestamp {
+			return nil, errors.New("claim " + name + " is out of range")
+		}
+		return t, nil
+	}
+
+	exp, err := boundedTimeClaim("exp")
+	if err != nil {
+		return japikeyerrors.NewValidationError("token expiration claim is invalid")
+	}
+	if exp == nil {
+		return japikeyerrors.NewValidationError("token missing expiration claim")
+	}
+	if !now.Before(exp.Add(leeway)) {
+		return japikeyerrors.NewTokenExpiredError("token has expired")
+	}
+
+	nbf, err := boundedTimeClaim("nbf")
+	if err != nil {
+		return japikeyerrors.NewValidationError("token not before claim is invalid")
+	}
+	if nbf != nil && now.Add(nbfSkew).Before(*nbf) {
+		return japikeyerrors.NewNotBeforeError("token is not yet valid")
+	}
+
+	iat, err := boundedTimeClaim("iat")
+	if err != nil {
+		return japikeyerrors.NewValidationError("token issued at claim is invalid")
+	}
+	if iat != nil && now.Add(iatSkew).Before(*iat) {
+		return japikeyerrors.NewValidationError("token used before issued")
+	}
+
+	if config.MaxTokenAge > 0 {
+		if iat == nil {
+			return japikeyerrors.NewValidationError("token missing issued at claim")
+		}
+		if now.Sub(*iat) > config.MaxTokenAge {
+			return japikeyerrors.NewTokenTooOldError("token was issued too long ago")
+		}
+	}
+
+	return nil
+}
+
+// numericClaim converts a numeric claim value to float64. Besides the float64 produced by the
+// default JSON decoding, it accepts json.Number (from decoders using UseNumber), int and int64.
+func numericClaim(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
 	}
+	return 0, false
+}
 
-	if err := validateIssuer(issuer, baseIssuerURL, keyID); err != nil {
+// timeClaim returns the named NumericDate claim as a time, or nil if the claim is absent.
+// If tolerateStrings is set, string values holding Unix seconds or an RFC 3339 date are accepted.
+func timeClaim(claims jwt.MapClaims, name string, tolerateStrings bool) (*time.Time, error) {
+	raw, ok := claims[name]
+	if !ok {
+		return nil, nil
+	}
+
+	if str, isString := raw.(string); isString && tolerateStrings {
+		if parsed, err := time.Parse(time.RFC3339, str); err == nil {
+			return &parsed, nil
+		}
+		raw = json.Number(str)
+	}
+
+	seconds, ok := numericClaim(raw)
+	if !ok || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return nil, errors.New("claim " + name + " is not a number")
+	}
+
+	// Converting a float outside the int64 range is implementation-defined, so reject it first
+	if seconds < math.MinInt64 || seconds >= math.MaxInt64 {
+		return nil, errors.New("claim " + name + " is out of range")
+	}
+
+	whole, frac := math.Modf(seconds)
+	t := time.Unix(int64(whole), int64(frac*1e9))
+	return &t, nil
+}
+
+// grantedScopes collects the entries granted by the scope string and permissions array claims.
+func grantedScopes(claims jwt.MapClaims) (map[string]bool, error) {
+	granted := make(map[string]bool)
+
+	if scopeRaw, ok := claims[ScopeClaim]; ok {
+		scope, ok := scopeRaw.(string)
+		if !ok {
+			return nil, japikeyerrors.NewValidationError("token scope claim must be a string")
+		}
+		for _, entry := range strings.Fields(scope) {
+			granted[entry] = true
+		}
+	}
+
+	if permissionsRaw, ok := claims[PermissionsClaim]; ok {
+		permissions, ok := permissionsRaw.([]interface{})
+		if !ok {
+			return nil, japikeyerrors.NewValidationError("token permissions claim must be an array")
+		}
+		for _, permissionRaw := range permissions {
+			permission, ok := permissionRaw.(string)
+			if !ok {
+				return nil, japikeyerrors.NewValidationError("token permissions claim must contain only strings")
+			}
+			granted[permission] = true
+		}
+	}
+
+	return granted, nil
+}
+
+// validateScopes validates that every required scope is granted by the token.
+func validateScopes(claims jwt.MapClaims, requiredScopes []string) error {
+	if len(requiredScopes) == 0 {
+		return nil
+	}
+
+	granted, err := grantedScopes(claims)
+	if err != nil {
 		return err
 	}
 
+	for _, required := range requiredScopes {
+		if !granted[required] {
+			return japikeyerrors.NewScopeError(fmt.Sprintf("token missing required scope: %s", required))
+		}
+	}
+
 	return nil
 }
 
+// validateClaimDepth validates that the claims do not nest deeper than maxDepth.
+func validateClaimDepth(claims jwt.MapClaims, maxDepth int) error {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxClaimDepth
+	}
+
+	if claimDepth(map[string]interface{}(claims)) > maxDepth {
+		return japikeyerrors.NewValidationError(fmt.Sprintf("token claims exceed maximum nesting depth of %d", maxDepth))
+	}
+
+	return nil
+}
+
+// claimDepth returns the nesting depth of a decoded JSON value.
+func claimDepth(value interface{}) int {
+	maxChild := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			maxChild = max(maxChild, claimDepth(child))
+		}
+	case []interface{}:
+		for _, child := range v {
+			maxChild = max(maxChild, claimDepth(child))
+		}
+	default:
+		return 0
+	}
+	return maxChild + 1
+}
+
 // checkTokenSize validates that the token size is within the maximum allowed limit.
 func checkTokenSize(tokenString string) error {
 	if len(tokenString) > MaxTokenSize {
-		return japikeyerrors.NewValidationError(fmt.Sprintf("token size exceeds maximum allowed size of %d bytes", MaxTokenSize))
+		return japikeyerrors.NewTokenSizeError(fmt.Sprintf("token size exceeds maximum allowed size of %d bytes", MaxTokenSize))
 	}
 	return nil
 }
 
+// checkTokenSegments validates that a three-part token has no empty segments and that the
+// signature segment decodes to a non-empty value. Tokens with a different number of parts
+// are left for the parser to reject.
+func checkTokenSegments(tokenString string) error {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return japikeyerrors.NewTokenFormatError("token contains an empty segment")
+		}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) == 0 {
+		return japikeyerrors.NewTokenFormatError("token signature segment is invalid")
+	}
+
+	return nil
+}
+
 // Verify takes in the JWT string, the config, as well as a callback function which retrieves the JWK if given the key id.
 // It either returns the validated claims, or an appropriate error.
+// Errors from the callback are returned as KeyNotFoundError, except FetchError,
+// DatabaseTimeoutError and DatabaseUnavailableError, which are returned unchanged so that an
+// unreachable key source can be told apart from an unknown key.
 func Verify(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, error) {
+	// Every clock reading is guarded by timings != nil, so disabled timings cost nothing
+	var timings *Timings
+	var started, mark time.Time
+	if config.CollectTimings {
+		timings = &Timings{}
+		started = time.Now()
+		mark = started
+	}
+
 	// FR-020: Enforce maximum token size limit BEFORE any parsing
 	if err := checkTokenSize(tokenString); err != nil {
 		return nil, err
 	}
 
-	// FR-014: Use golang-jwt library for parsing and validation
+	if err := checkTokenSegments(tokenString); err != nil {
+		return nil, err
+	}
+
+	header, err := decodeHeader(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPayloadEncoding(header); err != nil {
+		return nil, err
+	}
+	if config.RequiredType != "" {
+		if err := checkRequiredType(header, config.RequiredType); err != nil {
+			return nil, err
+		}
+	} else if config.RequireTypeHeader {
+		if err := checkTypeHeader(header); err != nil {
+			return nil, err
+		}
+	}
+	if timings != nil {
+		timings.Structural = lap(&mark)
+	}
+
+	// FR-014: Use golang-jwt library for parsing and signature validation
 	// FR-010, FR-022: Validate algorithm is exactly RS256
-	// FR-016: Validate exp claim is present and not expired (no clock skew)
-	// FR-017: Validate nbf if present (no clock skew)
-	// FR-018: Validate iat if present (no clock skew)
+	// Time claims are validated separately by validateTimeClaims, since the library
+	// applies a single symmetric leeway to all of them
 	parser := jwt.NewParser(
 		jwt.WithValidMethods([]string{AlgorithmRS256}),
-		jwt.WithExpirationRequired(),
+		jwt.WithoutClaimsValidation(),
 	)
 
 	claims := jwt.MapClaims{}
 	var keyID uuid.UUID
+	var checkedAlg string
+	var verifiedKey *rsa.PublicKey
 	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if timings != nil {
+			timings.Parse = lap(&mark)
+		}
+
+		// Record the algorithm the parser accepted, so it can be re-checked after verification
+		checkedAlg = token.Method.Alg()
+
 		// FR-027: Validate key ID is present and properly formatted
 		var extractErr error
-		keyID, extractErr = extractKeyIDFromHeader(token.Header)
+		keyID, extractErr = extractKeyID(token.Header, claims, config.KeyIDClaim)
 		if extractErr != nil {
 			return nil, extractErr
 		}
@@ -164,11 +952,35 @@ func Verify(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*Veri
 			if _, ok := err.(*japikeyerrors.KeyNotFoundError); ok {
 				return nil, err
 			}
+			// An unavailable key source is not an unknown key; keep it distinguishable
+			if outageErr := keySourceOutage(err); outageErr != nil {
+				return nil, outageErr
+			}
 			return nil, japikeyerrors.NewKeyNotFoundError("failed to retrieve public key")
 		}
+		// A nil key would otherwise surface as an opaque signature failure
+		if publicKey == nil {
+			return nil, japikeyerrors.NewKeyRetrievalError("key callback returned no public key")
+		}
 
+		if err := checkKeyThumbprint(token.Header, publicKey, config.RequireKeyThumbprint); err != nil {
+			return nil, err
+		}
+		if config.KeyIDIsThumbprint {
+			if err := checkThumbprintKeyID(keyID, publicKey); err != nil {
+				return nil, err
+			}
+		}
+
+		if timings != nil {
+			timings.KeyLookup = lap(&mark)
+		}
+		verifiedKey = publicKey
 		return publicKey, nil
 	})
+	if timings != nil {
+		timings.Signature = lap(&mark)
+	}
 
 	if err != nil {
 		// FR-028: Prevent information leakage - map library errors to generic messages
@@ -176,13 +988,22 @@ func Verify(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*Veri
 			return nil, japikeyerrors.NewTokenExpiredError("token has expired")
 		}
 		if errors.Is(err, jwt.ErrTokenNotValidYet) {
-			return nil, japikeyerrors.NewValidationError("token is not yet valid")
+			return nil, japikeyerrors.NewNotBeforeError("token is not yet valid")
 		}
 		if errors.Is(err, jwt.ErrTokenMalformed) {
 			return nil, japikeyerrors.NewValidationError("token is malformed")
 		}
+		// Errors returned from the key callback are wrapped by the library, so unwrap them
+		var keyNotFoundErr *japikeyerrors.KeyNotFoundError
+		if errors.As(err, &keyNotFoundErr) {
+			return nil, keyNotFoundErr
+		}
+		if outageErr := keySourceOutage(err); outageErr != nil {
+			return nil, outageErr
+		}
 		// Check if it's a validation error from our custom validation
-		if validationErr, ok := err.(*japikeyerrors.ValidationError); ok {
+		var validationErr *japikeyerrors.ValidationError
+		if errors.As(err, &validationErr) {
 			return nil, validationErr
 		}
 		return nil, japikeyerrors.NewValidationError("signature verification failed")
@@ -192,15 +1013,312 @@ func Verify(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*Veri
 		return nil, japikeyerrors.NewValidationError("token signature is invalid")
 	}
 
+	algorithm, err := checkAlgorithm(token, checkedAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateClaimDepth(claims, config.MaxClaimDepth); err != nil {
+		return nil, err
+	}
+
+	// FR-016: Validate exp claim is present and not expired (strict unless Leeway is set)
+	// FR-017: Validate nbf if present
+	// FR-018: Validate iat if present
+	if err := validateTimeClaims(claims, config, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// Validate JAPIKey-specific requirements
-	if err := validateJAPIKeyClaims(claims, config.BaseIssuerURL, keyID); err != nil {
+	if err := validateJAPIKeyClaims(claims, config, keyID); err != nil {
+		return nil, err
+	}
+
+	if config.RequireSubject {
+		if err := validateSubject(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.SingleAudienceOnly {
+		if err := validateSingleAudience(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateForwardReservedClaims(claims, config.ForwardReservedClaims); err != nil {
+		return nil, err
+	}
+
+	if err := validateScopes(claims, config.RequiredScopes); err != nil {
+		return nil, err
+	}
+
+	if config.KeyActivation != nil {
+		if err := checkKeyActivation(keyID, config, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	confirmation, err := validateConfirmation(claims, config.ConfirmationCheck)
+	if err != nil {
 		return nil, err
 	}
 
+	if timings != nil {
+		timings.Claims = lap(&mark)
+		timings.Total = mark.Sub(started)
+	}
+
 	// Return the validated claims (preserving all custom claims)
 	result := &VerificationResult{
-		Claims: claims,
-		KeyID:  keyID,
+		Claims:       claims,
+		KeyID:        keyID,
+		Confirmation: confirmation,
+		Algorithm:    algorithm,
+		PublicKey:    verifiedKey,
+		Timings:      timings,
+	}
+
+	if config.OnVerified != nil {
+		if err := config.OnVerified(result); err != nil {
+			if validationErr, ok := err.(*japikeyerrors.ValidationError); ok {
+				return nil, validationErr
+			}
+			return nil, japikeyerrors.NewValidationError("token rejected by verification hook")
+		}
+	}
+
+	for _, claim := range config.RedactClaims {
+		delete(result.Claims, claim)
+	}
+
+	return result, nil
+}
+
+// lap returns the time elapsed since mark and resets mark to now.
+func lap(mark *time.Time) time.Duration {
+	now := time.Now()
+	elapsed := now.Sub(*mark)
+	*mark = now
+	return elapsed
+}
+
+// VerifyWithSet is Verify with a callback returning a JWKS; the key matching the token's kid is
+// selected from the set. A set without that kid, or no set at all, maps to KeyNotFoundError.
+func VerifyWithSet(tokenString string, config VerifyConfig, setFunc JWKSetCallback) (*VerificationResult, error) {
+	return Verify(tokenString, config, func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		keySet, err := setFunc(keyID)
+		if err != nil {
+			return nil, err
+		}
+		if keySet == nil {
+			return nil, japikeyerrors.NewKeyNotFoundError("key ID not found in JWKS")
+		}
+		return keySet.GetPublicKey(keyID)
+	})
+}
+
+// VerifyWithCandidates is Verify with a callback that may return several candidate keys. The
+// candidates are tried in a deterministic order, so the outcome never depends on the order the
+// callback returns them in: first those whose KeyID matches the token's kid, then the rest; within
+// each group the most recently created first, with unknown creation times last; ties keep the
+// callback's order. The first candidate that verifies the token wins. If none does, the error from
+// the first candidate is returned; no candidates maps to KeyNotFoundError.
+func VerifyWithCandidates(tokenString string, config VerifyConfig, candidatesFunc JWKCandidatesCallback) (*VerificationResult, error) {
+	// The candidates are fetched once, through the first Verify call, and then tried in order
+	var candidates []KeyCandidate
+	var fetchErr error
+	fetched := false
+	next := 0
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		if !fetched {
+			fetched = true
+			candidates, fetchErr = candidatesFunc(keyID)
+			// Cloned so that ordering never reorders the callback's own slice
+			candidates = slices.Clone(candidates)
+			orderCandidates(candidates, keyID)
+		}
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		if next >= len(candidates) {
+			return nil, japikeyerrors.NewKeyNotFoundError("no candidate keys for key ID")
+		}
+		candidate := candidates[next]
+		next++
+		return candidate.PublicKey, nil
+	}
+
+	result, firstErr := Verify(tokenString, config, keyFunc)
+	for firstErr != nil && next > 0 && next < len(candidates) {
+		if result, err := Verify(tokenString, config, keyFunc); err == nil {
+			return result, nil
+		}
+	}
+	return result, firstErr
+}
+
+// VerifyWithKeyHistory is Verify with a callback returning every key material the token's kid
+// has had, current first. The first key whose signature check passes is used for the rest of
+// verification, so a token signed before an in-place rotation keeps verifying during the overlap.
+// If no key matches, the error is a ValidationError reporting a failed signature; an empty
+// history maps to KeyNotFoundError.
+//
+// Distinct kids per key, with Verify and a plain JWKCallback, are strongly preferred: reusing a
+// kid across key material defeats caching and key pinning by kid. This is only a compatibility
+// accommodation for issuers that do so.
+func VerifyWithKeyHistory(tokenString string, config VerifyConfig, historyFunc JWKHistoryCallback) (*VerificationResult, error) {
+	return Verify(tokenString, config, func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		history, err := historyFunc(keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		var fallback *rsa.PublicKey
+		for _, publicKey := range history {
+			if publicKey == nil {
+				continue
+			}
+			if VerifySignature(tokenString, publicKey, nil) == nil {
+				return publicKey, nil
+			}
+			if fallback == nil {
+				fallback = publicKey
+			}
+		}
+		if fallback == nil {
+			return nil, japikeyerrors.NewKeyNotFoundError("no keys in history for key ID")
+		}
+		// No key matches: let Verify report the failed signature against the current key
+		return fallback, nil
+	})
+}
+
+// orderCandidates sorts candidates for VerifyWithCandidates: kid matches first, then newest first.
+func orderCandidates(candidates []KeyCandidate, keyID uuid.UUID) {
+	slices.SortStableFunc(candidates, func(a, b KeyCandidate) int {
+		if aMatch, bMatch := a.KeyID == keyID, b.KeyID == keyID; aMatch != bMatch {
+			if aMatch {
+				return -1
+			}
+			return 1
+		}
+		if a.CreatedAt.IsZero() != b.CreatedAt.IsZero() {
+			if b.CreatedAt.IsZero() {
+				return -1
+			}
+			return 1
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+}
+
+// checkKeyThumbprint compares the jkt header, if present, with the thumbprint of publicKey.
+func checkKeyThumbprint(header map[string]interface{}, publicKey *rsa.PublicKey, required bool) error {
+	thumbprintRaw, ok := header[KeyThumbprintHeader]
+	if !ok {
+		if required {
+			return japikeyerrors.NewValidationError("token missing key thumbprint header")
+		}
+		return nil
+	}
+
+	thumbprint, ok := thumbprintRaw.(string)
+	if !ok || thumbprint == "" {
+		return japikeyerrors.NewValidationError("token key thumbprint header is invalid")
+	}
+
+	expected, err := jwks.Thumbprint(publicKey)
+	if err != nil {
+		return japikeyerrors.NewValidationError("failed to compute key thumbprint")
+	}
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(expected)) != 1 {
+		return japikeyerrors.NewValidationError("token key thumbprint does not match the retrieved key")
+	}
+
+	return nil
+}
+
+// keySourceOutage returns the FetchError, DatabaseTimeoutError or DatabaseUnavailableError in
+// err's chain, or nil. These report that the key source could not be reached, not that the key
+// is unknown, so Verify returns them unchanged instead of as a KeyNotFoundError.
+func keySourceOutage(err error) error {
+	var fetchErr *japikeyerrors.FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr
+	}
+	var timeoutErr *japikeyerrors.DatabaseTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr
+	}
+	var unavailableErr *japikeyerrors.DatabaseUnavailableError
+	if errors.As(err, &unavailableErr) {
+		return unavailableErr
+	}
+	return nil
+}
+
+// checkKeyActivation asserts that the signing key for keyID is active at now, within Leeway.
+func checkKeyActivation(keyID uuid.UUID, config VerifyConfig, now time.Time) error {
+	activeFrom, err := config.KeyActivation(keyID)
+	if err != nil {
+		if validationErr, ok := err.(*japikeyerrors.ValidationError); ok {
+			return validationErr
+		}
+		return japikeyerrors.NewValidationError("failed to check signing key activation")
+	}
+	if !activeFrom.IsZero() && now.Add(max(config.Leeway, 0)).Before(activeFrom) {
+		return japikeyerrors.NewValidationError("signing key is not yet active")
+	}
+	return nil
+}
+
+// checkThumbprintKeyID asserts that keyID is the key ID derived from the thumbprint of publicKey.
+func checkThumbprintKeyID(keyID uuid.UUID, publicKey *rsa.PublicKey) error {
+	expected, err := jwks.ThumbprintKeyID(publicKey)
+	if err != nil {
+		return japikeyerrors.NewValidationError("failed to compute key thumbprint")
+	}
+	if keyID != expected {
+		return japikeyerrors.NewValidationError("token key ID is not the thumbprint of the retrieved key")
+	}
+	return nil
+}
+
+// checkAlgorithm asserts that the token's header alg, the method that verified the signature,
+// and the algorithm accepted when the key was looked up (checkedAlg) are all RS256, so that the
+// claimed and actual algorithm can never drift apart. It returns the verified algorithm.
+func checkAlgorithm(token *jwt.Token, checkedAlg string) (string, error) {
+	if token.Method == nil {
+		return "", japikeyerrors.NewValidationError("token signing method is missing")
+	}
+	methodAlg := token.Method.Alg()
+	headerAlg, _ := token.Header["alg"].(string)
+
+	if methodAlg != AlgorithmRS256 || headerAlg != methodAlg || checkedAlg != methodAlg {
+		return "", japikeyerrors.NewValidationError("token algorithm does not match the verified signing method")
+	}
+
+	return methodAlg, nil
+}
+
+// VerifyForSubject verifies the token like Verify and additionally requires its sub claim to equal
+// expectedSubject, returning a ValidationError coded SubjectMismatchError otherwise.
+// If ctx is already done, its error is returned without verifying.
+func VerifyForSubject(ctx context.Context, tokenString string, expectedSubject string, config VerifyConfig, keyFunc JWKCallback) (*VerificationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := Verify(tokenString, config, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := result.Claims.GetSubject()
+	if err != nil || subject == "" || subject != expectedSubject {
+		return nil, japikeyerrors.NewSubjectMismatchError("token subject does not match the expected subject")
 	}
 
 	return result, nil
@@ -210,29 +1328,182 @@ func Verify(tokenString string, config VerifyConfig, keyFunc JWKCallback) (*Veri
 // It decodes the token without verification and validates version, issuer format, and kid matching.
 // Based on the JavaScript implementation: https://github.com/susu-dot-dev/japikey_js/blob/main/packages/authenticate/src/index.ts
 func ShouldVerify(tokenString string, baseIssuer string) bool {
-	// FR-020: Check token size
-	if len(tokenString) > MaxTokenSize {
+	header, claims, err := ParseClaimsUnverified(tokenString)
+	if err != nil {
+		return false
+	}
+
+	// Validate kid is present and is a valid UUID
+	keyID, err := extractKeyIDFromHeader(header)
+	if err != nil {
 		return false
 	}
 
+	// Validate JAPIKey-specific requirements (version and issuer)
+	if err := validateJAPIKeyClaims(claims, VerifyConfig{BaseIssuerURL: baseIssuer}, keyID); err != nil {
+		return false
+	}
+
+	return true
+}
+
+// ParseClaimsUnverified decodes the header and claims of a token WITHOUT verifying its signature
+// or validating any claim.
+//
+// WARNING: the result is UNVERIFIED and can be forged by anyone. It must never be used for
+// authorization decisions; use it only for tooling, logging, or routing (e.g. choosing which
+// JWKS to fetch) before calling Verify.
+//
+// The token size and segment checks performed by Verify still apply.
+func ParseClaimsUnverified(tokenString string) (map[string]interface{}, map[string]interface{}, error) {
+	// FR-020: Check token size
+	if err := checkTokenSize(tokenString); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkTokenSegments(tokenString); err != nil {
+		return nil, nil, err
+	}
+
 	// Decode token without verification (similar to jose.decodeJwt and jose.decodeProtectedHeader)
 	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
 	claims := jwt.MapClaims{}
 	token, _, err := parser.ParseUnverified(tokenString, claims)
 	if err != nil {
-		return false
+		return nil, nil, japikeyerrors.NewValidationError("token is malformed")
 	}
 
-	// Validate kid is present and is a valid UUID
-	keyID, err := extractKeyIDFromHeader(token.Header)
+	return token.Header, claims, nil
+}
+
+// PeekKeyID decodes only the token header and returns its kid, WITHOUT verifying the token.
+//
+// WARNING: the result is UNVERIFIED. It is meant for logging and correlating failures to
+// specific keys, e.g. on the failure path of Verify, and must never be used for authorization.
+func PeekKeyID(tokenString string) (uuid.UUID, error) {
+	if err := checkTokenSize(tokenString); err != nil {
+		return uuid.Nil, err
+	}
+
+	header, err := decodeHeader(tokenString)
 	if err != nil {
-		return false
+		return uuid.Nil, err
 	}
 
-	// Validate JAPIKey-specific requirements (version and issuer)
-	if err := validateJAPIKeyClaims(claims, baseIssuer, keyID); err != nil {
-		return false
+	return extractKeyIDFromHeader(header)
+}
+
+// decodeHeader decodes the JOSE header of a token without looking at the rest of it.
+func decodeHeader(tokenString string) (map[string]interface{}, error) {
+	headerSegment, _, found := strings.Cut(tokenString, ".")
+	if !found || headerSegment == "" {
+		return nil, japikeyerrors.NewValidationError("token is malformed")
 	}
 
-	return true
+	headerJSON, err := base64.RawURLEncoding.DecodeString(headerSegment)
+	if err != nil {
+		return nil, japikeyerrors.NewValidationError("token is malformed")
+	}
+
+	var header map[string]interface{}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, japikeyerrors.NewValidationError("token is malformed")
+	}
+
+	return header, nil
+}
+
+// checkTypeHeader requires the typ header to declare a JWT.
+func checkTypeHeader(header map[string]interface{}) error {
+	typ, ok := header[TypeHeader].(string)
+	if !ok {
+		return japikeyerrors.NewHeaderValidationError("token missing type header")
+	}
+	if !strings.EqualFold(typ, TokenType) {
+		return japikeyerrors.NewHeaderValidationError("token type header must be " + TokenType)
+	}
+	return nil
+}
+
+// checkRequiredType requires the typ header to be exactly required.
+func checkRequiredType(header map[string]interface{}, required string) error {
+	typ, ok := header[TypeHeader].(string)
+	if !ok {
+		return japikeyerrors.NewHeaderValidationError("token missing type header")
+	}
+	if typ != required {
+		return japikeyerrors.NewHeaderValidationError("token type header must be " + required)
+	}
+	return nil
+}
+
+// checkPayloadEncoding rejects RFC 7797 unencoded payloads. JAPIKeys always use the default
+// base64url-encoded payload, so a b64 header other than true means the token is not a JAPIKey,
+// and rejecting it outright avoids misinterpreting the payload.
+func checkPayloadEncoding(header map[string]interface{}) error {
+	b64Raw, ok := header["b64"]
+	if !ok {
+		return nil
+	}
+	if b64, ok := b64Raw.(bool); !ok || !b64 {
+		return japikeyerrors.NewHeaderValidationError("tokens with unencoded payloads (b64=false) are not supported")
+	}
+	return nil
+}
+
+// VerifySignature checks only the token's signature against publicKey, pinning the signing
+// algorithm to allowedAlgs (RS256 when empty). Each allowed algorithm must be an RSA method
+// (RS* or PS*), so a token can never substitute HMAC or "none".
+//
+// WARNING: NO claims are validated — not exp, nbf, iss, aud, ver, or kid. A nil error only means
+// the token was signed by the holder of the private key, not that it is a valid JAPIKey. Use
+// Verify for authorization; this is a building block for custom flows that validate claims
+// themselves.
+//
+// The token size and segment checks performed by Verify still apply.
+func VerifySignature(tokenString string, publicKey *rsa.PublicKey, allowedAlgs []string) error {
+	if publicKey == nil {
+		return japikeyerrors.NewValidationError("public key is required")
+	}
+
+	if len(allowedAlgs) == 0 {
+		allowedAlgs = []string{AlgorithmRS256}
+	}
+	for _, alg := range allowedAlgs {
+		switch jwt.GetSigningMethod(alg).(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		default:
+			return japikeyerrors.NewValidationError(fmt.Sprintf("unsupported signature algorithm: %s", alg))
+		}
+	}
+
+	if err := checkTokenSize(tokenString); err != nil {
+		return err
+	}
+
+	if err := checkTokenSegments(tokenString); err != nil {
+		return err
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(allowedAlgs),
+		jwt.WithoutClaimsValidation(),
+	)
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenMalformed) {
+			return japikeyerrors.NewValidationError("token is malformed")
+		}
+		return japikeyerrors.NewValidationError("signature verification failed")
+	}
+
+	// Re-check that the header alg matches the method that verified the signature
+	headerAlg, _ := token.Header["alg"].(string)
+	if !token.Valid || token.Method == nil || headerAlg != token.Method.Alg() || !slices.Contains(allowedAlgs, headerAlg) {
+		return japikeyerrors.NewValidationError("token signature is invalid")
+	}
+
+	return nil
 }
@@ -1,9 +1,14 @@
 package japikey
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +16,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/jwks"
 )
 
 // createValidToken creates a valid JAPIKey token for testing purposes
@@ -1263,3 +1269,2688 @@ func TestVerifyIssuerNonStringClaim(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyEmptySegmentsReturnTokenFormatError(t *testing.T) {
+	tokenString, pubKey, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	parts := strings.Split(tokenString, ".")
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty signature", token: parts[0] + "." + parts[1] + "."},
+		{name: "empty payload", token: parts[0] + ".." + parts[2]},
+		{name: "empty header", token: "." + parts[1] + "." + parts[2]},
+		{name: "all segments empty", token: ".."},
+		{name: "undecodable signature", token: parts[0] + "." + parts[1] + ".A"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Verify(tc.token, config, mockKeyFunc(pubKey))
+			if err == nil {
+				t.Fatal("Expected error, got none")
+			}
+			if result != nil {
+				t.Error("Expected result to be nil")
+			}
+			if _, ok := err.(*errors.TokenFormatError); !ok {
+				t.Errorf("Expected TokenFormatError, got %T: %v", err, err)
+			}
+
+			if ShouldVerify(tc.token, "https://example.com/") {
+				t.Error("ShouldVerify returned true for token with invalid segments")
+			}
+		})
+	}
+}
+
+// createTokenWithClaims signs the given claims with a fresh key, using the standard test key ID.
+// Missing mandatory claims are filled in with valid defaults.
+func createTokenWithClaims(claims jwt.MapClaims) (string, *rsa.PublicKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defaults := jwt.MapClaims{
+		"sub": "test-user",
+		"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+		"aud": "test-audience",
+		"exp": time.Now().Add(1 * time.Hour).Unix(),
+		"ver": "japikey-v1",
+	}
+	for k, v := range defaults {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "123e4567-e89b-12d3-a456-426614174000"
+	tokenString, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, &privateKey.PublicKey, nil
+}
+
+func TestVerifyClockSkew(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name          string
+		claims        jwt.MapClaims
+		leeway        time.Duration
+		maxFutureSkew time.Duration
+		nbfLeeway     time.Duration
+		iatLeeway     time.Duration
+		expectedErr   error
+	}{
+		{
+			name:        "future iat rejected by default",
+			claims:      jwt.MapClaims{"iat": now.Add(30 * time.Second).Unix()},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "future nbf rejected by default",
+			claims:      jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:          "future iat within MaxFutureSkew accepted",
+			claims:        jwt.MapClaims{"iat": now.Add(30 * time.Second).Unix()},
+			maxFutureSkew: time.Minute,
+		},
+		{
+			name:          "future nbf within MaxFutureSkew accepted",
+			claims:        jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+			maxFutureSkew: time.Minute,
+		},
+		{
+			name:          "future iat beyond MaxFutureSkew rejected",
+			claims:        jwt.MapClaims{"iat": now.Add(5 * time.Minute).Unix()},
+			maxFutureSkew: time.Minute,
+			expectedErr:   &errors.ValidationError{},
+		},
+		{
+			name:          "MaxFutureSkew does not extend expiry",
+			claims:        jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()},
+			maxFutureSkew: time.Minute,
+			expectedErr:   &errors.TokenExpiredError{},
+		},
+		{
+			name:   "Leeway extends expiry",
+			claims: jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()},
+			leeway: time.Minute,
+		},
+		{
+			name:   "Leeway applies to nbf when MaxFutureSkew is unset",
+			claims: jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+			leeway: time.Minute,
+		},
+		{
+			name:          "MaxFutureSkew replaces Leeway for nbf",
+			claims:        jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+			leeway:        time.Minute,
+			maxFutureSkew: 10 * time.Second,
+			expectedErr:   &errors.ValidationError{},
+		},
+		{
+			name: "NbfLeeway accepts future nbf while iat stays strict",
+			claims: jwt.MapClaims{
+				"nbf": now.Add(30 * time.Second).Unix(),
+				"iat": now.Add(30 * time.Second).Unix(),
+			},
+			nbfLeeway:   time.Minute,
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:      "NbfLeeway accepts future nbf",
+			claims:    jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+			nbfLeeway: time.Minute,
+		},
+		{
+			name:      "IatLeeway accepts future iat",
+			claims:    jwt.MapClaims{"iat": now.Add(30 * time.Second).Unix()},
+			iatLeeway: time.Minute,
+		},
+		{
+			name:          "IatLeeway takes precedence over MaxFutureSkew",
+			claims:        jwt.MapClaims{"iat": now.Add(30 * time.Second).Unix()},
+			maxFutureSkew: time.Minute,
+			iatLeeway:     10 * time.Second,
+			expectedErr:   &errors.ValidationError{},
+		},
+		{
+			name:          "NbfLeeway does not affect iat, which falls back to MaxFutureSkew",
+			claims:        jwt.MapClaims{"iat": now.Add(30 * time.Second).Unix()},
+			maxFutureSkew: time.Minute,
+			nbfLeeway:     10 * time.Second,
+		},
+		{
+			name:        "NbfLeeway takes precedence over Leeway",
+			claims:      jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+			leeway:      time.Minute,
+			nbfLeeway:   10 * time.Second,
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "NbfLeeway does not extend expiry",
+			claims:      jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()},
+			nbfLeeway:   time.Minute,
+			iatLeeway:   time.Minute,
+			expectedErr: &errors.TokenExpiredError{},
+		},
+		{
+			name:        "null exp rejected",
+			claims:      jwt.MapClaims{"exp": nil},
+			expectedErr: &errors.ValidationError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(tc.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{
+				BaseIssuerURL: "https://example.com/",
+				Timeout:       5 * time.Second,
+				Leeway:        tc.leeway,
+				MaxFutureSkew: tc.maxFutureSkew,
+				NbfLeeway:     tc.nbfLeeway,
+				IatLeeway:     tc.iatLeeway,
+			}
+
+			result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.expectedErr == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				if result == nil {
+					t.Error("Expected result to not be nil")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Expected error, got none")
+			}
+			if fmt.Sprintf("%T", err) != fmt.Sprintf("%T", tc.expectedErr) {
+				t.Errorf("Expected %T, got %T: %v", tc.expectedErr, err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequiredScopes(t *testing.T) {
+	testCases := []struct {
+		name           string
+		claims         jwt.MapClaims
+		requiredScopes []string
+		expectedCode   string
+	}{
+		{
+			name:   "no required scopes",
+			claims: jwt.MapClaims{},
+		},
+		{
+			name:           "scope string grants all",
+			claims:         jwt.MapClaims{"scope": "read write admin"},
+			requiredScopes: []string{"read", "write"},
+		},
+		{
+			name:           "permissions array grants all",
+			claims:         jwt.MapClaims{"permissions": []string{"read", "write"}},
+			requiredScopes: []string{"write", "read"},
+		},
+		{
+			name:           "scope and permissions combined",
+			claims:         jwt.MapClaims{"scope": "read", "permissions": []string{"write"}},
+			requiredScopes: []string{"read", "write"},
+		},
+		{
+			name:           "missing scope",
+			claims:         jwt.MapClaims{"scope": "read"},
+			requiredScopes: []string{"read", "write"},
+			expectedCode:   "ScopeError",
+		},
+		{
+			name:           "no scope claims",
+			claims:         jwt.MapClaims{},
+			requiredScopes: []string{"read"},
+			expectedCode:   "ScopeError",
+		},
+		{
+			name:           "scope substring does not match",
+			claims:         jwt.MapClaims{"scope": "readonly"},
+			requiredScopes: []string{"read"},
+			expectedCode:   "ScopeError",
+		},
+		{
+			name:           "non-string scope",
+			claims:         jwt.MapClaims{"scope": 42},
+			requiredScopes: []string{"read"},
+			expectedCode:   "ValidationError",
+		},
+		{
+			name:           "non-string permission",
+			claims:         jwt.MapClaims{"permissions": []interface{}{"read", 1}},
+			requiredScopes: []string{"read"},
+			expectedCode:   "ValidationError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(tc.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{
+				BaseIssuerURL:  "https://example.com/",
+				Timeout:        5 * time.Second,
+				RequiredScopes: tc.requiredScopes,
+			}
+
+			result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.expectedCode == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				if result == nil {
+					t.Error("Expected result to not be nil")
+				}
+				return
+			}
+
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T", err)
+			}
+			if validationErr.Code != tc.expectedCode {
+				t.Errorf("Expected code %s, got %s", tc.expectedCode, validationErr.Code)
+			}
+		})
+	}
+}
+
+func TestVerifyNilKeyFromCallbackReturnsKeyRetrievalError(t *testing.T) {
+	tokenString, _, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	nilKeyCallback := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return nil, nil
+	}
+
+	result, err := Verify(tokenString, config, nilKeyCallback)
+	if result != nil {
+		t.Error("Expected result to be nil when callback returns no key")
+	}
+
+	keyNotFoundErr, ok := err.(*errors.KeyNotFoundError)
+	if !ok {
+		t.Fatalf("Expected KeyNotFoundError, got %T: %v", err, err)
+	}
+	if keyNotFoundErr.Code != "KeyRetrievalError" {
+		t.Errorf("Expected code KeyRetrievalError, got %s", keyNotFoundErr.Code)
+	}
+}
+
+func TestVerifyCallbackKeyNotFoundErrorIsPreserved(t *testing.T) {
+	tokenString, _, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	notFoundCallback := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return nil, errors.NewKeyNotFoundError("no such key")
+	}
+
+	_, err = Verify(tokenString, config, notFoundCallback)
+	keyNotFoundErr, ok := err.(*errors.KeyNotFoundError)
+	if !ok {
+		t.Fatalf("Expected KeyNotFoundError, got %T: %v", err, err)
+	}
+	if keyNotFoundErr.Code != "KeyNotFoundError" {
+		t.Errorf("Expected code KeyNotFoundError, got %s", keyNotFoundErr.Code)
+	}
+}
+
+func TestVerifyIssuerNormalizeURL(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	testCases := []struct {
+		name       string
+		issuer     string
+		baseURL    string
+		normalize  bool
+		shouldPass bool
+	}{
+		{
+			name:       "default https port rejected without normalization",
+			issuer:     "https://example.com:443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			shouldPass: false,
+		},
+		{
+			name:       "default https port in token accepted",
+			issuer:     "https://example.com:443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "default https port in base accepted",
+			issuer:     "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com:443/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "default http port accepted",
+			issuer:     "http://example.com:80/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "http://example.com/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "uppercase scheme accepted",
+			issuer:     "HTTPS://example.com/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "trailing-dot host rejected without normalization",
+			issuer:     "https://example.com./123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			shouldPass: false,
+		},
+		{
+			name:       "trailing-dot host in token accepted",
+			issuer:     "https://example.com./123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "trailing-dot host in base accepted",
+			issuer:     "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com./",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "trailing-dot host with default port accepted",
+			issuer:     "https://example.com.:443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "only one trailing dot stripped",
+			issuer:     "https://example.com../123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: false,
+		},
+		{
+			name:       "mixed-case host rejected without normalization",
+			issuer:     "https://Example.COM/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			shouldPass: false,
+		},
+		{
+			name:       "mixed-case host accepted",
+			issuer:     "https://Example.COM/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "mixed-case trailing-dot host with non-default port accepted",
+			issuer:     "https://API.Example.com.:8443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://api.example.com:8443/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "IPv6 host with default port accepted",
+			issuer:     "https://[::1]:443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://[::1]/",
+			normalize:  true,
+			shouldPass: true,
+		},
+		{
+			name:       "non-default port rejected",
+			issuer:     "https://example.com:8443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: false,
+		},
+		{
+			name:       "https default port on http scheme rejected",
+			issuer:     "http://example.com:443/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "http://example.com/",
+			normalize:  true,
+			shouldPass: false,
+		},
+		{
+			name:       "scheme mismatch rejected",
+			issuer:     "http://example.com/123e4567-e89b-12d3-a456-426614174000",
+			baseURL:    "https://example.com/",
+			normalize:  true,
+			shouldPass: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithIssuer(tc.issuer, keyID)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{
+				BaseIssuerURL:      tc.baseURL,
+				Timeout:            5 * time.Second,
+				NormalizeIssuerURL: tc.normalize,
+			}
+
+			result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.shouldPass {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				if result == nil {
+					t.Error("Expected result to not be nil")
+				}
+			} else {
+				if err == nil {
+					t.Error("Expected error, got none")
+				}
+				if result != nil {
+					t.Error("Expected result to be nil")
+				}
+			}
+		})
+	}
+}
+
+func TestParseClaimsUnverified(t *testing.T) {
+	tokenString, _, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	header, claims, err := ParseClaimsUnverified(tokenString)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if header["kid"] != keyID.String() {
+		t.Errorf("Expected kid %s, got %v", keyID, header["kid"])
+	}
+	if header["alg"] != AlgorithmRS256 {
+		t.Errorf("Expected alg %s, got %v", AlgorithmRS256, header["alg"])
+	}
+	if claims["sub"] != "test-user" {
+		t.Errorf("Expected sub test-user, got %v", claims["sub"])
+	}
+
+	// The signature is not checked, so a tampered signature still parses
+	parts := strings.Split(tokenString, ".")
+	tampered := parts[0] + "." + parts[1] + ".AAAA"
+	if _, _, err := ParseClaimsUnverified(tampered); err != nil {
+		t.Errorf("Expected tampered signature to parse without verification, got: %v", err)
+	}
+
+	if _, _, err := ParseClaimsUnverified("header.payload"); err == nil {
+		t.Error("Expected error for malformed token")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError, got %T", err)
+	}
+
+	if _, _, err := ParseClaimsUnverified(parts[0] + "." + parts[1] + "."); err == nil {
+		t.Error("Expected error for empty signature")
+	} else if _, ok := err.(*errors.TokenFormatError); !ok {
+		t.Errorf("Expected TokenFormatError, got %T", err)
+	}
+}
+
+func TestVerifyMaxClaimDepth(t *testing.T) {
+	// nested builds a value that, as a claim, gives the claims object the requested depth
+	nested := func(depth int) interface{} {
+		var value interface{} = "leaf"
+		for i := 1; i < depth; i++ {
+			value = map[string]interface{}{"child": value}
+		}
+		return value
+	}
+
+	testCases := []struct {
+		name          string
+		claims        jwt.MapClaims
+		maxClaimDepth int
+		shouldPass    bool
+	}{
+		{
+			name:       "one-level nested map within default",
+			claims:     jwt.MapClaims{"metadata": map[string]interface{}{"key": "value"}},
+			shouldPass: true,
+		},
+		{
+			name:       "depth equal to default accepted",
+			claims:     jwt.MapClaims{"deep": nested(DefaultMaxClaimDepth)},
+			shouldPass: true,
+		},
+		{
+			name:       "depth beyond default rejected",
+			claims:     jwt.MapClaims{"deep": nested(DefaultMaxClaimDepth + 1)},
+			shouldPass: false,
+		},
+		{
+			name:          "arrays count toward depth",
+			claims:        jwt.MapClaims{"list": []interface{}{[]interface{}{"a"}}},
+			maxClaimDepth: 2,
+			shouldPass:    false,
+		},
+		{
+			name:          "custom limit accepted",
+			claims:        jwt.MapClaims{"list": []string{"a"}},
+			maxClaimDepth: 2,
+			shouldPass:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(tc.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{
+				BaseIssuerURL: "https://example.com/",
+				Timeout:       5 * time.Second,
+				MaxClaimDepth: tc.maxClaimDepth,
+			}
+
+			result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.shouldPass {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				if result == nil {
+					t.Error("Expected result to not be nil")
+				}
+				return
+			}
+
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyConfirmationClaim(t *testing.T) {
+	const thumbprint = "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"
+
+	expectJKT := func(expected string) ConfirmationCheck {
+		return func(cnf *Confirmation) error {
+			if cnf == nil || cnf.JKT != expected {
+				return fmt.Errorf("thumbprint mismatch")
+			}
+			return nil
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		claims       jwt.MapClaims
+		check        ConfirmationCheck
+		expectedJKT  string
+		expectNilCnf bool
+		shouldPass   bool
+	}{
+		{
+			name:         "no cnf and no check",
+			claims:       jwt.MapClaims{},
+			expectNilCnf: true,
+			shouldPass:   true,
+		},
+		{
+			name:        "cnf exposed without check",
+			claims:      jwt.MapClaims{"cnf": map[string]interface{}{"jkt": thumbprint}},
+			expectedJKT: thumbprint,
+			shouldPass:  true,
+		},
+		{
+			name:        "matching thumbprint",
+			claims:      jwt.MapClaims{"cnf": map[string]interface{}{"jkt": thumbprint}},
+			check:       expectJKT(thumbprint),
+			expectedJKT: thumbprint,
+			shouldPass:  true,
+		},
+		{
+			name:   "mismatched thumbprint",
+			claims: jwt.MapClaims{"cnf": map[string]interface{}{"jkt": thumbprint}},
+			check:  expectJKT("other"),
+		},
+		{
+			name:   "missing cnf with check",
+			claims: jwt.MapClaims{},
+			check:  expectJKT(thumbprint),
+		},
+		{
+			name:         "custom cnf ignored without check",
+			claims:       jwt.MapClaims{"cnf": "jkt"},
+			expectNilCnf: true,
+			shouldPass:   true,
+		},
+		{
+			name:         "cnf with non-string jkt ignored without check",
+			claims:       jwt.MapClaims{"cnf": map[string]interface{}{"jkt": 42}},
+			expectNilCnf: true,
+			shouldPass:   true,
+		},
+		{
+			name:   "cnf not an object with check",
+			claims: jwt.MapClaims{"cnf": "jkt"},
+			check:  expectJKT(thumbprint),
+		},
+		{
+			name:   "cnf with non-string jkt with check",
+			claims: jwt.MapClaims{"cnf": map[string]interface{}{"jkt": 42}},
+			check:  expectJKT(thumbprint),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(tc.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{
+				BaseIssuerURL:     "https://example.com/",
+				Timeout:           5 * time.Second,
+				ConfirmationCheck: tc.check,
+			}
+
+			result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if !tc.shouldPass {
+				if result != nil {
+					t.Error("Expected result to be nil")
+				}
+				if _, ok := err.(*errors.ValidationError); !ok {
+					t.Errorf("Expected ValidationError, got %T: %v", err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if tc.expectNilCnf {
+				if result.Confirmation != nil {
+					t.Errorf("Expected nil confirmation, got %+v", result.Confirmation)
+				}
+				return
+			}
+			if result.Confirmation == nil || result.Confirmation.JKT != tc.expectedJKT {
+				t.Errorf("Expected confirmation jkt %s, got %+v", tc.expectedJKT, result.Confirmation)
+			}
+		})
+	}
+}
+
+func TestVerifyForSubject(t *testing.T) {
+	tokenString, pubKey, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	t.Run("matching subject", func(t *testing.T) {
+		result, err := VerifyForSubject(context.Background(), tokenString, "test-user", config, mockKeyFunc(pubKey))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result == nil || result.Claims["sub"] != "test-user" {
+			t.Errorf("Expected result for test-user, got %+v", result)
+		}
+	})
+
+	t.Run("mismatched subject", func(t *testing.T) {
+		result, err := VerifyForSubject(context.Background(), tokenString, "other-user", config, mockKeyFunc(pubKey))
+		if result != nil {
+			t.Error("Expected result to be nil for mismatched subject")
+		}
+		validationErr, ok := err.(*errors.ValidationError)
+		if !ok {
+			t.Fatalf("Expected ValidationError, got %T", err)
+		}
+		if validationErr.Code != "SubjectMismatchError" {
+			t.Errorf("Expected code SubjectMismatchError, got %s", validationErr.Code)
+		}
+	})
+
+	t.Run("empty expected subject", func(t *testing.T) {
+		noSubToken, noSubPubKey, err := createTokenWithClaims(jwt.MapClaims{"sub": ""})
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		if _, err := VerifyForSubject(context.Background(), noSubToken, "", config, mockKeyFunc(noSubPubKey)); err == nil {
+			t.Error("Expected error when token and expected subject are both empty")
+		}
+	})
+
+	t.Run("verification failure takes precedence", func(t *testing.T) {
+		otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		_, err := VerifyForSubject(context.Background(), tokenString, "test-user", config, mockKeyFunc(&otherKey.PublicKey))
+		if validationErr, ok := err.(*errors.ValidationError); !ok || validationErr.Code == "SubjectMismatchError" {
+			t.Errorf("Expected signature ValidationError, got %T: %v", err, err)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := VerifyForSubject(ctx, tokenString, "test-user", config, mockKeyFunc(pubKey)); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestPeekKeyID(t *testing.T) {
+	tokenString, _, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	peeked, err := PeekKeyID(tokenString)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if peeked != keyID {
+		t.Errorf("Expected kid %s, got %s", keyID, peeked)
+	}
+
+	// Only the header is decoded, so a broken payload and signature don't matter
+	parts := strings.Split(tokenString, ".")
+	if peeked, err := PeekKeyID(parts[0] + ".garbage"); err != nil || peeked != keyID {
+		t.Errorf("Expected kid %s from header-only decode, got %s (err: %v)", keyID, peeked, err)
+	}
+
+	encode := func(header string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(header))
+	}
+
+	malformed := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no dots", "abc"},
+		{"empty header", ".payload.sig"},
+		{"invalid base64", "!!!.payload.sig"},
+		{"invalid JSON", encode("{not json") + ".payload.sig"},
+		{"JSON array", encode("[1,2]") + ".payload.sig"},
+		{"JSON null", encode("null") + ".payload.sig"},
+		{"missing kid", encode(`{"alg":"RS256"}`) + ".payload.sig"},
+		{"non-string kid", encode(`{"kid":123}`) + ".payload.sig"},
+		{"non-UUID kid", encode(`{"kid":"not-a-uuid"}`) + ".payload.sig"},
+		{"oversized", strings.Repeat("a", MaxTokenSize+1)},
+	}
+
+	for _, tc := range malformed {
+		t.Run(tc.name, func(t *testing.T) {
+			peeked, err := PeekKeyID(tc.token)
+			if err == nil {
+				t.Errorf("Expected error, got kid %s", peeked)
+			}
+			if peeked != uuid.Nil {
+				t.Errorf("Expected nil UUID on error, got %s", peeked)
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestVerifyBaseIssuerURLs(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+
+	testCases := []struct {
+		name       string
+		issuer     string
+		config     VerifyConfig
+		shouldPass bool
+	}{
+		{
+			name:       "matches allowlist entry",
+			issuer:     "https://b.example.com/123e4567-e89b-12d3-a456-426614174000",
+			config:     VerifyConfig{BaseIssuerURLs: []string{"https://a.example.com", "https://b.example.com/"}},
+			shouldPass: true,
+		},
+		{
+			name:       "matches BaseIssuerURL alongside allowlist",
+			issuer:     "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			config:     VerifyConfig{BaseIssuerURL: "https://example.com/", BaseIssuerURLs: []string{"https://a.example.com"}},
+			shouldPass: true,
+		},
+		{
+			name:   "not in allowlist",
+			issuer: "https://c.example.com/123e4567-e89b-12d3-a456-426614174000",
+			config: VerifyConfig{BaseIssuerURLs: []string{"https://a.example.com", "https://b.example.com"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithIssuer(tc.issuer, keyID)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			result, err := Verify(tokenString, tc.config, mockKeyFunc(pubKey))
+			if tc.shouldPass {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				if result == nil {
+					t.Error("Expected result to not be nil")
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+
+	t.Run("empty allowlist entries are not a base URL", func(t *testing.T) {
+		tokenString, pubKey, _, err := createValidToken()
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		_, err = Verify(tokenString, VerifyConfig{BaseIssuerURLs: []string{""}}, mockKeyFunc(pubKey))
+		if _, ok := err.(*errors.InternalError); !ok {
+			t.Errorf("Expected InternalError, got %T: %v", err, err)
+		}
+	})
+}
+
+func TestVerifyAlgorithmAndOnVerified(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return pubKey, nil
+	}
+
+	t.Run("result records algorithm", func(t *testing.T) {
+		var hookResult *VerificationResult
+		config := VerifyConfig{
+			BaseIssuerURL: "https://example.com",
+			OnVerified: func(result *VerificationResult) error {
+				hookResult = result
+				return nil
+			},
+		}
+
+		result, err := Verify(tokenString, config, keyFunc)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result.Algorithm != AlgorithmRS256 {
+			t.Errorf("Expected algorithm %s, got %q", AlgorithmRS256, result.Algorithm)
+		}
+		if hookResult != result {
+			t.Error("Expected OnVerified to be called with the verification result")
+		}
+	})
+
+	t.Run("hook error rejects token", func(t *testing.T) {
+		config := VerifyConfig{
+			BaseIssuerURL: "https://example.com",
+			OnVerified: func(result *VerificationResult) error {
+				return fmt.Errorf("internal detail")
+			},
+		}
+
+		result, err := Verify(tokenString, config, keyFunc)
+		if err == nil || result != nil {
+			t.Fatal("Expected token to be rejected by the hook")
+		}
+		validationErr, ok := err.(*errors.ValidationError)
+		if !ok {
+			t.Fatalf("Expected ValidationError, got %T", err)
+		}
+		if strings.Contains(validationErr.Message, "internal detail") {
+			t.Errorf("Expected hook error details not to leak, got %q", validationErr.Message)
+		}
+	})
+
+	t.Run("hook validation error preserved", func(t *testing.T) {
+		config := VerifyConfig{
+			BaseIssuerURL: "https://example.com",
+			OnVerified: func(result *VerificationResult) error {
+				return errors.NewValidationError("custom rejection")
+			},
+		}
+
+		_, err := Verify(tokenString, config, keyFunc)
+		validationErr, ok := err.(*errors.ValidationError)
+		if !ok {
+			t.Fatalf("Expected ValidationError, got %T", err)
+		}
+		if validationErr.Message != "custom rejection" {
+			t.Errorf("Expected custom message, got %q", validationErr.Message)
+		}
+	})
+}
+
+// Regression test: the alg recorded when the key was looked up must still match the header and
+// the verifying method afterwards, so a desynchronised algorithm is caught.
+func TestCheckAlgorithm_DetectsTampering(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	parse := func(t *testing.T) *jwt.Token {
+		t.Helper()
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			return pubKey, nil
+		}, jwt.WithValidMethods([]string{AlgorithmRS256}))
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		return token
+	}
+
+	token := parse(t)
+	if alg, err := checkAlgorithm(token, AlgorithmRS256); err != nil || alg != AlgorithmRS256 {
+		t.Fatalf("Expected untampered token to pass, got %q, %v", alg, err)
+	}
+
+	testCases := []struct {
+		name       string
+		tamper     func(token *jwt.Token)
+		checkedAlg string
+	}{
+		{
+			name:       "header alg changed after check",
+			tamper:     func(token *jwt.Token) { token.Header["alg"] = "none" },
+			checkedAlg: AlgorithmRS256,
+		},
+		{
+			name:       "header alg removed after check",
+			tamper:     func(token *jwt.Token) { delete(token.Header, "alg") },
+			checkedAlg: AlgorithmRS256,
+		},
+		{
+			name:       "method swapped after check",
+			tamper:     func(token *jwt.Token) { token.Method = jwt.SigningMethodRS512 },
+			checkedAlg: AlgorithmRS256,
+		},
+		{
+			name:       "method missing",
+			tamper:     func(token *jwt.Token) { token.Method = nil },
+			checkedAlg: AlgorithmRS256,
+		},
+		{
+			name:       "different algorithm checked",
+			tamper:     func(token *jwt.Token) {},
+			checkedAlg: "HS256",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := parse(t)
+			tc.tamper(token)
+
+			_, err := checkAlgorithm(token, tc.checkedAlg)
+			if err == nil {
+				t.Fatal("Expected tampered algorithm to be rejected")
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestValidateTimeClaims_NumericTypes(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour).Unix()
+	past := now.Add(-time.Hour).Unix()
+
+	testCases := []struct {
+		name        string
+		claims      jwt.MapClaims
+		expectedErr error
+	}{
+		{name: "float64", claims: jwt.MapClaims{"exp": float64(future), "iat": float64(past)}},
+		{name: "json.Number", claims: jwt.MapClaims{"exp": json.Number(strconv.FormatInt(future, 10)), "nbf": json.Number(strconv.FormatInt(past, 10))}},
+		{name: "fractional json.Number", claims: jwt.MapClaims{"exp": json.Number(strconv.FormatInt(future, 10) + ".5")}},
+		{name: "int", claims: jwt.MapClaims{"exp": int(future), "iat": int(past)}},
+		{name: "int64", claims: jwt.MapClaims{"exp": future, "nbf": past}},
+		{name: "expired json.Number", claims: jwt.MapClaims{"exp": json.Number(strconv.FormatInt(past, 10))}, expectedErr: &errors.TokenExpiredError{}},
+		{name: "future nbf json.Number", claims: jwt.MapClaims{"exp": future, "nbf": json.Number(strconv.FormatInt(future, 10))}, expectedErr: &errors.ValidationError{}},
+		{name: "invalid json.Number", claims: jwt.MapClaims{"exp": json.Number("soon")}, expectedErr: &errors.ValidationError{}},
+		{name: "string exp", claims: jwt.MapClaims{"exp": "1700000000"}, expectedErr: &errors.ValidationError{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTimeClaims(tc.claims, VerifyConfig{}, now)
+			switch tc.expectedErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			case *errors.TokenExpiredError:
+				if _, ok := err.(*errors.TokenExpiredError); !ok {
+					t.Errorf("Expected TokenExpiredError, got %T: %v", err, err)
+				}
+			case *errors.ValidationError:
+				if _, ok := err.(*errors.ValidationError); !ok {
+					t.Errorf("Expected ValidationError, got %T: %v", err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTimeClaims_TimestampRange(t *testing.T) {
+	now := time.Now()
+	year3000 := time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	past := now.Add(-time.Hour).Unix()
+
+	testCases := []struct {
+		name         string
+		claims       jwt.MapClaims
+		maxTimestamp int64
+		shouldPass   bool
+	}{
+		{name: "year 3000 exp", claims: jwt.MapClaims{"exp": float64(year3000)}, shouldPass: true},
+		{name: "exp at default maximum", claims: jwt.MapClaims{"exp": int64(DefaultMaxTimestamp)}, shouldPass: true},
+		{name: "exp past default maximum", claims: jwt.MapClaims{"exp": int64(DefaultMaxTimestamp + 1)}},
+		{name: "negative exp", claims: jwt.MapClaims{"exp": float64(-1)}},
+		{name: "overflowing exp", claims: jwt.MapClaims{"exp": 1e300}},
+		{name: "overflowing json.Number exp", claims: jwt.MapClaims{"exp": json.Number("99999999999999999999")}},
+		{name: "negative nbf", claims: jwt.MapClaims{"exp": float64(year3000), "nbf": float64(-100)}},
+		{name: "negative iat", claims: jwt.MapClaims{"exp": float64(year3000), "iat": float64(-100)}},
+		{name: "overflowing iat", claims: jwt.MapClaims{"exp": float64(year3000), "iat": -1e300}},
+		{name: "exp within custom maximum", claims: jwt.MapClaims{"exp": float64(year3000), "iat": float64(past)}, maxTimestamp: year3000, shouldPass: true},
+		{name: "exp past custom maximum", claims: jwt.MapClaims{"exp": float64(year3000 + 1)}, maxTimestamp: year3000},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTimeClaims(tc.claims, VerifyConfig{MaxTimestamp: tc.maxTimestamp}, now)
+			if tc.shouldPass {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyYear3000Expiration(t *testing.T) {
+	exp := time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"exp": exp})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	result, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com/"}, mockKeyFunc(pubKey))
+	if err != nil {
+		t.Fatalf("Expected year 3000 expiration to be accepted, got: %v", err)
+	}
+	if got, _ := result.Claims.GetExpirationTime(); got == nil || got.Unix() != exp {
+		t.Errorf("Expected exp %d, got %v", exp, got)
+	}
+}
+func TestVerifyMaxTokenAge(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name         string
+		claims       jwt.MapClaims
+		maxTokenAge  time.Duration
+		expectedCode string
+	}{
+		{
+			name:        "recent token accepted",
+			claims:      jwt.MapClaims{"iat": now.Add(-time.Minute).Unix()},
+			maxTokenAge: time.Hour,
+		},
+		{
+			name:         "old token rejected despite valid exp",
+			claims:       jwt.MapClaims{"iat": now.Add(-2 * time.Hour).Unix(), "exp": now.Add(24 * time.Hour).Unix()},
+			maxTokenAge:  time.Hour,
+			expectedCode: "TokenTooOldError",
+		},
+		{
+			name:         "missing iat rejected when configured",
+			claims:       jwt.MapClaims{},
+			maxTokenAge:  time.Hour,
+			expectedCode: "ValidationError",
+		},
+		{
+			name:   "old token accepted when unset",
+			claims: jwt.MapClaims{"iat": now.Add(-48 * time.Hour).Unix()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(tc.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{BaseIssuerURL: "https://example.com", MaxTokenAge: tc.maxTokenAge}
+			_, err = Verify(tokenString, config, func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+				return pubKey, nil
+			})
+
+			if tc.expectedCode == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != tc.expectedCode {
+				t.Errorf("Expected code %s, got %s", tc.expectedCode, validationErr.Code)
+			}
+		})
+	}
+}
+
+func TestVerifyWithJWKSKeyFunc(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	keySet, err := jwks.NewJWKS(pubKey, keyID)
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+
+	if _, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com"}, keySet.KeyFunc()); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	otherSet, err := jwks.NewJWKS(pubKey, uuid.New())
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	_, err = Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com"}, otherSet.KeyFunc())
+	if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T: %v", err, err)
+	}
+}
+
+func TestVerifyRejectsUnencodedPayloadHeader(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	keyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		return &privateKey.PublicKey, nil
+	}
+
+	signWithB64 := func(t *testing.T, b64 interface{}) string {
+		t.Helper()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub": "test-user",
+			"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			"aud": "test-audience",
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+			"ver": "japikey-v1",
+		})
+		token.Header["kid"] = "123e4567-e89b-12d3-a456-426614174000"
+		token.Header["b64"] = b64
+		token.Header["crit"] = []string{"b64"}
+		tokenString, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	for _, b64 := range []interface{}{false, "false", 0} {
+		t.Run(fmt.Sprintf("b64=%v", b64), func(t *testing.T) {
+			tokenString := signWithB64(t, b64)
+
+			_, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc)
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != "HeaderValidationError" {
+				t.Errorf("Expected HeaderValidationError code, got %s", validationErr.Code)
+			}
+			if Classify(tokenString) == KindJAPIKey {
+				t.Error("Expected token with unencoded payload not to classify as a JAPIKey")
+			}
+		})
+	}
+
+	t.Run("b64=true", func(t *testing.T) {
+		tokenString := signWithB64(t, true)
+		if _, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com"}, keyFunc); err != nil {
+			t.Errorf("Expected explicit b64=true to verify, got: %v", err)
+		}
+	})
+}
+
+func TestValidateTimeClaims_TolerateStringTimestamps(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name        string
+		claims      jwt.MapClaims
+		tolerate    bool
+		expectedErr error
+	}{
+		{
+			name:        "string exp rejected by default",
+			claims:      jwt.MapClaims{"exp": strconv.FormatInt(future.Unix(), 10)},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:     "Unix seconds string accepted",
+			claims:   jwt.MapClaims{"exp": strconv.FormatInt(future.Unix(), 10), "iat": strconv.FormatInt(past.Unix(), 10)},
+			tolerate: true,
+		},
+		{
+			name:     "RFC 3339 strings accepted",
+			claims:   jwt.MapClaims{"exp": future.Format(time.RFC3339), "nbf": past.Format(time.RFC3339)},
+			tolerate: true,
+		},
+		{
+			name:     "numeric timestamps still accepted",
+			claims:   jwt.MapClaims{"exp": float64(future.Unix())},
+			tolerate: true,
+		},
+		{
+			name:        "expired RFC 3339 exp rejected",
+			claims:      jwt.MapClaims{"exp": past.Format(time.RFC3339)},
+			tolerate:    true,
+			expectedErr: &errors.TokenExpiredError{},
+		},
+		{
+			name:        "future RFC 3339 nbf rejected",
+			claims:      jwt.MapClaims{"exp": future.Format(time.RFC3339), "nbf": future.Format(time.RFC3339)},
+			tolerate:    true,
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "unparseable string rejected",
+			claims:      jwt.MapClaims{"exp": "next tuesday"},
+			tolerate:    true,
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "NaN string rejected",
+			claims:      jwt.MapClaims{"exp": "NaN"},
+			tolerate:    true,
+			expectedErr: &errors.ValidationError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTimeClaims(tc.claims, VerifyConfig{TolerateStringTimestamps: tc.tolerate}, now)
+			switch tc.expectedErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			case *errors.TokenExpiredError:
+				if _, ok := err.(*errors.TokenExpiredError); !ok {
+					t.Errorf("Expected TokenExpiredError, got %T: %v", err, err)
+				}
+			case *errors.ValidationError:
+				if _, ok := err.(*errors.ValidationError); !ok {
+					t.Errorf("Expected ValidationError, got %T: %v", err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerify_CollectTimings(t *testing.T) {
+	tokenString, pubKey, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Timings != nil {
+		t.Error("Expected no timings when CollectTimings is disabled")
+	}
+
+	// A slow callback must show up as key lookup time
+	lookupDelay := 20 * time.Millisecond
+	slowKeyFunc := func(keyID uuid.UUID) (*rsa.PublicKey, error) {
+		time.Sleep(lookupDelay)
+		return pubKey, nil
+	}
+
+	config.CollectTimings = true
+	result, err = Verify(tokenString, config, slowKeyFunc)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	timings := result.Timings
+	if timings == nil {
+		t.Fatal("Expected timings when CollectTimings is enabled")
+	}
+
+	if timings.KeyLookup < lookupDelay {
+		t.Errorf("Expected key lookup of at least %v, got %v", lookupDelay, timings.KeyLookup)
+	}
+	if timings.Signature <= 0 {
+		t.Errorf("Expected signature verification time to be recorded, got %v", timings.Signature)
+	}
+	phases := timings.Structural + timings.Parse + timings.KeyLookup + timings.Signature + timings.Claims
+	if timings.Total != phases {
+		t.Errorf("Expected total %v to equal the sum of the phases %v", timings.Total, phases)
+	}
+}
+
+func TestVerify_FormatErrorCodes(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	const kid = "123e4567-e89b-12d3-a456-426614174000"
+
+	sign := func(kidHeader interface{}, issuer interface{}) string {
+		claims := jwt.MapClaims{
+			"sub": "test-user",
+			"aud": "test-audience",
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+			"ver": "japikey-v1",
+		}
+		if issuer != nil {
+			claims["iss"] = issuer
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		if kidHeader != nil {
+			token.Header["kid"] = kidHeader
+		}
+		tokenString, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		expectedCode string
+	}{
+		{"missing kid", sign(nil, "https://example.com/"+kid), "KeyIDFormatError"},
+		{"non-string kid", sign(42, "https://example.com/"+kid), "KeyIDFormatError"},
+		{"malformed kid", sign("not-a-uuid", "https://example.com/"+kid), "KeyIDFormatError"},
+		{"missing issuer", sign(kid, nil), "IssuerFormatError"},
+		{"non-string issuer", sign(kid, 42), "IssuerFormatError"},
+		{"malformed issuer key ID", sign(kid, "https://example.com/not-a-uuid"), "IssuerFormatError"},
+		{"issuer without path", sign(kid, "https://example.com"), "IssuerFormatError"},
+		{"issuer equal to base", sign(kid, "https://example.com/"), "IssuerFormatError"},
+		{"wrong issuer base", sign(kid, "https://other.example.com/"+kid), "ValidationError"},
+		{"issuer for another kid", sign(kid, "https://example.com/"+uuid.NewString()), "ValidationError"},
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(tt.token, config, mockKeyFunc(&privateKey.PublicKey))
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, got %s (%s)", tt.expectedCode, validationErr.Code, validationErr.Message)
+			}
+		})
+	}
+}
+
+func TestVerifyRequireHTTPSIssuer(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	testCases := []struct {
+		name       string
+		issuer     string
+		baseURL    string
+		require    bool
+		shouldPass bool
+	}{
+		{"https accepted", "https://example.com/" + keyID.String(), "https://example.com/", true, true},
+		{"uppercase https accepted", "HTTPS://example.com/" + keyID.String(), "HTTPS://example.com/", true, true},
+		{"http rejected", "http://example.com/" + keyID.String(), "http://example.com/", true, false},
+		{"schemeless rejected", "example.com/" + keyID.String(), "example.com/", true, false},
+		{"http accepted by default", "http://example.com/" + keyID.String(), "http://example.com/", false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithIssuer(tc.issuer, keyID)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{BaseIssuerURL: tc.baseURL, RequireHTTPSIssuer: tc.require}
+			_, err = Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.shouldPass {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if !strings.Contains(err.Error(), "must use https") {
+				t.Errorf("Expected https error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyKeyIDClaim(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	kid := "123e4567-e89b-12d3-a456-426614174000"
+	otherKid := uuid.NewString()
+
+	sign := func(headerKid, claimKid interface{}) string {
+		claims := jwt.MapClaims{
+			"sub": "test-user",
+			"iss": "https://example.com/" + kid,
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+			"ver": "japikey-v1",
+		}
+		if claimKid != nil {
+			claims["kid"] = claimKid
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		if headerKid != nil {
+			token.Header["kid"] = headerKid
+		}
+		tokenString, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		claimName    string
+		expectedCode string
+	}{
+		{"claim only", sign(nil, kid), "kid", ""},
+		{"header only", sign(kid, nil), "kid", ""},
+		{"header and claim agree", sign(kid, kid), "kid", ""},
+		{"header and claim disagree", sign(otherKid, kid), "kid", "KeyIDFormatError"},
+		{"claim disagrees with issuer", sign(nil, otherKid), "kid", "ValidationError"},
+		{"non-string claim", sign(nil, 42), "kid", "KeyIDFormatError"},
+		{"malformed claim", sign(nil, "not-a-uuid"), "kid", "KeyIDFormatError"},
+		{"neither present", sign(nil, nil), "kid", "KeyIDFormatError"},
+		{"claim ignored by default", sign(nil, kid), "", "KeyIDFormatError"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := VerifyConfig{BaseIssuerURL: "https://example.com/", KeyIDClaim: tt.claimName}
+			result, err := Verify(tt.token, config, mockKeyFunc(&privateKey.PublicKey))
+			if tt.expectedCode == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if result.KeyID.String() != kid {
+					t.Errorf("Expected key ID %s, got %s", kid, result.KeyID)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != tt.expectedCode {
+				t.Errorf("Expected code %s, got %s (%s)", tt.expectedCode, validationErr.Code, validationErr.Message)
+			}
+		})
+	}
+}
+
+func TestVerifyIssuerMissingKeyIDSegment(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	testCases := []struct {
+		name    string
+		issuer  string
+		baseURL string
+	}{
+		{"bare host", "https://example.com", "https://example.com/"},
+		{"bare host with trailing slash", "https://example.com/", "https://example.com/"},
+		{"bare host with port", "https://example.com:8443", "https://example.com:8443/"},
+		{"base with path", "https://example.com/keys", "https://example.com/keys/"},
+		{"base with path and trailing slash", "https://example.com/keys/", "https://example.com/keys"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithIssuer(tc.issuer, keyID)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			_, err = Verify(tokenString, VerifyConfig{BaseIssuerURL: tc.baseURL}, mockKeyFunc(pubKey))
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != "IssuerFormatError" {
+				t.Errorf("Expected code IssuerFormatError, got %s", validationErr.Code)
+			}
+			if !strings.Contains(validationErr.Message, "issuer missing key ID path segment") {
+				t.Errorf("Expected missing key ID segment message, got: %s", validationErr.Message)
+			}
+		})
+	}
+
+	// An issuer under a different base still has a key ID segment, so it is a plain mismatch
+	tokenString, pubKey, err := createTokenWithIssuer("https://other.example.com/"+keyID.String(), keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	_, err = Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com/"}, mockKeyFunc(pubKey))
+	if validationErr, ok := err.(*errors.ValidationError); !ok || validationErr.Code != "ValidationError" {
+		t.Errorf("Expected plain ValidationError, got %T: %v", err, err)
+	}
+}
+
+func TestVerify_DisableKidIssuerBinding(t *testing.T) {
+	testCases := []struct {
+		name        string
+		issuer      string
+		disable     bool
+		normalize   bool
+		expectValid bool
+	}{
+		{name: "kid-bound issuer accepted", issuer: "https://example.com/123e4567-e89b-12d3-a456-426614174000", disable: true, expectValid: true},
+		{name: "base issuer accepted", issuer: "https://example.com", disable: true, expectValid: true},
+		{name: "federated issuer path accepted", issuer: "https://example.com/tenants/acme", disable: true, expectValid: true},
+		{name: "federated issuer rejected by default", issuer: "https://example.com/tenants/acme", expectValid: false},
+		{name: "other host rejected", issuer: "https://other.example.com/tenants/acme", disable: true, expectValid: false},
+		{name: "host prefix rejected", issuer: "https://example.com.evil.test/tenants/acme", disable: true, expectValid: false},
+		{name: "normalized issuer accepted", issuer: "HTTPS://example.com:443/tenants/acme", disable: true, normalize: true, expectValid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"iss": tc.issuer})
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			config := VerifyConfig{
+				BaseIssuerURL:           "https://example.com/",
+				Timeout:                 5 * time.Second,
+				DisableKidIssuerBinding: tc.disable,
+				NormalizeIssuerURL:      tc.normalize,
+			}
+
+			_, err = Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.expectValid && err != nil {
+				t.Errorf("Expected token to verify, got: %v", err)
+			}
+			if !tc.expectValid {
+				if _, ok := err.(*errors.ValidationError); !ok {
+					t.Errorf("Expected ValidationError, got %T: %v", err, err)
+				}
+			}
+		})
+	}
+
+	// The signature must still verify against the key the callback returns for the kid
+	tokenString, _, err := createTokenWithClaims(jwt.MapClaims{"iss": "https://example.com/tenants/acme"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	config := VerifyConfig{BaseIssuerURL: "https://example.com/", Timeout: 5 * time.Second, DisableKidIssuerBinding: true}
+	if _, err := Verify(tokenString, config, mockKeyFunc(&otherKey.PublicKey)); err == nil {
+		t.Error("Expected signature from a different key to be rejected")
+	}
+}
+
+func TestVerificationResult_ClaimAccessors(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{
+		"permissions": []string{"read", "write"},
+		"role":        "admin",
+		"mixed":       []interface{}{"read", 42},
+		"count":       42,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+	result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	permissions, err := result.StringSlice("permissions")
+	if err != nil {
+		t.Fatalf("Expected permissions, got error: %v", err)
+	}
+	if !slices.Equal(permissions, []string{"read", "write"}) {
+		t.Errorf("Expected [read write], got %v", permissions)
+	}
+
+	role, err := result.String("role")
+	if err != nil {
+		t.Fatalf("Expected role, got error: %v", err)
+	}
+	if role != "admin" {
+		t.Errorf("Expected role admin, got %s", role)
+	}
+
+	failures := []struct {
+		name string
+		call func() error
+	}{
+		{"missing string", func() error { _, err := result.String("missing"); return err }},
+		{"non-string", func() error { _, err := result.String("count"); return err }},
+		{"missing slice", func() error { _, err := result.StringSlice("missing"); return err }},
+		{"non-array slice", func() error { _, err := result.StringSlice("role"); return err }},
+		{"non-string element", func() error { _, err := result.StringSlice("mixed"); return err }},
+		{"nil result", func() error { _, err := (*VerificationResult)(nil).String("role"); return err }},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.call().(*errors.ValidationError); !ok {
+				t.Errorf("Expected ValidationError, got %T", tt.call())
+			}
+		})
+	}
+}
+
+func TestVerifyMaxIssuerLength(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	// A valid issuer just over the default limit
+	baseURL := "https://example.com/" + strings.Repeat("a", DefaultMaxIssuerLength-40)
+	issuer := baseURL + "/" + keyID.String()
+	if len(issuer) <= DefaultMaxIssuerLength {
+		t.Fatalf("Test issuer must exceed the default limit, got %d bytes", len(issuer))
+	}
+
+	tokenString, pubKey, err := createTokenWithIssuer(issuer, keyID)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	testCases := []struct {
+		name            string
+		maxIssuerLength int
+		expectValid     bool
+	}{
+		{name: "default limit rejects", maxIssuerLength: 0, expectValid: false},
+		{name: "lower limit rejects", maxIssuerLength: 100, expectValid: false},
+		{name: "raised limit accepts", maxIssuerLength: 2 * DefaultMaxIssuerLength, expectValid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := VerifyConfig{
+				BaseIssuerURL:   baseURL,
+				Timeout:         5 * time.Second,
+				MaxIssuerLength: tc.maxIssuerLength,
+			}
+
+			_, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if !strings.Contains(validationErr.Message, "maximum length") {
+				t.Errorf("Expected issuer length error, got: %s", validationErr.Message)
+			}
+		})
+	}
+}
+
+func TestVerifyRequireTypeHeader(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	sign := func(typ interface{}) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub": "test-user",
+			"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+			"ver": "japikey-v1",
+		})
+		token.Header["kid"] = "123e4567-e89b-12d3-a456-426614174000"
+		if typ == nil {
+			delete(token.Header, "typ")
+		} else {
+			token.Header["typ"] = typ
+		}
+		tokenString, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	testCases := []struct {
+		name        string
+		typ         interface{}
+		require     bool
+		expectValid bool
+	}{
+		{name: "JWT accepted", typ: "JWT", require: true, expectValid: true},
+		{name: "lowercase jwt accepted", typ: "jwt", require: true, expectValid: true},
+		{name: "missing typ rejected", typ: nil, require: true, expectValid: false},
+		{name: "other typ rejected", typ: "at+jwt", require: true, expectValid: false},
+		{name: "non-string typ rejected", typ: 1, require: true, expectValid: false},
+		{name: "missing typ ignored by default", typ: nil, expectValid: true},
+		{name: "other typ ignored by default", typ: "at+jwt", expectValid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := VerifyConfig{
+				BaseIssuerURL:     "https://example.com/",
+				Timeout:           5 * time.Second,
+				RequireTypeHeader: tc.require,
+			}
+
+			_, err := Verify(sign(tc.typ), config, mockKeyFunc(&privateKey.PublicKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != "HeaderValidationError" {
+				t.Errorf("Expected code HeaderValidationError, got %s", validationErr.Code)
+			}
+		})
+	}
+}
+
+func TestVerifyRequiredType(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	sign := func(typ interface{}) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub": "test-user",
+			"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+			"ver": "japikey-v1",
+		})
+		token.Header["kid"] = "123e4567-e89b-12d3-a456-426614174000"
+		if typ == nil {
+			delete(token.Header, "typ")
+		} else {
+			token.Header["typ"] = typ
+		}
+		tokenString, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	testCases := []struct {
+		name              string
+		typ               interface{}
+		required          string
+		requireTypeHeader bool
+		expectValid       bool
+	}{
+		{name: "at+jwt matches", typ: "at+jwt", required: "at+jwt", expectValid: true},
+		{name: "JWT matches", typ: "JWT", required: "JWT", expectValid: true},
+		{name: "mismatch rejected", typ: "JWT", required: "at+jwt"},
+		{name: "case mismatch rejected", typ: "jwt", required: "JWT"},
+		{name: "absent rejected", typ: nil, required: "at+jwt"},
+		{name: "non-string rejected", typ: 1, required: "at+jwt"},
+		{name: "overrides RequireTypeHeader", typ: "at+jwt", required: "at+jwt", requireTypeHeader: true, expectValid: true},
+		{name: "absent rejected with RequireTypeHeader", typ: nil, required: "at+jwt", requireTypeHeader: true},
+		{name: "no requirement by default", typ: nil, expectValid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := VerifyConfig{
+				BaseIssuerURL:     "https://example.com/",
+				Timeout:           5 * time.Second,
+				RequiredType:      tc.required,
+				RequireTypeHeader: tc.requireTypeHeader,
+			}
+
+			_, err := Verify(sign(tc.typ), config, mockKeyFunc(&privateKey.PublicKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*errors.ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Code != "HeaderValidationError" {
+				t.Errorf("Expected code HeaderValidationError, got %s", validationErr.Code)
+			}
+		})
+	}
+}
+
+func TestVerifyWithSet(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	matching, err := jwks.NewJWKS(pubKey, keyID)
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+	otherKid, err := jwks.NewJWKS(pubKey, uuid.New())
+	if err != nil {
+		t.Fatalf("Failed to create JWKS: %v", err)
+	}
+
+	result, err := VerifyWithSet(tokenString, config, func(uuid.UUID) (*jwks.JWKS, error) { return matching, nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.KeyID != keyID {
+		t.Errorf("Expected key ID %s, got %s", keyID, result.KeyID)
+	}
+
+	notFound := []struct {
+		name    string
+		setFunc JWKSetCallback
+	}{
+		{"kid not in set", func(uuid.UUID) (*jwks.JWKS, error) { return otherKid, nil }},
+		{"nil set", func(uuid.UUID) (*jwks.JWKS, error) { return nil, nil }},
+		{"callback error", func(uuid.UUID) (*jwks.JWKS, error) { return nil, errors.NewKeyNotFoundError("gone") }},
+	}
+	for _, tt := range notFound {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyWithSet(tokenString, config, tt.setFunc)
+			if _, ok := err.(*errors.KeyNotFoundError); !ok {
+				t.Errorf("Expected KeyNotFoundError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequireSubject(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	sign := func(subject interface{}) string {
+		claims := jwt.MapClaims{
+			"iss": "https://example.com/123e4567-e89b-12d3-a456-426614174000",
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+			"ver": "japikey-v1",
+		}
+		if subject != nil {
+			claims["sub"] = subject
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "123e4567-e89b-12d3-a456-426614174000"
+		tokenString, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	testCases := []struct {
+		name        string
+		subject     interface{}
+		require     bool
+		expectValid bool
+	}{
+		{name: "subject accepted", subject: "test-user", require: true, expectValid: true},
+		{name: "missing subject rejected", subject: nil, require: true, expectValid: false},
+		{name: "empty subject rejected", subject: "", require: true, expectValid: false},
+		{name: "non-string subject rejected", subject: 42, require: true, expectValid: false},
+		{name: "missing subject allowed by default", subject: nil, expectValid: true},
+		{name: "empty subject allowed by default", subject: "", expectValid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := VerifyConfig{
+				BaseIssuerURL:  "https://example.com/",
+				Timeout:        5 * time.Second,
+				RequireSubject: tc.require,
+			}
+
+			_, err := Verify(sign(tc.subject), config, mockKeyFunc(&privateKey.PublicKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyExactIssuer(t *testing.T) {
+	keyID := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+	pinned := "https://example.com/" + keyID.String()
+
+	testCases := []struct {
+		name        string
+		issuer      string
+		config      VerifyConfig
+		expectValid bool
+		expectedErr error
+	}{
+		{
+			name:        "exact match accepted",
+			issuer:      pinned,
+			config:      VerifyConfig{ExactIssuer: pinned},
+			expectValid: true,
+		},
+		{
+			name:        "trailing slash rejected",
+			issuer:      pinned + "/",
+			config:      VerifyConfig{ExactIssuer: pinned},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "different host rejected",
+			issuer:      "https://example.org/" + keyID.String(),
+			config:      VerifyConfig{ExactIssuer: pinned},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "different scheme rejected",
+			issuer:      "http://example.com/" + keyID.String(),
+			config:      VerifyConfig{ExactIssuer: pinned},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "pinned issuer for a different kid rejected",
+			issuer:      "https://example.com/" + uuid.New().String(),
+			config:      VerifyConfig{ExactIssuer: "https://example.com/" + uuid.New().String()},
+			expectedErr: &errors.ValidationError{},
+		},
+		{
+			name:        "default port accepted with normalization",
+			issuer:      "https://example.com:443/" + keyID.String(),
+			config:      VerifyConfig{ExactIssuer: pinned, NormalizeIssuerURL: true},
+			expectValid: true,
+		},
+		{
+			name:        "combined with base issuer URL rejected",
+			issuer:      pinned,
+			config:      VerifyConfig{ExactIssuer: pinned, BaseIssuerURL: "https://example.com/"},
+			expectedErr: &errors.InternalError{},
+		},
+		{
+			name:        "combined with base issuer URLs rejected",
+			issuer:      pinned,
+			config:      VerifyConfig{ExactIssuer: pinned, BaseIssuerURLs: []string{"https://example.com/"}},
+			expectedErr: &errors.InternalError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithIssuer(tc.issuer, keyID)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+			tc.config.Timeout = 5 * time.Second
+
+			_, err = Verify(tokenString, tc.config, mockKeyFunc(pubKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if fmt.Sprintf("%T", err) != fmt.Sprintf("%T", tc.expectedErr) {
+				t.Errorf("Expected %T, got %T: %v", tc.expectedErr, err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyKeyActivation(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+
+	activationFunc := func(activeFrom time.Time, err error) func(uuid.UUID) (time.Time, error) {
+		return func(kid uuid.UUID) (time.Time, error) {
+			if kid != keyID {
+				t.Errorf("Expected activation lookup for %s, got %s", keyID, kid)
+			}
+			return activeFrom, err
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		activation  func(uuid.UUID) (time.Time, error)
+		leeway      time.Duration
+		expectValid bool
+	}{
+		{name: "no gating by default", expectValid: true},
+		{name: "active immediately", activation: activationFunc(time.Time{}, nil), expectValid: true},
+		{name: "activated in the past", activation: activationFunc(time.Now().Add(-time.Minute), nil), expectValid: true},
+		{name: "pre-published key rejected", activation: activationFunc(time.Now().Add(time.Hour), nil), expectValid: false},
+		{name: "activation within leeway", activation: activationFunc(time.Now().Add(10*time.Second), nil), leeway: time.Minute, expectValid: true},
+		{name: "lookup error rejected", activation: activationFunc(time.Time{}, fmt.Errorf("database down")), expectValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := VerifyConfig{
+				BaseIssuerURL: "https://example.com/",
+				Timeout:       5 * time.Second,
+				Leeway:        tc.leeway,
+				KeyActivation: tc.activation,
+			}
+
+			_, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyWithCandidates(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		candidates  []KeyCandidate
+		expectValid bool
+	}{
+		{
+			name:        "single matching candidate",
+			candidates:  []KeyCandidate{{KeyID: keyID, PublicKey: pubKey}},
+			expectValid: true,
+		},
+		{
+			name: "signing key after a wrong kid match",
+			candidates: []KeyCandidate{
+				{KeyID: uuid.New(), PublicKey: pubKey},
+				{KeyID: keyID, PublicKey: &otherKey.PublicKey},
+			},
+			expectValid: true,
+		},
+		{
+			name: "older signing key during rotation overlap",
+			candidates: []KeyCandidate{
+				{KeyID: uuid.New(), PublicKey: pubKey, CreatedAt: now.Add(-time.Hour)},
+				{KeyID: uuid.New(), PublicKey: &otherKey.PublicKey, CreatedAt: now},
+			},
+			expectValid: true,
+		},
+		{
+			name:       "no candidate verifies",
+			candidates: []KeyCandidate{{KeyID: keyID, PublicKey: &otherKey.PublicKey}, {KeyID: uuid.New(), PublicKey: &otherKey.PublicKey}},
+		},
+		{name: "no candidates"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			result, err := VerifyWithCandidates(tokenString, config, func(kid uuid.UUID) ([]KeyCandidate, error) {
+				calls++
+				return tt.candidates, nil
+			})
+			if calls != 1 {
+				t.Errorf("Expected the callback to be called once, got %d", calls)
+			}
+			if tt.expectValid {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if result.KeyID != keyID {
+					t.Errorf("Expected key ID %s, got %s", keyID, result.KeyID)
+				}
+				return
+			}
+			if err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestVerifyWithKeyHistory(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	tests := []struct {
+		name        string
+		history     []*rsa.PublicKey
+		expectValid bool
+		expectedErr string
+	}{
+		{name: "current key signed", history: []*rsa.PublicKey{pubKey}, expectValid: true},
+		{name: "previous key signed", history: []*rsa.PublicKey{&otherKey.PublicKey, pubKey}, expectValid: true},
+		{name: "nil entries skipped", history: []*rsa.PublicKey{nil, pubKey}, expectValid: true},
+		{name: "only nil entries", history: []*rsa.PublicKey{nil}, expectedErr: "*errors.KeyNotFoundError"},
+		{name: "no key verifies", history: []*rsa.PublicKey{&otherKey.PublicKey}, expectedErr: "*errors.ValidationError"},
+		{name: "empty history", expectedErr: "*errors.KeyNotFoundError"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifyWithKeyHistory(tokenString, config, func(kid uuid.UUID) ([]*rsa.PublicKey, error) {
+				if kid != keyID {
+					t.Errorf("Expected kid %s, got %s", keyID, kid)
+				}
+				return tt.history, nil
+			})
+			if tt.expectValid {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if result.KeyID != keyID {
+					t.Errorf("Expected key ID %s, got %s", keyID, result.KeyID)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+			if fmt.Sprintf("%T", err) != tt.expectedErr {
+				t.Errorf("Expected %s, got %T: %v", tt.expectedErr, err, err)
+			}
+		})
+	}
+
+	t.Run("claim failures are reported after the signature matches", func(t *testing.T) {
+		expired, expiredKey, err := createTokenWithClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		_, err = VerifyWithKeyHistory(expired, config, func(uuid.UUID) ([]*rsa.PublicKey, error) {
+			return []*rsa.PublicKey{&otherKey.PublicKey, expiredKey}, nil
+		})
+		if _, ok := err.(*errors.TokenExpiredError); !ok {
+			t.Errorf("Expected TokenExpiredError, got %T: %v", err, err)
+		}
+	})
+
+	t.Run("callback errors are propagated", func(t *testing.T) {
+		_, err := VerifyWithKeyHistory(tokenString, config, func(uuid.UUID) ([]*rsa.PublicKey, error) {
+			return nil, errors.NewKeyNotFoundError("unknown kid")
+		})
+		if _, ok := err.(*errors.KeyNotFoundError); !ok {
+			t.Errorf("Expected KeyNotFoundError, got %T: %v", err, err)
+		}
+	})
+}
+
+func TestOrderCandidates(t *testing.T) {
+	keyID := uuid.New()
+	now := time.Now()
+	unknown := KeyCandidate{KeyID: uuid.New()}
+	older := KeyCandidate{KeyID: uuid.New(), CreatedAt: now.Add(-time.Hour)}
+	newer := KeyCandidate{KeyID: uuid.New(), CreatedAt: now}
+	matching := KeyCandidate{KeyID: keyID, CreatedAt: now.Add(-2 * time.Hour)}
+
+	candidates := []KeyCandidate{unknown, older, matching, newer}
+	orderCandidates(candidates, keyID)
+
+	expected := []KeyCandidate{matching, newer, older, unknown}
+	for i := range expected {
+		if candidates[i].KeyID != expected[i].KeyID {
+			t.Fatalf("Unexpected order at %d: got %s, expected %s", i, candidates[i].KeyID, expected[i].KeyID)
+		}
+	}
+}
+
+func TestVerifyRedactClaims(t *testing.T) {
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{
+		"email": "user@example.com",
+		"name":  "Test User",
+		"role":  "admin",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	hookSawEmail := false
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+		RedactClaims:  []string{"email", "name", "iss", "missing"},
+		OnVerified: func(result *VerificationResult) error {
+			hookSawEmail = result.Claims["email"] == "user@example.com"
+			return nil
+		},
+	}
+
+	result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, claim := range []string{"email", "name", "iss"} {
+		if _, ok := result.Claims[claim]; ok {
+			t.Errorf("Expected claim %q to be redacted", claim)
+		}
+	}
+	if result.Claims["role"] != "admin" || result.Claims["sub"] != "test-user" {
+		t.Errorf("Expected other claims to be kept, got %v", result.Claims)
+	}
+	if !hookSawEmail {
+		t.Error("Expected OnVerified to see the claims before redaction")
+	}
+}
+
+func TestVerifySingleAudienceOnly(t *testing.T) {
+	testCases := []struct {
+		name        string
+		audience    interface{}
+		single      bool
+		expectValid bool
+	}{
+		{name: "string audience accepted", audience: "test-audience", single: true, expectValid: true},
+		{name: "array audience rejected", audience: []string{"a", "b"}, single: true, expectValid: false},
+		{name: "single-entry array rejected", audience: []string{"a"}, single: true, expectValid: false},
+		{name: "numeric audience rejected", audience: 42, single: true, expectValid: false},
+		{name: "array audience accepted by default", audience: []string{"a", "b"}, expectValid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"aud": tc.audience})
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+			config := VerifyConfig{
+				BaseIssuerURL:      "https://example.com/",
+				Timeout:            5 * time.Second,
+				SingleAudienceOnly: tc.single,
+			}
+
+			_, err = Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tc.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestVerificationResult_Audiences(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		expected []string
+	}{
+		{"single string", jwt.MapClaims{"aud": "api"}, []string{"api"}},
+		{"array", jwt.MapClaims{"aud": []interface{}{"api", "web"}}, []string{"api", "web"}},
+		{"string slice", jwt.MapClaims{"aud": []string{"api"}}, []string{"api"}},
+		{"empty array", jwt.MapClaims{"aud": []interface{}{}}, []string{}},
+		{"missing", jwt.MapClaims{}, nil},
+		{"non-string entry", jwt.MapClaims{"aud": []interface{}{"api", 1}}, nil},
+		{"wrong type", jwt.MapClaims{"aud": 42}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &VerificationResult{Claims: tt.claims}
+			audiences := result.Audiences()
+			if (audiences == nil) != (tt.expected == nil) || !slices.Equal(audiences, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, audiences)
+			}
+			if standard := newStandardClaims(tt.claims); !slices.Equal(standard.Audience, tt.expected) {
+				t.Errorf("Expected StandardClaims.Audience %#v, got %#v", tt.expected, standard.Audience)
+			}
+		})
+	}
+
+	var nilResult *VerificationResult
+	if nilResult.Audiences() != nil {
+		t.Error("Expected nil audiences for a nil result")
+	}
+
+	// Both forms survive a real verification round trip
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"aud": []string{"api", "web"}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	result, err := Verify(tokenString, VerifyConfig{BaseIssuerURL: "https://example.com/", Timeout: 5 * time.Second}, mockKeyFunc(pubKey))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !slices.Equal(result.Audiences(), []string{"api", "web"}) {
+		t.Errorf("Expected [api web], got %v", result.Audiences())
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, key interface{}) string {
+		// Claims are deliberately invalid: expired, no iss, no ver
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})
+		tokenString, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tokenString
+	}
+
+	rs256 := sign(jwt.SigningMethodRS256, privateKey)
+	ps256 := sign(jwt.SigningMethodPS256, privateKey)
+	hs256 := sign(jwt.SigningMethodHS256, []byte("secret"))
+	none := sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType)
+
+	testCases := []struct {
+		name        string
+		token       string
+		publicKey   *rsa.PublicKey
+		allowedAlgs []string
+		shouldPass  bool
+	}{
+		{"RS256 with default algorithms ignores claims", rs256, &privateKey.PublicKey, nil, true},
+		{"PS256 when allowed", ps256, &privateKey.PublicKey, []string{"PS256"}, true},
+		{"PS256 when only RS256 allowed", ps256, &privateKey.PublicKey, nil, false},
+		{"wrong key", rs256, &otherKey.PublicKey, nil, false},
+		{"HS256 token", hs256, &privateKey.PublicKey, nil, false},
+		{"none token", none, &privateKey.PublicKey, nil, false},
+		{"HS256 in allowed algorithms", hs256, &privateKey.PublicKey, []string{"HS256"}, false},
+		{"none in allowed algorithms", none, &privateKey.PublicKey, []string{"none"}, false},
+		{"unknown algorithm", rs256, &privateKey.PublicKey, []string{"XX999"}, false},
+		{"nil key", rs256, nil, nil, false},
+		{"malformed token", "not.a.jwt", &privateKey.PublicKey, nil, false},
+		{"oversized token", strings.Repeat("a", MaxTokenSize+1), &privateKey.PublicKey, nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifySignature(tc.token, tc.publicKey, tc.allowedAlgs)
+			if tc.shouldPass {
+				if err != nil {
+					t.Errorf("Expected signature to verify, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			switch err.(type) {
+			case *errors.ValidationError, *errors.TokenFormatError:
+			default:
+				t.Errorf("Expected ValidationError or TokenFormatError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestVerificationResultPublicKey(t *testing.T) {
+	tokenString, pubKey, keyID, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	config := VerifyConfig{BaseIssuerURL: "https://example.com/"}
+
+	result, err := Verify(tokenString, config, mockKeyFunc(pubKey))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.PublicKey != pubKey {
+		t.Error("Expected the callback's key on the result")
+	}
+
+	// The matching candidate is reported, not the first one tried
+	result, err = VerifyWithCandidates(tokenString, config, func(uuid.UUID) ([]KeyCandidate, error) {
+		return []KeyCandidate{
+			{KeyID: keyID, PublicKey: &otherKey.PublicKey},
+			{KeyID: uuid.New(), PublicKey: pubKey},
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.PublicKey.Equal(pubKey) {
+		t.Error("Expected the matching candidate's key on the result")
+	}
+
+	result, err = Verify(tokenString, config, mockKeyFunc(&otherKey.PublicKey))
+	if err == nil || result != nil {
+		t.Errorf("Expected no result for a failed signature, got %+v", result)
+	}
+}
+
+func TestNewVerificationResult(t *testing.T) {
+	keyID := uuid.New()
+	claims := map[string]interface{}{
+		"sub": "test-user",
+		"aud": []interface{}{"a", "b"},
+		"cnf": map[string]interface{}{"jkt": "thumbprint"},
+	}
+
+	result := NewVerificationResult(claims, keyID, AlgorithmRS256)
+
+	if result.KeyID != keyID {
+		t.Errorf("Expected key ID %s, got %s", keyID, result.KeyID)
+	}
+	if result.Algorithm != AlgorithmRS256 {
+		t.Errorf("Expected algorithm %s, got %s", AlgorithmRS256, result.Algorithm)
+	}
+	if subject, err := result.String("sub"); err != nil || subject != "test-user" {
+		t.Errorf("Expected subject test-user, got %q (err: %v)", subject, err)
+	}
+	if audiences := result.Audiences(); !slices.Equal(audiences, []string{"a", "b"}) {
+		t.Errorf("Expected audiences [a b], got %v", audiences)
+	}
+	if result.Confirmation == nil || result.Confirmation.JKT != "thumbprint" {
+		t.Errorf("Expected confirmation from cnf claim, got %+v", result.Confirmation)
+	}
+
+	// The claims are copied, so later changes to the input don't leak into the result
+	claims["sub"] = "someone-else"
+	if subject, _ := result.String("sub"); subject != "test-user" {
+		t.Errorf("Expected result claims to be a copy, got subject %q", subject)
+	}
+
+	empty := NewVerificationResult(nil, uuid.Nil, "")
+	if empty.Claims == nil {
+		t.Error("Expected non-nil claims for nil input")
+	}
+	if empty.Confirmation != nil {
+		t.Errorf("Expected no confirmation, got %+v", empty.Confirmation)
+	}
+}
+
+func TestVerifyKeySourceErrorsPassThrough(t *testing.T) {
+	tokenString, _, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create valid token: %v", err)
+	}
+	config := VerifyConfig{BaseIssuerURL: "https://example.com/"}
+
+	testCases := []struct {
+		name     string
+		keyErr   error
+		expected string
+	}{
+		{"fetch error", errors.NewFetchError("issuer unreachable"), "*errors.FetchError"},
+		{"database timeout", errors.NewDatabaseTimeoutError("slow"), "*errors.DatabaseTimeoutError"},
+		{"database unavailable", errors.NewDatabaseUnavailableError("down"), "*errors.DatabaseUnavailableError"},
+		{"wrapped fetch error", fmt.Errorf("lookup: %w", errors.NewFetchError("issuer unreachable")), "*errors.FetchError"},
+		{"other error", fmt.Errorf("boom"), "*errors.KeyNotFoundError"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Verify(tokenString, config, func(uuid.UUID) (*rsa.PublicKey, error) {
+				return nil, tc.keyErr
+			})
+			if got := fmt.Sprintf("%T", err); got != tc.expected {
+				t.Errorf("Expected %s, got %s: %v", tc.expected, got, err)
+			}
+		})
+	}
+}
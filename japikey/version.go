@@ -0,0 +1,92 @@
+package japikey
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	japikeyerrors "github.com/susu-dot-dev/japikey/errors"
+)
+
+const (
+	// VersionPrefix is the prefix of the version claim value, followed by the version number
+	VersionPrefix = "japikey-v"
+
+	// MaxSupportedVersion is the highest token version accepted by default
+	MaxSupportedVersion = 1
+)
+
+// reservedClaimsByVersion lists the claim names each future token version reserves
+var reservedClaimsByVersion = map[int][]string{
+	2: {"act", "azp", "client_id", "roles", "tenant"},
+}
+
+// ReservedClaimsAfter returns the claim names reserved by token versions later than version,
+// sorted, for VerifyConfig.ForwardReservedClaims. The reserved sets are:
+//
+//	japikey-v2: act, azp, client_id, roles, tenant
+func ReservedClaimsAfter(version int) []string {
+	var names []string
+	for v, reserved := range reservedClaimsByVersion {
+		if v > version {
+			names = append(names, reserved...)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+var (
+	maxVersionMu sync.RWMutex
+	// maxVersionOverride replaces MaxSupportedVersion when > 0
+	maxVersionOverride int
+)
+
+// SetMaxVersion overrides the highest token version accepted during verification and returns
+// a function that restores the previous value. Values < 1 reset to MaxSupportedVersion.
+//
+// It is intended for tests and controlled staged rollouts of a new token version, not as a
+// production default. The override is process-wide; it is safe for concurrent use, but
+// overlapping overrides must be restored in reverse order.
+func SetMaxVersion(n int) (restore func()) {
+	maxVersionMu.Lock()
+	defer maxVersionMu.Unlock()
+
+	previous := maxVersionOverride
+	maxVersionOverride = max(n, 0)
+
+	return func() {
+		maxVersionMu.Lock()
+		defer maxVersionMu.Unlock()
+		maxVersionOverride = previous
+	}
+}
+
+// maxVersion returns the highest token version currently accepted.
+func maxVersion() int {
+	maxVersionMu.RLock()
+	defer maxVersionMu.RUnlock()
+
+	if maxVersionOverride > 0 {
+		return maxVersionOverride
+	}
+	return MaxSupportedVersion
+}
+
+// parseVersion extracts the version number from a version claim value such as japikey-v1.
+func parseVersion(version string) (int, error) {
+	numberStr, ok := strings.CutPrefix(version, VersionPrefix)
+	if !ok {
+		return 0, japikeyerrors.NewValidationError(fmt.Sprintf("invalid version: %s", version))
+	}
+
+	number, err := strconv.Atoi(numberStr)
+	// Reject non-canonical forms such as japikey-v01 or japikey-v+1
+	if err != nil || strconv.Itoa(number) != numberStr || number < 1 {
+		return 0, japikeyerrors.NewValidationError(fmt.Sprintf("invalid version: %s", version))
+	}
+
+	return number, nil
+}
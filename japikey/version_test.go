@@ -0,0 +1,151 @@
+package japikey
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/susu-dot-dev/japikey/errors"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version     string
+		expected    int
+		expectError bool
+	}{
+		{"japikey-v1", 1, false},
+		{"japikey-v2", 2, false},
+		{"japikey-v10", 10, false},
+		{"japikey-v0", 0, true},
+		{"japikey-v01", 0, true},
+		{"japikey-v+1", 0, true},
+		{"japikey-v-1", 0, true},
+		{"japikey-v", 0, true},
+		{"japikey-vx", 0, true},
+		{"other-v1", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			number, err := parseVersion(tt.version)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got version %d", tt.version, number)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if number != tt.expected {
+				t.Errorf("Expected version %d, got %d", tt.expected, number)
+			}
+		})
+	}
+}
+
+func TestSetMaxVersion(t *testing.T) {
+	config := VerifyConfig{
+		BaseIssuerURL: "https://example.com/",
+		Timeout:       5 * time.Second,
+	}
+
+	tokenString, pubKey, err := createTokenWithClaims(jwt.MapClaims{"ver": "japikey-v2"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	if _, err := Verify(tokenString, config, mockKeyFunc(pubKey)); err == nil {
+		t.Fatal("Expected v2 token to be rejected by default")
+	}
+
+	restore := SetMaxVersion(2)
+	if _, err := Verify(tokenString, config, mockKeyFunc(pubKey)); err != nil {
+		t.Errorf("Expected v2 token to be accepted after SetMaxVersion(2), got: %v", err)
+	}
+
+	v1Token, v1PubKey, _, err := createValidToken()
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if _, err := Verify(v1Token, config, mockKeyFunc(v1PubKey)); err != nil {
+		t.Errorf("Expected v1 token to remain accepted, got: %v", err)
+	}
+
+	restore()
+	if _, err := Verify(tokenString, config, mockKeyFunc(pubKey)); err == nil {
+		t.Error("Expected v2 token to be rejected after restore")
+	}
+	if maxVersion() != MaxSupportedVersion {
+		t.Errorf("Expected max version %d after restore, got %d", MaxSupportedVersion, maxVersion())
+	}
+}
+
+func TestSetMaxVersion_NestedRestore(t *testing.T) {
+	restoreOuter := SetMaxVersion(3)
+	restoreInner := SetMaxVersion(5)
+
+	if maxVersion() != 5 {
+		t.Errorf("Expected max version 5, got %d", maxVersion())
+	}
+	restoreInner()
+	if maxVersion() != 3 {
+		t.Errorf("Expected max version 3 after inner restore, got %d", maxVersion())
+	}
+	restoreOuter()
+	if maxVersion() != MaxSupportedVersion {
+		t.Errorf("Expected max version %d after outer restore, got %d", MaxSupportedVersion, maxVersion())
+	}
+}
+
+func TestReservedClaimsAfter(t *testing.T) {
+	expected := []string{"act", "azp", "client_id", "roles", "tenant"}
+	if got := ReservedClaimsAfter(1); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := ReservedClaimsAfter(2); len(got) != 0 {
+		t.Errorf("Expected no claims reserved after version 2, got %v", got)
+	}
+}
+
+func TestVerifyForwardReservedClaims(t *testing.T) {
+	tests := []struct {
+		name        string
+		claims      jwt.MapClaims
+		reserved    []string
+		expectValid bool
+	}{
+		{"no reserved claims", jwt.MapClaims{"role": "admin"}, ReservedClaimsAfter(MaxSupportedVersion), true},
+		{"future claim rejected", jwt.MapClaims{"tenant": "acme"}, ReservedClaimsAfter(MaxSupportedVersion), false},
+		{"prefix pattern rejected", jwt.MapClaims{"v2_scope": "read"}, []string{"v2_*"}, false},
+		{"future claim allowed by default", jwt.MapClaims{"tenant": "acme"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenString, pubKey, err := createTokenWithClaims(tt.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+			config := VerifyConfig{
+				BaseIssuerURL:         "https://example.com/",
+				Timeout:               5 * time.Second,
+				ForwardReservedClaims: tt.reserved,
+			}
+
+			_, err = Verify(tokenString, config, mockKeyFunc(pubKey))
+			if tt.expectValid {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*errors.ValidationError); !ok {
+				t.Fatalf("Expected ValidationError, got %T: %v", err, err)
+			}
+		})
+	}
+}
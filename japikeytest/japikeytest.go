@@ -0,0 +1,73 @@
+// Package japikeytest provides a running JAPIKey issuer for integration tests, so that
+// downstream projects can mint tokens and verify them end to end against a real JWKS endpoint.
+package japikeytest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/internal/middleware"
+	"github.com/susu-dot-dev/japikey/japikey"
+)
+
+// TestIssuer is an in-memory issuer backed by a japikey.Keystore, whose public keys are served
+// by an httptest.Server using the same JWKS router as production issuers.
+type TestIssuer struct {
+	server   *httptest.Server
+	keystore *japikey.Keystore
+}
+
+// NewTestIssuer starts a test issuer. The server is shut down automatically by t.Cleanup.
+func NewTestIssuer(t testing.TB) *TestIssuer {
+	t.Helper()
+
+	issuer := &TestIssuer{keystore: japikey.NewKeystore()}
+
+	router, err := middleware.CreateJWKSRouter(middleware.JWKSRouterConfig{DB: issuer})
+	if err != nil {
+		t.Fatalf("japikeytest: failed to create JWKS router: %v", err)
+	}
+	issuer.server = httptest.NewServer(router)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// URL returns the issuer's base URL, for use as BaseIssuerURL when verifying its tokens.
+func (i *TestIssuer) URL() string {
+	return i.server.URL
+}
+
+// Mint issues a new JAPIKey from the keystore and publishes its public key. config.Issuer is
+// always replaced with URL()/kid, so the token verifies against URL() as the base issuer URL.
+// The kid comes from config.KeyIDGenerator if set. Configs with a Signer or PrivateKey are
+// rejected with a ValidationError, since the keystore generates the key pair.
+func (i *TestIssuer) Mint(config japikey.Config) (*japikey.JAPIKey, error) {
+	generate := config.KeyIDGenerator
+	if generate == nil {
+		generate = uuid.New
+	}
+	// Pick the kid up front so that it can be embedded in the issuer claim
+	keyID := generate()
+	config.Issuer = i.URL() + "/" + keyID.String()
+	config.KeyIDGenerator = func() uuid.UUID { return keyID }
+
+	return i.keystore.Issue(config)
+}
+
+// GetKey implements middleware.DatabaseDriver over the minted keys.
+func (i *TestIssuer) GetKey(ctx context.Context, kid string) (*middleware.KeyLookupResult, error) {
+	keyID, err := uuid.Parse(kid)
+	if err != nil {
+		return nil, errors.NewKeyNotFoundError("key not found")
+	}
+
+	publicKey, err := i.keystore.GetPublicKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return &middleware.KeyLookupResult{PublicKey: publicKey}, nil
+}
@@ -0,0 +1,100 @@
+package japikeytest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/susu-dot-dev/japikey/errors"
+	"github.com/susu-dot-dev/japikey/japikey"
+)
+
+func TestTestIssuer_MintAndVerifyRemotely(t *testing.T) {
+	issuer := NewTestIssuer(t)
+
+	result, err := issuer.Mint(japikey.Config{
+		Subject:   "test-user",
+		Audience:  "test-audience",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to mint token: %v", err)
+	}
+
+	keyFunc, err := japikey.NewRemoteKeyFunc(japikey.RemoteKeyFuncConfig{BaseIssuerURL: issuer.URL()})
+	if err != nil {
+		t.Fatalf("Failed to create remote key func: %v", err)
+	}
+
+	verified, err := japikey.Verify(result.JWT, japikey.VerifyConfig{BaseIssuerURL: issuer.URL()}, keyFunc)
+	if err != nil {
+		t.Fatalf("Expected minted token to verify, got: %v", err)
+	}
+	if verified.KeyID != result.KeyID {
+		t.Errorf("Expected key ID %s, got %s", result.KeyID, verified.KeyID)
+	}
+	if verified.Claims["sub"] != "test-user" {
+		t.Errorf("Expected sub test-user, got %v", verified.Claims["sub"])
+	}
+
+	if _, err := keyFunc(uuid.New()); err == nil {
+		t.Error("Expected error for a key the issuer never minted")
+	} else if _, ok := err.(*errors.KeyNotFoundError); !ok {
+		t.Errorf("Expected KeyNotFoundError, got %T", err)
+	}
+}
+
+func TestTestIssuer_MintValidatesConfig(t *testing.T) {
+	issuer := NewTestIssuer(t)
+
+	_, err := issuer.Mint(japikey.Config{Audience: "test-audience", ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError for missing subject, got %T: %v", err, err)
+	}
+}
+
+func TestTestIssuer_MintRejectsSigningFields(t *testing.T) {
+	issuer := NewTestIssuer(t)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	_, err = issuer.Mint(japikey.Config{
+		Subject:    "test-user",
+		Audience:   "test-audience",
+		ExpiresAt:  time.Now().Add(1 * time.Hour),
+		PrivateKey: privateKey,
+	})
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("Expected ValidationError for a caller-supplied private key, got %T: %v", err, err)
+	}
+}
+
+func TestTestIssuer_MintUsesKeyIDGenerator(t *testing.T) {
+	issuer := NewTestIssuer(t)
+	keyID := uuid.New()
+
+	result, err := issuer.Mint(japikey.Config{
+		Subject:        "test-user",
+		Audience:       "test-audience",
+		ExpiresAt:      time.Now().Add(1 * time.Hour),
+		KeyIDGenerator: func() uuid.UUID { return keyID },
+	})
+	if err != nil {
+		t.Fatalf("Failed to mint token: %v", err)
+	}
+	if result.KeyID != keyID {
+		t.Errorf("Expected key ID %s from the generator, got %s", keyID, result.KeyID)
+	}
+
+	keyFunc, err := japikey.NewRemoteKeyFunc(japikey.RemoteKeyFuncConfig{BaseIssuerURL: issuer.URL()})
+	if err != nil {
+		t.Fatalf("Failed to create remote key func: %v", err)
+	}
+	if _, err := japikey.Verify(result.JWT, japikey.VerifyConfig{BaseIssuerURL: issuer.URL()}, keyFunc); err != nil {
+		t.Errorf("Expected minted token to verify, got: %v", err)
+	}
+}
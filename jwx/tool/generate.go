@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 
 	"github.com/google/uuid"
@@ -44,6 +45,17 @@ func GenerateExampleKeys() (string, error) {
 	return publicKeyBase64, nil
 }
 
+// encodeExponent validates an RSA public exponent and encodes it as a Base64urlUInt, the minimal
+// big-endian bytes without leading zeros
+func encodeExponent(exponent int) (string, error) {
+	if exponent < 3 || exponent > 1<<31-1 || exponent%2 == 0 {
+		return "", fmt.Errorf("exponent must be an odd integer between 3 and %d, got: %d", 1<<31-1, exponent)
+	}
+
+	exponentBytes := big.NewInt(int64(exponent)).Bytes()
+	return base64.RawURLEncoding.EncodeToString(exponentBytes), nil
+}
+
 func generateAction(c *cli.Context) error {
 	// Validate that we have the required argument (key ID)
 	if c.NArg() != 1 {
@@ -87,12 +99,18 @@ func generateAction(c *cli.Context) error {
 	// Encode the modulus to Base64url format (as required by JWK spec)
 	modulusBase64URL := base64.RawURLEncoding.EncodeToString(publicKeyBytes)
 
-	// Create the JWK with default RSA exponent (65537 = 0x010001 = AQAB in base64)
+	// Encode the exponent from the --exponent flag (default 65537 = 0x010001 = AQAB in base64)
+	exponentBase64URL, err := encodeExponent(c.Int("exponent"))
+	if err != nil {
+		return err
+	}
+
+	// Create the JWK
 	jwk := JWK{
 		Kty: "RSA",
 		Kid: keyID,
 		N:   modulusBase64URL,
-		E:   "AQAB", // Base64 encoding of 65537 (0x010001)
+		E:   exponentBase64URL,
 	}
 
 	// Validate that the JWK has all required fields
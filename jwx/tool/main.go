@@ -22,7 +22,14 @@ func main() {
 				Usage:     "Generate JWKS JSON from base64 public key and UUID",
 				Args:      true,
 				ArgsUsage: "[KEY_ID]",
-				Action:    generateAction,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "exponent",
+						Usage: "RSA public exponent of the key",
+						Value: 65537,
+					},
+				},
+				Action: generateAction,
 			},
 		},
 	}
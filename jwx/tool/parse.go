@@ -77,16 +77,19 @@ func parseAction(c *cli.Context) error {
 		return fmt.Errorf("exponent (e) cannot be empty after decoding")
 	}
 
-	// Convert the exponent bytes to an integer
-	// The exponent is typically small, so we can use a simple conversion
+	// Convert the exponent bytes to an integer. Any exponent is supported, not just 65537,
+	// but it must fit the range crypto/rsa accepts; the length check prevents overflow
+	if len(exponentBytes) > 4 {
+		return fmt.Errorf("exponent (e) is too large")
+	}
 	exponent := 0
 	for _, b := range exponentBytes {
 		exponent = (exponent << 8) | int(b)
 	}
 
-	// Validate that the exponent is a reasonable value (e.g., not 0 or negative)
-	if exponent <= 0 {
-		return fmt.Errorf("exponent (e) must be a positive integer, got: %d", exponent)
+	// Validate that the exponent is a usable RSA exponent
+	if exponent < 3 || exponent > 1<<31-1 || exponent%2 == 0 {
+		return fmt.Errorf("exponent (e) must be an odd integer between 3 and %d, got: %d", 1<<31-1, exponent)
 	}
 
 	// Convert the modulus bytes to a big integer